@@ -21,8 +21,10 @@ import (
 	"os"
 	"path"
 	"syscall"
+	"time"
 
 	"github.com/bombsimon/logrusr/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -33,9 +35,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/audit"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/control"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/peer"
@@ -46,6 +51,7 @@ import (
 	"github.com/clusterlink-net/clusterlink/pkg/util/log"
 	"github.com/clusterlink-net/clusterlink/pkg/util/runnable"
 	"github.com/clusterlink-net/clusterlink/pkg/util/tls"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 	"github.com/clusterlink-net/clusterlink/pkg/versioninfo"
 )
 
@@ -99,6 +105,23 @@ type Options struct {
 	LogLevel string
 	// PeerLabels hold the peer attributes (as "<key>:<value>" strings), to be used in access policies
 	PeerLabels map[string]string
+	// AuditLogFile is the path to a file where audit records of authorization decisions will be written.
+	// If empty, authorization decisions are not audited.
+	AuditLogFile string
+	// AuditSampleRate is the fraction of allowed authorization decisions to audit.
+	AuditSampleRate float64
+	// TracingEndpoint is the address of an OpenTelemetry collector to which traces will be exported.
+	// If empty, traces are not exported.
+	TracingEndpoint string
+	// SignPeerAttributes is set if the source attributes sent to remote peers are signed using the peer certificate.
+	SignPeerAttributes bool
+	// RequireSignedPeerAttributes is set if remote peers must sign the source attributes they send.
+	RequireSignedPeerAttributes bool
+	// JWTKeyRotationInterval is the time between rotations of the key signing access tokens.
+	// If zero, the key is not rotated.
+	JWTKeyRotationInterval time.Duration
+	// RevocationGracePeriod is the time an active connection may stay open once it is no longer allowed.
+	RevocationGracePeriod time.Duration
 }
 
 // AddFlags adds flags to fs and binds them to options.
@@ -109,6 +132,23 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"The log level. One of fatal, error, warn, info, debug.")
 	fs.StringToStringVar(&o.PeerLabels, "peer-label", nil,
 		`Peer attributes to be used in access policies. Values should have the form "<key>=<value>"`)
+	fs.StringVar(&o.AuditLogFile, "audit-log-file", "",
+		"Path to a file where audit records of authorization decisions will be written (one JSON record per line). "+
+			"Use '-' for stdout. If not specified, authorization decisions are not audited.")
+	fs.Float64Var(&o.AuditSampleRate, "audit-sample-rate", 1,
+		"Fraction (between 0 and 1) of allowed authorization decisions to audit. Denied decisions are always audited.")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", "",
+		"Address (host:port) of an OpenTelemetry collector to which traces will be exported using OTLP over gRPC. "+
+			"If not specified, traces are not exported.")
+	fs.BoolVar(&o.SignPeerAttributes, "sign-peer-attributes", false,
+		"Sign the attributes of source workloads sent to remote peers using the peer certificate.")
+	fs.BoolVar(&o.RequireSignedPeerAttributes, "require-signed-peer-attributes", false,
+		"Reject requests of remote peers whose source workload attributes are not signed.")
+	fs.DurationVar(&o.JWTKeyRotationInterval, "jwt-key-rotation-interval", control.DefaultJWKRotationInterval,
+		"Time between rotations of the key signing access tokens. If zero, the key is not rotated.")
+	fs.DurationVar(&o.RevocationGracePeriod, "revocation-grace-period", authz.DefaultRevocationGracePeriod,
+		"Time an active connection may stay open once it is no longer allowed (e.g. following an access policy "+
+			"change or the deletion of its import, export or peer), before the dataplanes terminate it.")
 }
 
 // Run the various controlplane servers.
@@ -128,6 +168,16 @@ func (o *Options) Run() error {
 
 	logrus.Infof("Starting cl-controlplane (version: %s)", versioninfo.Short())
 
+	shutdownTracing, err := tracing.Init(context.Background(), "cl-controlplane", o.TracingEndpoint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.Errorf("Cannot shutdown tracing: %v", err)
+		}
+	}()
+
 	namespace := os.Getenv(NamespaceEnvVariable)
 	if namespace == "" {
 		namespace = SystemNamespace
@@ -178,6 +228,8 @@ func (o *Options) Run() error {
 				},
 			},
 		},
+		// metrics are served by the controlplane HTTP server
+		Metrics:                 metricsserver.Options{BindAddress: "0"},
 		LeaderElection:          true,
 		LeaderElectionNamespace: namespace,
 		LeaderElectionID:        "cl-controlplane",
@@ -194,8 +246,29 @@ func (o *Options) Run() error {
 	grpcServer := grpc.NewServer("controlplane-grpc", controlplaneCertData.ServerConfig())
 
 	authzManager := authz.NewManager(mgr.GetClient(), namespace, o.PeerLabels)
+	authzManager.SetSignAttributes(o.SignPeerAttributes)
+	authzManager.SetRequireSignedAttributes(o.RequireSignedPeerAttributes)
+	authzManager.SetRevocationGracePeriod(o.RevocationGracePeriod)
 	peerCertsWatcher.AddConsumer(authzManager)
 
+	if o.AuditLogFile != "" {
+		auditFile, err := audit.OpenFile(o.AuditLogFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := auditFile.Close(); err != nil {
+				logrus.Errorf("Cannot close audit log file: %v", err)
+			}
+		}()
+
+		auditLogger, err := audit.NewLogger(audit.NewJSONSink(auditFile), o.AuditSampleRate)
+		if err != nil {
+			return err
+		}
+		authzManager.SetAuditLogger(auditLogger)
+	}
+
 	err = authz.CreateControllers(authzManager, mgr)
 	if err != nil {
 		return fmt.Errorf("cannot create authz controllers: %w", err)
@@ -204,6 +277,7 @@ func (o *Options) Run() error {
 	authz.RegisterService(authzManager, grpcServer.GetGRPCServer())
 
 	controlManager := control.NewManager(mgr.GetClient(), namespace)
+	controlManager.SetJWKRotationInterval(o.JWTKeyRotationInterval)
 	peerCertsWatcher.AddConsumer(controlManager)
 	if err := controlManager.CreateJWKSSecret(context.Background()); err != nil {
 		return fmt.Errorf("cannot create JWKS secret: %w", err)
@@ -215,6 +289,8 @@ func (o *Options) Run() error {
 	}
 
 	xdsManager := xds.NewManager()
+	xdsManager.SetRevocationGracePeriod(o.RevocationGracePeriod)
+	authzManager.SetConnectionRevoker(xdsManager)
 	xds.RegisterService(
 		context.Background(), xdsManager, grpcServer.GetGRPCServer())
 	peerCertsWatcher.AddConsumer(xdsManager)
@@ -250,6 +326,8 @@ func (o *Options) Run() error {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		}
 	})
+	authz.RegisterExplainHandler(authzManager, httpServer.Router())
+	httpServer.Router().Handle(api.MetricsPath, promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
 
 	runnableManager := runnable.NewManager()
 	runnableManager.Add(peerCertsWatcher)
@@ -18,6 +18,8 @@ package main
 
 import (
 	"os"
+	// Embedding the time zone database, used by scheduled access policies, as the image may not include it.
+	_ "time/tzdata"
 
 	"github.com/clusterlink-net/clusterlink/cmd/cl-controlplane/app"
 	"github.com/clusterlink-net/clusterlink/pkg/versioninfo"
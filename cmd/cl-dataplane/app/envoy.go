@@ -54,8 +54,20 @@ func (o *Options) runEnvoy(dataplaneID string) error {
 		"certificateSecret": cpapi.CertificateSecret,
 		"validationSecret":  cpapi.ValidationSecret,
 
-		"authorizationHeader": cpapi.AuthorizationHeader,
-		"targetClusterHeader": cpapi.TargetClusterHeader,
+		"authorizationHeader":     cpapi.AuthorizationHeader,
+		"peerAuthorizationHeader": cpapi.PeerAuthorizationHeader,
+		"targetClusterHeader":     cpapi.TargetClusterHeader,
+
+		"connectUDPPathPrefix": cpapi.ConnectUDPPathPrefix,
+
+		"egressAccessLogName":   cpapi.EgressAccessLogName,
+		"ingressAccessLogName":  cpapi.IngressAccessLogName,
+		"importNameHeader":      cpapi.ImportNameHeader,
+		"importNamespaceHeader": cpapi.ImportNamespaceHeader,
+		"importPortHeader":      cpapi.ImportPortHeader,
+		"clientIPHeader":        cpapi.ClientIPHeader,
+		"importSourceHeader":    cpapi.ImportSourceHeader,
+		"filterChainHeader":     cpapi.FilterChainHeader,
 	}
 
 	var envoyConf bytes.Buffer
@@ -115,6 +115,8 @@ static_resources:
         typed_config:
           "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
           stat_prefix: hcm-egress
+          http2_protocol_options:
+            allow_connect: true
           route_config:
             virtual_hosts:
             - name: egress
@@ -125,8 +127,39 @@ static_resources:
                 route:
                   cluster_header: {{.targetClusterHeader}}
                   auto_host_rewrite: true
+              - match:
+                  prefix: {{.connectUDPPathPrefix}}
+                route:
+                  cluster_header: {{.targetClusterHeader}}
+                  auto_host_rewrite: true
+              - match:
+                  prefix: /
+                route:
+                  cluster_header: {{.targetClusterHeader}}
+                  auto_host_rewrite: true
+                  timeout: 0s
           upgrade_configs:
           - upgrade_type: CONNECT
+          - upgrade_type: CONNECT-UDP
+          access_log:
+          - name: envoy.access_loggers.http_grpc
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig
+              common_config:
+                log_name: {{.egressAccessLogName}}
+                transport_api_version: V3
+                grpc_service:
+                  envoy_grpc:
+                    cluster_name: {{.controlplaneCluster}}
+              additional_request_headers_to_log:
+              - {{.importNameHeader}}
+              - {{.importNamespaceHeader}}
+              - {{.importPortHeader}}
+              - {{.clientIPHeader}}
+              - {{.importSourceHeader}}
+              - {{.filterChainHeader}}
+          access_log_options:
+            flush_log_on_tunnel_successfully_established: true
           http_filters:
           - name: envoy.filters.http.ext_authz
             typed_config:
@@ -177,6 +210,22 @@ static_resources:
             - name: ingress
               domains: ["*"]
               routes:
+              - match:
+                  prefix: /
+                  headers:
+                  - name: {{.peerAuthorizationHeader}}
+                    present_match: true
+                route:
+                  cluster_header: {{.targetClusterHeader}}
+                  auto_host_rewrite: true
+                  timeout: 0s
+                request_headers_to_remove:
+                - {{.peerAuthorizationHeader}}
+                - {{.importNameHeader}}
+                - {{.importNamespaceHeader}}
+                - {{.importPortHeader}}
+                - {{.clientIPHeader}}
+                - {{.importSourceHeader}}
               - match:
                   connect_matcher: {}
                 route:
@@ -184,12 +233,33 @@ static_resources:
                   upgrade_configs:
                   - upgrade_type: CONNECT
                     connect_config: {}
+              - match:
+                  prefix: {{.connectUDPPathPrefix}}
+                route:
+                  cluster_header: {{.targetClusterHeader}}
+                  upgrade_configs:
+                  - upgrade_type: CONNECT-UDP
+                    connect_config: {}
               - match:
                   prefix: /
                 direct_response:
                   status: 200
           upgrade_configs:
           - upgrade_type: CONNECT
+          - upgrade_type: CONNECT-UDP
+          access_log:
+          - name: envoy.access_loggers.http_grpc
+            filter:
+              response_flag_filter:
+                flags: ["UO"]
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig
+              common_config:
+                log_name: {{.ingressAccessLogName}}
+                transport_api_version: V3
+                grpc_service:
+                  envoy_grpc:
+                    cluster_name: {{.controlplaneCluster}}
           http_filters:
           - name: composite
             typed_config:
@@ -223,18 +293,40 @@ static_resources:
                           allowed_headers:
                             patterns:
                             - exact: {{.authorizationHeader}}
+                            - exact: traceparent
+                            - exact: tracestate
                 matcher_list:
                   matchers:
                   - predicate:
-                      single_predicate:
-                        input:
-                          name: method-matcher
-                          typed_config:
-                            "@type": type.googleapis.com/envoy.type.matcher.v3.HttpRequestHeaderMatchInput
-                            header_name: :method
-                        value_match:
-                          exact: CONNECT
-                          ignore_case: true
+                      or_matcher:
+                        predicate:
+                        - single_predicate:
+                            input:
+                              name: method-matcher
+                              typed_config:
+                                "@type": type.googleapis.com/envoy.type.matcher.v3.HttpRequestHeaderMatchInput
+                                header_name: :method
+                            value_match:
+                              exact: CONNECT
+                              ignore_case: true
+                        - single_predicate:
+                            input:
+                              name: upgrade-matcher
+                              typed_config:
+                                "@type": type.googleapis.com/envoy.type.matcher.v3.HttpRequestHeaderMatchInput
+                                header_name: upgrade
+                            value_match:
+                              exact: connect-udp
+                              ignore_case: true
+                        - single_predicate:
+                            input:
+                              name: peer-authorization-matcher
+                              typed_config:
+                                "@type": type.googleapis.com/envoy.type.matcher.v3.HttpRequestHeaderMatchInput
+                                header_name: {{.peerAuthorizationHeader}}
+                            value_match:
+                              safe_regex:
+                                regex: ".*"
                     on_match:
                       action:
                         name: connect-action
@@ -257,6 +349,7 @@ static_resources:
                               allowed_headers:
                                 patterns:
                                 - exact: {{.authorizationHeader}}
+                                - exact: {{.peerAuthorizationHeader}}
           - name: envoy.filters.http.router
             typed_config:
               "@type": type.googleapis.com/envoy.extensions.filters.http.router.v3.Router
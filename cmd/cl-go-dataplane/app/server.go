@@ -14,7 +14,9 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -22,6 +24,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -36,6 +39,7 @@ import (
 	utilhttp "github.com/clusterlink-net/clusterlink/pkg/util/http"
 	"github.com/clusterlink-net/clusterlink/pkg/util/log"
 	"github.com/clusterlink-net/clusterlink/pkg/util/tls"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 const (
@@ -58,6 +62,14 @@ type Options struct {
 	LogFile string
 	// LogLevel is the log level.
 	LogLevel string
+	// TracingEndpoint is the address of an OpenTelemetry collector to which traces will be exported.
+	// If empty, traces are not exported.
+	TracingEndpoint string
+	// FlowLogFile is the path to a file where records of completed flows will be written.
+	// If empty, flow records are only kept in memory.
+	FlowLogFile string
+	// PeerMultiplexing enables multiplexing connections to each peer over shared HTTP/2 connections.
+	PeerMultiplexing bool
 }
 
 // AddFlags adds flags to fs and binds them to options.
@@ -68,6 +80,15 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"Path to a file where logs will be written. If not specified, logs will be printed to stderr.")
 	fs.StringVar(&o.LogLevel, "log-level", logLevel,
 		"The log level. One of fatal, error, warn, info, debug.")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", "",
+		"Address (host:port) of an OpenTelemetry collector to which traces will be exported using OTLP over gRPC. "+
+			"If not specified, traces are not exported.")
+	fs.StringVar(&o.FlowLogFile, "flow-log-file", "",
+		"Path to a file where records of completed flows will be written (one JSON record per line). "+
+			"Use '-' for stdout. If not specified, only recent flows are kept in memory.")
+	fs.BoolVar(&o.PeerMultiplexing, "peer-multiplexing", true,
+		"Multiplex connections to each peer over shared HTTP/2 connections. "+
+			"Peers not supporting multiplexing are connected using a dedicated TLS connection per connection.")
 }
 
 // RequiredFlags are the names of flags that must be explicitly specified.
@@ -98,10 +119,26 @@ func (o *Options) runGoDataplane(dataplaneID string, parsedCertData *tls.ParsedC
 
 	dataplaneServerAddress := fmt.Sprintf(":%d", api.ListenPort)
 	dataplane := dpserver.NewDataplane(dataplaneID, controlplaneClient, parsedCertData)
+	dataplane.SetPeerMultiplexing(o.PeerMultiplexing)
+	if o.FlowLogFile != "" {
+		flowLogFile, err := openFlowLogFile(o.FlowLogFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := flowLogFile.Close(); err != nil {
+				logrus.Errorf("Cannot close flow log file: %v", err)
+			}
+		}()
+
+		dataplane.SetFlowSink(dpserver.NewJSONFlowSink(flowLogFile))
+	}
+
 	go func() {
 		err := dataplane.StartDataplaneServer(dataplaneServerAddress)
 		logrus.Errorf("Failed to start dataplane server: %v.", err)
 	}()
+	go dataplane.RunAccessLogger()
 
 	xdsClient := dpclient.NewXDSClient(dataplane, controlplaneClient)
 
@@ -115,6 +152,8 @@ func (o *Options) runGoDataplane(dataplaneID string, parsedCertData *tls.ParsedC
 			w.WriteHeader(http.StatusServiceUnavailable)
 		}
 	})
+	httpServer.Router().Handle(api.MetricsPath, promhttp.Handler())
+	dataplane.RegisterFlowsHandler(httpServer.Router())
 	go func() {
 		err := httpServer.Start()
 		logrus.Errorf("Failed to start readiness server: %v.", err)
@@ -125,6 +164,21 @@ func (o *Options) runGoDataplane(dataplaneID string, parsedCertData *tls.ParsedC
 	return fmt.Errorf("xDS Client stopped: %w", err)
 }
 
+// openFlowLogFile opens a file for appending flow records.
+// The path "-" stands for the standard output.
+func openFlowLogFile(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening flow log file: %w", err)
+	}
+
+	return f, nil
+}
+
 // Run the dataplane.
 func (o *Options) Run() error {
 	f, err := log.Set(o.LogLevel, o.LogFile)
@@ -139,6 +193,16 @@ func (o *Options) Run() error {
 		}()
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background(), "cl-go-dataplane", o.TracingEndpoint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.Errorf("Cannot shutdown tracing: %v", err)
+		}
+	}()
+
 	// parse TLS files
 	parsedCertData, _, err := tls.ParseFiles(CAFile, CertificateFile, KeyFile)
 	if err != nil {
@@ -19,6 +19,7 @@ import (
 	"github.com/clusterlink-net/clusterlink/cmd/clusterlink/cmd/create"
 	deletion "github.com/clusterlink-net/clusterlink/cmd/clusterlink/cmd/delete"
 	"github.com/clusterlink-net/clusterlink/cmd/clusterlink/cmd/deploy"
+	"github.com/clusterlink-net/clusterlink/cmd/clusterlink/cmd/policy"
 )
 
 // NewCLADMCommand returns a cobra.Command to run the clusterlink command.
@@ -33,6 +34,7 @@ func NewCLADMCommand() *cobra.Command {
 	cmds.AddCommand(create.NewCmdCreate())
 	cmds.AddCommand(deploy.NewCmdDeploy())
 	cmds.AddCommand(deletion.NewCmdDelete())
+	cmds.AddCommand(policy.NewCmdPolicy())
 
 	return cmds
 }
@@ -0,0 +1,183 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	// Importing this package for initializing the OIDC authentication plugin for client-go.
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+
+	"github.com/clusterlink-net/clusterlink/cmd/cl-controlplane/app"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+// ExplainOptions contains everything necessary to create and run a 'policy explain' subcommand.
+type ExplainOptions struct {
+	// FromPod is the source pod (namespace/name).
+	FromPod string
+	// ToImport is the target import (namespace/name).
+	ToImport string
+	// Port is the port name of the target import.
+	Port string
+	// Namespace where the ClusterLink components are deployed.
+	Namespace string
+}
+
+// NewCmdPolicyExplain returns a cobra.Command to run the 'policy explain' subcommand.
+func NewCmdPolicyExplain() *cobra.Command {
+	opts := &ExplainOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Explain the access policy decisions on connections from a pod to an imported service.",
+		Long: `Explain the access policy decisions on connections from a pod to an imported service.
+For each of the import sources, prints the decision and the deciding policy, without opening any connection.`,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return opts.Run()
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+
+	for _, flag := range opts.RequiredFlags() {
+		if err := cmd.MarkFlagRequired(flag); err != nil {
+			fmt.Printf("Error marking required flag '%s': %v\n", flag, err)
+			os.Exit(1)
+		}
+	}
+
+	return cmd
+}
+
+// AddFlags adds flags to fs and binds them to options.
+func (o *ExplainOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.FromPod, "from-pod", "", "Source pod, in the form of namespace/name.")
+	fs.StringVar(&o.ToImport, "to-import", "", "Target import, in the form of namespace/name.")
+	fs.StringVar(&o.Port, "port", "", "Port name of the target import. May be omitted for imports with a single port.")
+	fs.StringVar(&o.Namespace, "namespace", app.SystemNamespace,
+		"Namespace where the ClusterLink components are deployed.")
+}
+
+// RequiredFlags are the names of flags that must be explicitly specified.
+func (o *ExplainOptions) RequiredFlags() []string {
+	return []string{"from-pod", "to-import"}
+}
+
+// Run the 'policy explain' subcommand.
+func (o *ExplainOptions) Run() error {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pod, err := o.getControlplanePod(ctx, clientset)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		cpapi.PolicyExplainFromPodParam:  o.FromPod,
+		cpapi.PolicyExplainToImportParam: o.ToImport,
+		cpapi.PolicyExplainPortParam:     o.Port,
+	}
+	body, err := clientset.CoreV1().Pods(o.Namespace).ProxyGet(
+		"http", pod, strconv.Itoa(cpapi.ReadinessListenPort), cpapi.PolicyExplainPath, params,
+	).DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot explain policy decisions: %w", err)
+	}
+
+	var explanation cpapi.PolicyExplanation
+	if err := json.Unmarshal(body, &explanation); err != nil {
+		return fmt.Errorf("cannot decode policy explanation: %w", err)
+	}
+
+	return o.print(os.Stdout, &explanation)
+}
+
+// getControlplanePod returns the name of a running controlplane pod.
+func (o *ExplainOptions) getControlplanePod(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	pods, err := clientset.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + cpapi.Name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list controlplane pods: %w", err)
+	}
+
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == v1.PodRunning {
+			return pods.Items[i].Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no running controlplane pod in namespace '%s'", o.Namespace)
+}
+
+// print writes the policy explanation in a human-readable form.
+func (o *ExplainOptions) print(w io.Writer, explanation *cpapi.PolicyExplanation) error {
+	fmt.Fprintf(w, "Source attributes of %s:\n", o.FromPod)
+	keys := make([]string, 0, len(explanation.SrcAttributes))
+	for k := range explanation.SrcAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s=%s\n", k, explanation.SrcAttributes[k])
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PEER\tEXPORT\tDECISION\tPOLICY\tTIER")
+	for _, source := range explanation.Sources {
+		export := source.ExportNamespace + "/" + source.ExportName
+		if source.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror: %s\t\t\n", source.Peer, export, source.Error)
+			continue
+		}
+
+		tier := "regular"
+		switch {
+		case source.MatchedBy == connectivitypdp.DefaultDenyPolicyName:
+			tier = "-"
+		case source.Privileged:
+			tier = "privileged"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", source.Peer, export, source.Decision, source.MatchedBy, tier)
+	}
+
+	return tw.Flush()
+}
@@ -15,12 +15,19 @@ package v1alpha1
 
 import (
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusterlink-net/clusterlink/pkg/util/cron"
 )
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Accepted",type=string,JSONPath=`.status.conditions[?(@.type=="AccessPolicyAccepted")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // AccessPolicy defines whether a set of connections should be allowed or denied.
 // If multiple AccessPolicy objects match a given connection, deny policies take
@@ -31,10 +38,16 @@ type AccessPolicy struct {
 
 	// Spec represents the attributes of the exported service.
 	Spec AccessPolicySpec `json:"spec,omitempty"`
+	// Status represents the status of the access policy.
+	Status AccessPolicyStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Accepted",type=string,JSONPath=`.status.conditions[?(@.type=="AccessPolicyAccepted")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PrivilegedAccessPolicy is the cluster-scoped version of AccessPolicy.
 // PrivilegedAccessPolicies are intended to be used by cluster admins, and take precedence over AccessPolicies.
@@ -45,6 +58,8 @@ type PrivilegedAccessPolicy struct {
 
 	// Spec represents the attributes of the exported service.
 	Spec AccessPolicySpec `json:"spec,omitempty"`
+	// Status represents the status of the access policy.
+	Status AccessPolicyStatus `json:"status,omitempty"`
 }
 
 // AccessPolicyAction specifies whether an AccessPolicy allows or denies
@@ -62,12 +77,26 @@ type WorkloadSetOrSelectorList []WorkloadSetOrSelector
 // WorkloadSetOrSelector describes a set of workloads, based on their attributes (labels).
 // Exactly one of the two fields should be non-empty.
 type WorkloadSetOrSelector struct {
-	// WorkloadSets allows specifying predefined sets of workloads - not yet supported.
+	// WorkloadSets allows specifying predefined sets of workloads, by their names.
+	// AccessPolicies reference WorkloadSets in their namespace,
+	// and PrivilegedAccessPolicies reference PrivilegedWorkloadSets.
+	// A workload matches if it is in any of the sets. A reference to a missing set matches no workload,
+	// unless referenced by a deny policy, where it matches all workloads.
 	WorkloadSets []string `json:"workloadSets,omitempty"`
 	// WorkloadSelector is a K8s-style label selector, selecting Pods and Services according to their labels.
 	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
 }
 
+// AccessPolicyPort specifies a destination service port to which an access policy refers.
+type AccessPolicyPort struct {
+	// Name of the service port, as set in the Import and Export ports.
+	// If empty, all ports match.
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:Enum=TCP;UDP
+	// Protocol of the service (TCP or UDP). If empty, all protocols match.
+	Protocol Protocol `json:"protocol,omitempty"`
+}
+
 // AccessPolicySpec specifies the connections AccessPolicy and PrivilegedAccessPolicy make decisions on
 // as well as the policy's decision on these connection.
 type AccessPolicySpec struct {
@@ -77,6 +106,41 @@ type AccessPolicySpec struct {
 	From WorkloadSetOrSelectorList `json:"from"`
 	// To specifies the set of destination services to which this policy refers.
 	To WorkloadSetOrSelectorList `json:"to"`
+	// Ports, if set, restricts the policy to connections to the given destination service ports.
+	// A connection matches if it matches any of the ports. If empty, connections to all ports match.
+	Ports []AccessPolicyPort `json:"ports,omitempty"`
+	// Priority, if set, orders the policy within its tier (privileged or regular).
+	// Prioritized policies are evaluated before policies with no priority, by ascending priority,
+	// and then by name. The first prioritized policy to match a connection takes the decision,
+	// regardless of its action. Among policies with no priority, deny policies take precedence over allow policies.
+	Priority *int32 `json:"priority,omitempty"`
+	// NotBefore, if set, is the time before which the policy is not in effect.
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// NotAfter, if set, is the time after which the policy is no longer in effect.
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+	// Schedule, if set, restricts the policy to recurring time windows (within NotBefore and NotAfter).
+	Schedule *AccessPolicySchedule `json:"schedule,omitempty"`
+	// DeleteAfterExpiry, if true, the policy is deleted once its NotAfter time passes.
+	DeleteAfterExpiry bool `json:"deleteAfterExpiry,omitempty"`
+}
+
+// AccessPolicySchedule specifies recurring time windows during which an access policy is in effect.
+type AccessPolicySchedule struct {
+	// Cron is a standard 5-field cron expression (minute, hour, day of month, month, day of week),
+	// selecting the minutes during which the policy is in effect.
+	// For example, "* 9-16 * * 1-5" selects 09:00-16:59 on weekdays.
+	Cron string `json:"cron"`
+	// TimeZone is the IANA time zone name in which the cron expression is evaluated. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Location returns the time zone in which the schedule is evaluated.
+func (s *AccessPolicySchedule) Location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(s.TimeZone)
 }
 
 // +kubebuilder:object:root=true
@@ -103,6 +167,24 @@ type AccessPolicyList struct {
 	Items []AccessPolicy `json:"items"`
 }
 
+const (
+	// AccessPolicyAccepted is a condition type for indicating whether the policy is valid and in force.
+	AccessPolicyAccepted string = "AccessPolicyAccepted"
+	// AccessPolicyExpired is a condition type for indicating whether the NotAfter time of the policy has passed.
+	AccessPolicyExpired string = "AccessPolicyExpired"
+	// AccessPolicyWorkloadSetsResolved is a condition type for indicating whether
+	// all workload sets referenced by the policy exist.
+	AccessPolicyWorkloadSetsResolved string = "AccessPolicyWorkloadSetsResolved"
+)
+
+// AccessPolicyStatus represents the status of an access policy.
+type AccessPolicyStatus struct {
+	// ObservedGeneration is the most recent generation of the access policy handled by the controlplane.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the access policy.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
 // Validate returns an error if the given AccessPolicy is invalid. Otherwise, returns nil.
 func (p *AccessPolicySpec) Validate() error {
 	if p.Action != AccessPolicyActionAllow && p.Action != AccessPolicyActionDeny {
@@ -117,7 +199,26 @@ func (p *AccessPolicySpec) Validate() error {
 	if len(p.To) == 0 {
 		return fmt.Errorf("empty To field is not allowed")
 	}
-	return p.To.validate()
+	if err := p.To.validate(); err != nil {
+		return err
+	}
+	for i := range p.Ports {
+		if protocol := p.Ports[i].Protocol; protocol != "" && protocol != ProtocolTCP && protocol != ProtocolUDP {
+			return fmt.Errorf("unsupported port protocol %s", protocol)
+		}
+	}
+	if p.NotBefore != nil && p.NotAfter != nil && !p.NotBefore.Before(p.NotAfter) {
+		return fmt.Errorf("NotBefore must be earlier than NotAfter")
+	}
+	if p.Schedule != nil {
+		if _, err := cron.Parse(p.Schedule.Cron); err != nil {
+			return err
+		}
+		if _, err := p.Schedule.Location(); err != nil {
+			return fmt.Errorf("invalid schedule time zone: %w", err)
+		}
+	}
+	return nil
 }
 
 func (wsl WorkloadSetOrSelectorList) validate() error {
@@ -136,7 +237,7 @@ func (wss *WorkloadSetOrSelector) validate() error {
 		return fmt.Errorf("exactly one of WorkloadSets or WorkloadSelector must be set")
 	}
 	if len(wss.WorkloadSets) > 0 {
-		return fmt.Errorf("workload sets are not yet supported")
+		return nil
 	}
 	_, err := metav1.LabelSelectorAsSelector(wss.WorkloadSelector)
 	return err
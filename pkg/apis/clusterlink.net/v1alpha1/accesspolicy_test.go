@@ -48,4 +48,12 @@ func TestValidation(t *testing.T) {
 	badPolicy.Spec.To = []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet}
 	err = badPolicy.Spec.Validate()
 	require.Nil(t, err)
+
+	badPolicy.Spec.Ports = []v1alpha1.AccessPolicyPort{{Name: "admin", Protocol: "SCTP"}}
+	err = badPolicy.Spec.Validate()
+	require.NotNil(t, err) // protocol is not a legal protocol
+
+	badPolicy.Spec.Ports = []v1alpha1.AccessPolicyPort{{Name: "admin", Protocol: v1alpha1.ProtocolTCP}, {}}
+	err = badPolicy.Spec.Validate()
+	require.Nil(t, err)
 }
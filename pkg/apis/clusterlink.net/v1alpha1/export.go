@@ -32,14 +32,113 @@ type Export struct {
 	Status ExportStatus `json:"status,omitempty"`
 }
 
+// Protocol represents the transport protocol of a shared service.
+type Protocol string
+
+const (
+	ProtocolTCP Protocol = "TCP"
+	ProtocolUDP Protocol = "UDP"
+
+	ProtocolDefault = ProtocolTCP
+)
+
+// AppProtocol represents the application protocol of a shared service.
+type AppProtocol string
+
+const (
+	// AppProtocolTCP forwards the connections to the service as opaque byte streams.
+	AppProtocolTCP AppProtocol = "tcp"
+	// AppProtocolHTTP forwards the HTTP requests to the service, each request separately.
+	AppProtocolHTTP AppProtocol = "http"
+)
+
+// ExportPort represents a named port of an exported service.
+type ExportPort struct {
+	// Name of the port. Imports refer to the exported port using this name.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Port of the exported service.
+	Port uint16 `json:"port"`
+}
+
 // ExportSpec contains all attributes of an exported service.
+// +kubebuilder:validation:XValidation:rule="!has(self.ports) || !has(self.port)",message="only one of port and ports may be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.appProtocol) || self.appProtocol != 'http' || !has(self.protocol) || self.protocol != 'UDP'",message="appProtocol http requires protocol TCP"
 type ExportSpec struct {
 	// Host of the exported service.
 	// If empty, export will point to a service with the same
 	// name and namespace as the export object.
 	Host string `json:"host,omitempty"`
 	// Port of the exported service.
+	// Exporting a single port by number is equivalent to exporting a single unnamed port.
 	Port uint16 `json:"port,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	// Ports of the exported service. Cannot be used together with Port.
+	Ports []ExportPort `json:"ports,omitempty"`
+	// +kubebuilder:validation:Enum=TCP;UDP
+	// +kubebuilder:default="TCP"
+	// Protocol of the exported service (TCP or UDP).
+	Protocol Protocol `json:"protocol,omitempty"`
+	// +kubebuilder:validation:Enum=tcp;http
+	// AppProtocol is the application protocol of the exported service (tcp or http).
+	// Setting it to http allows importing peers to send HTTP requests to the service, each request separately.
+	// Defaults to tcp.
+	AppProtocol AppProtocol `json:"appProtocol,omitempty"`
+	// RateLimit limits the connections from remote peers to the exported service.
+	RateLimit *ExportRateLimit `json:"rateLimit,omitempty"`
+}
+
+// RateLimitScope determines how connections are grouped when limiting their rate.
+type RateLimitScope string
+
+const (
+	// RateLimitScopeExport limits the connections to the exported service together.
+	RateLimitScopeExport RateLimitScope = "Export"
+	// RateLimitScopePeer limits the connections from each remote peer separately.
+	RateLimitScopePeer RateLimitScope = "Peer"
+	// RateLimitScopeClientNamespace limits the connections from each client namespace
+	// of each remote peer separately.
+	RateLimitScopeClientNamespace RateLimitScope = "ClientNamespace"
+)
+
+// ExportRateLimit limits the connections to an exported service.
+// Connections exceeding the limits are rejected and counted in the export status.
+type ExportRateLimit struct {
+	// ConnectionsPerSecond is the maximal rate of new connections, enforced by each controlplane instance
+	// when authorizing connections. Zero means no limit.
+	ConnectionsPerSecond uint32 `json:"connectionsPerSecond,omitempty"`
+	// Burst is the maximal number of new connections allowed at once.
+	// Defaults to ConnectionsPerSecond.
+	Burst uint32 `json:"burst,omitempty"`
+	// +kubebuilder:validation:Enum=Export;Peer;ClientNamespace
+	// +kubebuilder:default="Export"
+	// Per determines how connections are grouped when limiting their rate (Export, Peer or ClientNamespace).
+	Per RateLimitScope `json:"per,omitempty"`
+	// MaxConnections is the maximal number of concurrent connections to the exported service,
+	// enforced by each dataplane instance. Zero means no limit.
+	MaxConnections uint32 `json:"maxConnections,omitempty"`
+}
+
+// ServicePorts returns the ports of the exported service.
+// A single Port is returned as an unnamed port.
+func (s *ExportSpec) ServicePorts() []ExportPort {
+	if len(s.Ports) > 0 {
+		return s.Ports
+	}
+
+	return []ExportPort{{Port: s.Port}}
+}
+
+// ServicePort returns the exported port with the given name.
+func (s *ExportSpec) ServicePort(name string) (ExportPort, bool) {
+	for _, port := range s.ServicePorts() {
+		if port.Name == name {
+			return port, true
+		}
+	}
+
+	return ExportPort{}, false
 }
 
 const (
@@ -51,6 +150,8 @@ const (
 type ExportStatus struct {
 	// Conditions of the export.
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// RejectedConnections is the number of connections rejected due to the export rate limit.
+	RejectedConnections int64 `json:"rejectedConnections,omitempty"`
 }
 
 // +kubebuilder:object:root=true
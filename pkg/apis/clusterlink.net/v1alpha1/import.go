@@ -14,6 +14,10 @@
 package v1alpha1
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -40,6 +44,11 @@ type ImportSource struct {
 	ExportName string `json:"exportName"`
 	// ExportNamespace is the namespace of the exported service.
 	ExportNamespace string `json:"exportNamespace"`
+	// +kubebuilder:validation:Minimum=1
+	// Weight of the source, used by the weighted load-balancing scheme.
+	// The share of connections routed to the source is its weight divided by the sum of all weights.
+	// Defaults to 1.
+	Weight uint32 `json:"weight,omitempty"`
 }
 
 // LBScheme represents a load balancing scheme.
@@ -49,22 +58,249 @@ const (
 	LBSchemeRandom     LBScheme = "random"
 	LBSchemeRoundRobin LBScheme = "round-robin"
 	LBSchemeStatic     LBScheme = "static"
+	// LBSchemeWeighted randomly selects sources, proportionally to their weights.
+	LBSchemeWeighted LBScheme = "weighted"
+	// LBSchemeLocality prefers sources whose peer is in the same zone, and then region, as the local peer.
+	// Other sources are selected only if all preferred sources fail.
+	LBSchemeLocality LBScheme = "locality"
+	// LBSchemeLeastConnections selects the source whose peer has the least active connections.
+	LBSchemeLeastConnections LBScheme = "least-connections"
+	// LBSchemeLeastLatency selects the source whose peer has the lowest connection setup latency.
+	LBSchemeLeastLatency LBScheme = "least-latency"
 
 	LBSchemeDefault = LBSchemeRoundRobin
 )
 
+// ImportPort represents a named port of an imported service.
+type ImportPort struct {
+	// Name of the port. Must match the name of the exported port it is imported from.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Port of the imported service.
+	Port uint16 `json:"port"`
+	// TargetPort of the imported port.
+	// This is the internal (non user-facing) listening port used by the dataplane pods.
+	TargetPort uint16 `json:"targetPort,omitempty"`
+}
+
 // ImportSpec contains all attributes of an imported service.
+// +kubebuilder:validation:XValidation:rule="has(self.ports) != has(self.port)",message="exactly one of port and ports must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.appProtocol) || self.appProtocol != 'http' || !has(self.protocol) || self.protocol != 'UDP'",message="appProtocol http requires protocol TCP"
+// +kubebuilder:validation:XValidation:rule="!has(self.http) || (has(self.appProtocol) && self.appProtocol == 'http')",message="http requires appProtocol http"
 type ImportSpec struct {
 	// Port of the imported service.
-	Port uint16 `json:"port"`
+	// Importing a single port by number is equivalent to importing a single unnamed port.
+	Port uint16 `json:"port,omitempty"`
 	// TargetPort of the imported service.
 	// This is the internal (non user-facing) listening port used by the dataplane pods.
 	TargetPort uint16 `json:"targetPort,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	// Ports of the imported service. Cannot be used together with Port.
+	Ports []ImportPort `json:"ports,omitempty"`
+	// +kubebuilder:validation:Enum=TCP;UDP
+	// +kubebuilder:default="TCP"
+	// Protocol of the imported service (TCP or UDP).
+	// Must match the protocol of the exported services it is imported from.
+	Protocol Protocol `json:"protocol,omitempty"`
+	// +kubebuilder:validation:Enum=tcp;http
+	// AppProtocol is the application protocol of the imported service (tcp or http).
+	// Setting it to http authorizes and load-balances each HTTP request separately, instead of each connection,
+	// and requires the exported services to be exported with appProtocol http. Defaults to tcp.
+	AppProtocol AppProtocol `json:"appProtocol,omitempty"`
+	// HTTP configures the handling of HTTP requests. Requires AppProtocol http.
+	HTTP *ImportHTTP `json:"http,omitempty"`
 	// Sources to import from.
 	Sources []ImportSource `json:"sources"`
 	// +kubebuilder:default="round-robin"
-	// LBScheme is the load-balancing scheme to use (e.g., random, static, round-robin)
+	// LBScheme is the load-balancing scheme to use (e.g., random, static, round-robin, weighted, locality,
+	// least-connections, least-latency)
 	LBScheme LBScheme `json:"lbScheme"`
+	// SessionAffinity, if set, routes consecutive connections from the same client to the same source,
+	// as long as it stays reachable and allowed.
+	SessionAffinity *SessionAffinity `json:"sessionAffinity,omitempty"`
+	// OutlierDetection, if set, ejects sources which repeatedly fail connections.
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
+	// CircuitBreaker, if set, limits the connections to each source.
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// SessionAffinityType is the client identity by which session affinity is kept.
+type SessionAffinityType string
+
+const (
+	// SessionAffinityClientIP keeps session affinity by the client IP address.
+	SessionAffinityClientIP SessionAffinityType = "ClientIP"
+	// SessionAffinityClientPod keeps session affinity by the client pod.
+	SessionAffinityClientPod SessionAffinityType = "ClientPod"
+	// SessionAffinityClientServiceAccount keeps session affinity by the client service account.
+	SessionAffinityClientServiceAccount SessionAffinityType = "ClientServiceAccount"
+
+	// DefaultSessionAffinityTimeoutSeconds is the default session affinity timeout (3 hours).
+	DefaultSessionAffinityTimeoutSeconds int32 = 10800
+)
+
+// SessionAffinity configures routing of consecutive connections from the same client to the same import source.
+type SessionAffinity struct {
+	// +kubebuilder:validation:Enum=ClientIP;ClientPod;ClientServiceAccount
+	// Type is the client identity by which session affinity is kept.
+	Type SessionAffinityType `json:"type"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10800
+	// TimeoutSeconds is the time since the last connection of a client after which its session affinity expires.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// Timeout returns the session affinity timeout.
+func (a *SessionAffinity) Timeout() time.Duration {
+	if a.TimeoutSeconds <= 0 {
+		return time.Duration(DefaultSessionAffinityTimeoutSeconds) * time.Second
+	}
+
+	return time.Duration(a.TimeoutSeconds) * time.Second
+}
+
+// ServicePorts returns the ports of the imported service.
+// A single Port is returned as an unnamed port.
+func (s *ImportSpec) ServicePorts() []ImportPort {
+	if len(s.Ports) > 0 {
+		return s.Ports
+	}
+
+	return []ImportPort{{Port: s.Port, TargetPort: s.TargetPort}}
+}
+
+// ServicePort returns the imported port with the given name.
+func (s *ImportSpec) ServicePort(name string) (ImportPort, bool) {
+	for _, port := range s.ServicePorts() {
+		if port.Name == name {
+			return port, true
+		}
+	}
+
+	return ImportPort{}, false
+}
+
+// ImportHTTP configures the handling of HTTP requests to an imported service.
+type ImportHTTP struct {
+	// Retries is the maximal number of times a request is retried, if failing to connect or
+	// failing with a 5xx status. Each retry may be routed to a different source.
+	// Requests with large bodies may not be retried.
+	Retries uint32 `json:"retries,omitempty"`
+	// Routes route requests, by their headers, to a subset of the sources.
+	// A request is routed by the first route it matches.
+	// Requests matching no route are routed to any of the sources.
+	Routes []ImportHTTPRoute `json:"routes,omitempty"`
+}
+
+// ImportHTTPRoute routes HTTP requests with matching headers to the sources of specific peers.
+type ImportHTTPRoute struct {
+	// +kubebuilder:validation:MinProperties=1
+	// Headers which a request must have for matching the route, with the exact given values.
+	// Header names are case-insensitive.
+	Headers map[string]string `json:"headers"`
+	// +kubebuilder:validation:MinItems=1
+	// Peers whose sources matching requests are routed to.
+	Peers []string `json:"peers"`
+}
+
+// Matches returns whether the given request headers (whose names are lowercase) match the route.
+func (r *ImportHTTPRoute) Matches(headers map[string]string) bool {
+	for name, value := range r.Headers {
+		if headers[strings.ToLower(name)] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RouteSources returns the sources which a request with the given headers (whose names are lowercase)
+// may be routed to.
+func (s *ImportSpec) RouteSources(headers map[string]string) []ImportSource {
+	if s.HTTP == nil {
+		return s.Sources
+	}
+
+	for i := range s.HTTP.Routes {
+		route := &s.HTTP.Routes[i]
+		if !route.Matches(headers) {
+			continue
+		}
+
+		var sources []ImportSource
+		for _, source := range s.Sources {
+			if slices.Contains(route.Peers, source.Peer) {
+				sources = append(sources, source)
+			}
+		}
+		return sources
+	}
+
+	return s.Sources
+}
+
+const (
+	// DefaultOutlierConsecutiveFailures is the default number of consecutive failures for ejecting a source.
+	DefaultOutlierConsecutiveFailures uint32 = 5
+	// DefaultOutlierBaseEjectionSeconds is the default base ejection time of a source.
+	DefaultOutlierBaseEjectionSeconds uint32 = 30
+	// DefaultOutlierMaxEjectionSeconds is the default maximal ejection time of a source.
+	DefaultOutlierMaxEjectionSeconds uint32 = 300
+)
+
+// OutlierDetection configures ejection of import sources which repeatedly fail connections.
+// A source is ejected once its consecutive connection failures, as reported by the dataplanes,
+// reach ConsecutiveFailures. The ejection time doubles for every additional consecutive failure,
+// up to MaxEjectionSeconds. A successful connection resets the failures count.
+type OutlierDetection struct {
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=5
+	// ConsecutiveFailures is the number of consecutive connection failures after which a source is ejected.
+	ConsecutiveFailures uint32 `json:"consecutiveFailures,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=30
+	// BaseEjectionSeconds is the ejection time of a source after reaching ConsecutiveFailures.
+	BaseEjectionSeconds uint32 `json:"baseEjectionSeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=300
+	// MaxEjectionSeconds is the maximal ejection time of a source.
+	MaxEjectionSeconds uint32 `json:"maxEjectionSeconds,omitempty"`
+}
+
+// FailureThreshold returns the number of consecutive connection failures after which a source is ejected.
+func (o *OutlierDetection) FailureThreshold() uint32 {
+	if o.ConsecutiveFailures == 0 {
+		return DefaultOutlierConsecutiveFailures
+	}
+
+	return o.ConsecutiveFailures
+}
+
+// BaseEjectionTime returns the ejection time of a source after reaching the failure threshold.
+func (o *OutlierDetection) BaseEjectionTime() time.Duration {
+	if o.BaseEjectionSeconds == 0 {
+		return time.Duration(DefaultOutlierBaseEjectionSeconds) * time.Second
+	}
+
+	return time.Duration(o.BaseEjectionSeconds) * time.Second
+}
+
+// MaxEjectionTime returns the maximal ejection time of a source.
+func (o *OutlierDetection) MaxEjectionTime() time.Duration {
+	if o.MaxEjectionSeconds == 0 {
+		return time.Duration(DefaultOutlierMaxEjectionSeconds) * time.Second
+	}
+
+	return time.Duration(o.MaxEjectionSeconds) * time.Second
+}
+
+// CircuitBreaker configures limits on the connections to import sources.
+type CircuitBreaker struct {
+	// +kubebuilder:validation:Minimum=1
+	// MaxConnections is the maximal number of concurrent connections to each source.
+	// New connections are not routed to a source which reached this limit.
+	MaxConnections uint32 `json:"maxConnections"`
 }
 
 const (
@@ -72,6 +308,9 @@ const (
 	ImportTargetPortValid string = "ImportTargetPortValid"
 	// ImportServiceValid is a condition type for indicating whether the import service exists and valid.
 	ImportServiceValid string = "ImportServiceValid"
+	// ImportSourcesHealthy is a condition type for indicating whether all import sources are available,
+	// i.e. not ejected by outlier detection nor limited by the circuit breaker.
+	ImportSourcesHealthy string = "ImportSourcesHealthy"
 
 	LabelImportMerge string = "import.clusterlink.net/merge"
 )
@@ -0,0 +1,59 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+func TestRouteSources(t *testing.T) {
+	source1 := v1alpha1.ImportSource{Peer: "peer1", ExportName: "svc", ExportNamespace: "ns"}
+	source2 := v1alpha1.ImportSource{Peer: "peer2", ExportName: "svc", ExportNamespace: "ns"}
+	spec := v1alpha1.ImportSpec{
+		Sources:     []v1alpha1.ImportSource{source1, source2},
+		AppProtocol: v1alpha1.AppProtocolHTTP,
+	}
+
+	// no routes
+	require.Equal(t, spec.Sources, spec.RouteSources(map[string]string{"x-version": "v2"}))
+
+	spec.HTTP = &v1alpha1.ImportHTTP{
+		Routes: []v1alpha1.ImportHTTPRoute{{
+			Headers: map[string]string{"X-Version": "v2"},
+			Peers:   []string{"peer2"},
+		}, {
+			Headers: map[string]string{"x-version": "v2", "x-canary": "true"},
+			Peers:   []string{"peer1"},
+		}},
+	}
+
+	// header names are case-insensitive, values are not
+	require.Equal(t, []v1alpha1.ImportSource{source2}, spec.RouteSources(map[string]string{"x-version": "v2"}))
+	require.Equal(t, spec.Sources, spec.RouteSources(map[string]string{"x-version": "V2"}))
+
+	// the first matching route applies
+	require.Equal(t, []v1alpha1.ImportSource{source2},
+		spec.RouteSources(map[string]string{"x-version": "v2", "x-canary": "true"}))
+
+	// unmatched requests are routed to all sources
+	require.Equal(t, spec.Sources, spec.RouteSources(nil))
+
+	// a route whose peers are not sources leaves no sources
+	spec.HTTP.Routes[0].Peers = []string{"peer3"}
+	require.Empty(t, spec.RouteSources(map[string]string{"x-version": "v2"}))
+}
@@ -0,0 +1,96 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced
+
+// WorkloadSet defines a named, reusable set of workloads.
+// WorkloadSets can be referenced by name from AccessPolicies in the same namespace.
+type WorkloadSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec represents the workloads in the set.
+	Spec WorkloadSetSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// PrivilegedWorkloadSet is the cluster-scoped version of WorkloadSet.
+// PrivilegedWorkloadSets can be referenced by name from PrivilegedAccessPolicies.
+type PrivilegedWorkloadSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec represents the workloads in the set.
+	Spec WorkloadSetSpec `json:"spec,omitempty"`
+}
+
+// WorkloadSetSpec describes a set of workloads, based on their attributes (labels).
+type WorkloadSetSpec struct {
+	// WorkloadSelectors are K8s-style label selectors, selecting Pods and Services according to their labels.
+	// A workload is in the set if it matches any of the selectors.
+	WorkloadSelectors []metav1.LabelSelector `json:"workloadSelectors"`
+}
+
+// Validate returns an error if the given WorkloadSet is invalid. Otherwise, returns nil.
+func (s *WorkloadSetSpec) Validate() error {
+	if len(s.WorkloadSelectors) == 0 {
+		return fmt.Errorf("empty WorkloadSelectors field is not allowed")
+	}
+
+	for i := range s.WorkloadSelectors {
+		if _, err := metav1.LabelSelectorAsSelector(&s.WorkloadSelectors[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced
+
+// WorkloadSetList is a list of WorkloadSet objects.
+type WorkloadSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is the list of workload set objects.
+	Items []WorkloadSet `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// PrivilegedWorkloadSetList is a list of PrivilegedWorkloadSet objects.
+type PrivilegedWorkloadSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is the list of privileged workload set objects.
+	Items []PrivilegedWorkloadSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkloadSet{}, &PrivilegedWorkloadSet{}, &WorkloadSetList{}, &PrivilegedWorkloadSetList{})
+}
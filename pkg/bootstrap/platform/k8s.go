@@ -217,13 +217,16 @@ rules:
   resources: ["pods"]
   verbs: ["get", "list", "watch"]
 - apiGroups: ["clusterlink.net"]
-  resources: ["exports", "peers", "accesspolicies", "privilegedaccesspolicies"]
+  resources: ["exports", "peers", "workloadsets", "privilegedworkloadsets"]
   verbs: ["get", "list", "watch"]
+- apiGroups: ["clusterlink.net"]
+  resources: ["accesspolicies", "privilegedaccesspolicies"]
+  verbs: ["get", "list", "watch", "delete"]
 - apiGroups: ["clusterlink.net"]
   resources: ["imports"]
   verbs: ["get", "list", "watch", "update"]
 - apiGroups: ["clusterlink.net"]
-  resources: ["imports/status", "exports/status", "peers/status"]
+  resources: ["imports/status", "exports/status", "peers/status", "accesspolicies/status", "privilegedaccesspolicies/status"]
   verbs: ["update"]
 ---
 apiVersion: rbac.authorization.k8s.io/v1
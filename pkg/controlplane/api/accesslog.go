@@ -0,0 +1,28 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+const (
+	// EgressAccessLogName is the name of the access log streamed by dataplanes to the controlplane,
+	// reporting egress connections to remote peers.
+	// Each connection is reported once its tunnel to the remote peer is established
+	// (as an intermediate log entry, including the connection setup latency), and once it ends.
+	EgressAccessLogName = "egress"
+	// IngressAccessLogName is the name of the access log streamed by dataplanes to the controlplane,
+	// reporting ingress connections to exported services which were rejected
+	// due to the export limit of concurrent connections.
+	// The Go dataplane streams a single access log (named EgressAccessLogName), which also reports its
+	// ingress connections with the ID of their access token, once established and once they end.
+	IngressAccessLogName = "ingress"
+)
@@ -21,29 +21,48 @@ import (
 const (
 	// RemotePeerAuthorizationPath is the path remote peers use to send an authorization request.
 	RemotePeerAuthorizationPath = "/authz"
+	// ConnectUDPPathPrefix is the path prefix of requests for tunneling UDP flows to an exported service,
+	// using the well-known URI template of HTTP CONNECT-UDP (RFC 9298).
+	ConnectUDPPathPrefix = "/.well-known/masque/udp/"
+	// ConnectUDPProtocol is the upgrade protocol token of HTTP CONNECT-UDP requests.
+	ConnectUDPProtocol = "connect-udp"
 
 	// ImportNameHeader holds the name of the imported service.
 	ImportNameHeader = "x-import-name"
 	// ImportNamespaceHeader holds the namespace of the imported service.
 	ImportNamespaceHeader = "x-import-namespace"
+	// ImportPortHeader holds the port name of the imported service.
+	ImportPortHeader = "x-import-port"
 	// ClientIPHeader holds the IP address of the source client.
 	ClientIPHeader = "x-client-ip"
+	// FilterChainHeader holds the name of the import listener filter chain of a tunneled connection.
+	FilterChainHeader = "x-filter-chain"
+	// ImportSourceHeader holds the exported service (namespace/name) of the import source selected for an egress connection.
+	ImportSourceHeader = "x-import-source"
 
 	// AuthorizationHeader holds a signed token allowing ingress connections to access the dataplane.
 	AuthorizationHeader = "authorization"
+	// PeerAuthorizationHeader holds a signed token allowing ingress HTTP requests (of services
+	// shared with appProtocol http) to access the dataplane. The authorization header is left to the application.
+	PeerAuthorizationHeader = "x-clusterlink-authorization"
 
 	// TargetClusterHeader holds the name of the target cluster.
 	TargetClusterHeader = "host"
 
-	// AccessTokenHeader holds the access token for an exported service, sent back by the server.
+	// AccessTokenHeader holds the (comma-separated) access tokens for an exported service, sent back by the server.
 	AccessTokenHeader = "x-access-token"
+	// AccessTokenIDHeader holds the ID of the access token authorizing an ingress connection,
+	// set by the controlplane for the dataplane to report the connection.
+	AccessTokenIDHeader = "x-access-token-id"
 
-	// JWTSignatureAlgorithm defines the signing algorithm for JWT tokens.
-	JWTSignatureAlgorithm = jwa.HS256
+	// JWTSignatureAlgorithm defines the signing algorithm of generated keys for JWT tokens.
+	JWTSignatureAlgorithm = jwa.ES256
 	// ExportNameJWTClaim holds the name of the requested exported service.
 	ExportNameJWTClaim = "export_name"
 	// ExportNamespaceJWTClaim holds the namespace of the requested exported service.
 	ExportNamespaceJWTClaim = "export_namespace"
+	// ExportPortJWTClaim holds the port name of the requested exported service.
+	ExportPortJWTClaim = "export_port"
 )
 
 // AuthorizationRequest represents an authorization request for accessing an exported service.
@@ -52,6 +71,15 @@ type AuthorizationRequest struct {
 	ServiceName string
 	// ServiceNamespace is the namespace of the requested exported service.
 	ServiceNamespace string
+	// ServicePort is the port name of the requested exported service.
+	// An empty name denotes the single unnamed port of the service.
+	ServicePort string
 	// Attributes of the source workload, to be used by the PDP on the remote peer
 	SrcAttributes connectivitypdp.WorkloadAttrs
+	// SignedSrcAttributes is an assertion of the source workload attributes (a JWT),
+	// signed using the peer certificate of the requesting peer. Empty if the requesting peer does not sign attributes.
+	SignedSrcAttributes string `json:",omitempty"`
+	// AccessTokens is the number of single-use access tokens requested, for multiple connections of the source workload.
+	// The server may issue fewer tokens. Zero denotes a single token.
+	AccessTokens int `json:",omitempty"`
 }
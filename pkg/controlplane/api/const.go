@@ -18,6 +18,8 @@ const (
 	ListenPort = 4444
 	// ReadinessListenPort is the port used to probe for controlplane readiness.
 	ReadinessListenPort = 4445
+	// MetricsPath is the path of the Prometheus metrics endpoint, served on ReadinessListenPort.
+	MetricsPath = "/metrics"
 	// Name is the controlplane name.
 	Name = "cl-controlplane"
 )
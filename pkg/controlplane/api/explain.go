@@ -0,0 +1,57 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+const (
+	// PolicyExplainPath is the path of the controlplane HTTP endpoint explaining access policy decisions.
+	PolicyExplainPath = "/policy/explain"
+	// PolicyExplainFromPodParam is the query parameter holding the source pod (namespace/name).
+	PolicyExplainFromPodParam = "from-pod"
+	// PolicyExplainToImportParam is the query parameter holding the target import (namespace/name).
+	PolicyExplainToImportParam = "to-import"
+	// PolicyExplainPortParam is the optional query parameter holding the port name of the target import.
+	PolicyExplainPortParam = "port"
+)
+
+// PolicyExplanation explains the access policy decisions on connections from a pod to an imported service.
+type PolicyExplanation struct {
+	// SrcAttributes are the attributes of the source pod.
+	SrcAttributes connectivitypdp.WorkloadAttrs `json:"srcAttributes"`
+	// Sources holds the decision on each of the import sources.
+	Sources []SourcePolicyDecision `json:"sources"`
+}
+
+// SourcePolicyDecision is the access policy decision on connections to a single import source.
+type SourcePolicyDecision struct {
+	// Peer is the name of the peer exporting the service.
+	Peer string `json:"peer"`
+	// ExportName is the name of the exported service.
+	ExportName string `json:"exportName"`
+	// ExportNamespace is the namespace of the exported service.
+	ExportNamespace string `json:"exportNamespace"`
+	// DstAttributes are the attributes of the exported service.
+	DstAttributes connectivitypdp.WorkloadAttrs `json:"dstAttributes,omitempty"`
+	// Decision is the access policy decision (allow or deny).
+	Decision string `json:"decision,omitempty"`
+	// MatchedBy is the name of the policy that took the decision.
+	MatchedBy string `json:"matchedBy,omitempty"`
+	// Privileged is true if the deciding policy is privileged.
+	Privileged bool `json:"privileged,omitempty"`
+	// Error explains why no decision could be made.
+	Error string `json:"error,omitempty"`
+}
@@ -13,6 +13,13 @@
 
 package api
 
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
 const (
 	// cluster names.
 
@@ -41,11 +48,72 @@ const (
 	ValidationSecret = "validation"
 	// CertificateSecret is the secret name of the dataplane certificate.
 	CertificateSecret = "certificate"
+
+	// PortNameSeparator separates the service name from the port name in cluster and listener names.
+	PortNameSeparator = ":"
+
+	// listener and cluster metadata.
+
+	// MetadataNamespace is the metadata namespace of import listeners and export clusters.
+	MetadataNamespace = "clusterlink"
+	// RevokedConnectionsMetadataKey is the import listener, import listener filter chain (or export cluster) metadata key
+	// listing the connections which were revoked by the controlplane, and are to be terminated by the dataplanes.
+	// As Envoy drains all connections of a modified filter chain, changing the list of a filter chain drains the chain.
+	// Export clusters list the revoked ingress connections reported by the Go dataplane.
+	RevokedConnectionsMetadataKey = "revokedConnections"
 )
 
-// ExportClusterName returns the cluster name of an exported service.
-func ExportClusterName(name, namespace string) string {
-	return ExportClusterPrefix + namespace + "/" + name
+// ConnectionID identifies a connection reported by a dataplane.
+type ConnectionID struct {
+	// Dataplane is the ID of the dataplane forwarding the connection.
+	Dataplane string
+	// StreamID is the ID of the connection in the access log stream of the dataplane.
+	StreamID string
+}
+
+// RevokedConnectionsMetadata returns the import listener (or export cluster) metadata listing the given revoked connections.
+func RevokedConnectionsMetadata(revoked []ConnectionID) (*structpb.Struct, error) {
+	connections := make([]any, 0, len(revoked))
+	for _, id := range revoked {
+		connections = append(connections, map[string]any{
+			"dataplane": id.Dataplane,
+			"streamID":  id.StreamID,
+		})
+	}
+
+	return structpb.NewStruct(map[string]any{RevokedConnectionsMetadataKey: connections})
+}
+
+// ParseRevokedConnections returns the revoked connections listed in import listener (or export cluster) metadata.
+func ParseRevokedConnections(metadata *structpb.Struct) []ConnectionID {
+	var revoked []ConnectionID
+	for _, value := range metadata.GetFields()[RevokedConnectionsMetadataKey].GetListValue().GetValues() {
+		fields := value.GetStructValue().GetFields()
+		revoked = append(revoked, ConnectionID{
+			Dataplane: fields["dataplane"].GetStringValue(),
+			StreamID:  fields["streamID"].GetStringValue(),
+		})
+	}
+
+	return revoked
+}
+
+// ExportClusterName returns the cluster name of an exported service port.
+// An empty port name denotes the single unnamed port of an exported service.
+func ExportClusterName(name, namespace, port string) string {
+	return withPortName(ExportClusterPrefix+namespace+"/"+name, port)
+}
+
+// ParseExportClusterName returns the export name, namespace and port name encoded in an export cluster name.
+// The cluster name is expected not to include the ExportClusterPrefix.
+func ParseExportClusterName(clusterName string) (name, namespace, port string, err error) {
+	namespace, nameAndPort, ok := strings.Cut(clusterName, "/")
+	if !ok {
+		return "", "", "", fmt.Errorf("invalid export cluster name: %s", clusterName)
+	}
+
+	name, port, _ = strings.Cut(nameAndPort, PortNameSeparator)
+	return name, namespace, port, nil
 }
 
 // RemotePeerClusterName returns the cluster name of a remote peer.
@@ -53,7 +121,35 @@ func RemotePeerClusterName(name string) string {
 	return RemotePeerClusterPrefix + name
 }
 
-// ImportListenerName returns the listener name of an imported service.
-func ImportListenerName(name, namespace string) string {
-	return ImportListenerPrefix + namespace + "/" + name
+// ImportListenerName returns the listener name of an imported service port.
+// An empty port name denotes the single unnamed port of an imported service.
+func ImportListenerName(name, namespace, port string) string {
+	return withPortName(ImportListenerPrefix+namespace+"/"+name, port)
+}
+
+// ParseImportListenerName returns the import name, namespace and port name encoded in an import listener name.
+// The listener name is expected not to include the ImportListenerPrefix.
+func ParseImportListenerName(listenerName string) (name, namespace, port string, err error) {
+	namespace, nameAndPort, ok := strings.Cut(listenerName, "/")
+	if !ok {
+		return "", "", "", fmt.Errorf("invalid import listener name: %s", listenerName)
+	}
+
+	name, port, _ = strings.Cut(nameAndPort, PortNameSeparator)
+	return name, namespace, port, nil
+}
+
+// IsResourceOf returns true if the given resource name (cluster or listener) belongs to the
+// resource name of a service, ignoring the service port name.
+func IsResourceOf(resourceName, serviceResourceName string) bool {
+	return resourceName == serviceResourceName ||
+		strings.HasPrefix(resourceName, serviceResourceName+PortNameSeparator)
+}
+
+func withPortName(name, port string) string {
+	if port == "" {
+		return name
+	}
+
+	return name + PortNameSeparator + port
 }
@@ -0,0 +1,74 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+func TestImportListenerName(t *testing.T) {
+	for _, port := range []string{"", "http"} {
+		listenerName := api.ImportListenerName("svc", "ns", port)
+		name, namespace, parsedPort, err := api.ParseImportListenerName(listenerName[len(api.ImportListenerPrefix):])
+		require.Nil(t, err)
+		require.Equal(t, "svc", name)
+		require.Equal(t, "ns", namespace)
+		require.Equal(t, port, parsedPort)
+	}
+
+	_, _, _, err := api.ParseImportListenerName("svc")
+	require.NotNil(t, err)
+}
+
+func TestExportClusterName(t *testing.T) {
+	for _, port := range []string{"", "http"} {
+		clusterName := api.ExportClusterName("svc", "ns", port)
+		name, namespace, parsedPort, err := api.ParseExportClusterName(clusterName[len(api.ExportClusterPrefix):])
+		require.Nil(t, err)
+		require.Equal(t, "svc", name)
+		require.Equal(t, "ns", namespace)
+		require.Equal(t, port, parsedPort)
+	}
+}
+
+func TestIsResourceOf(t *testing.T) {
+	serviceListenerName := api.ImportListenerName("svc", "ns", "")
+	require.True(t, api.IsResourceOf(serviceListenerName, serviceListenerName))
+	require.True(t, api.IsResourceOf(api.ImportListenerName("svc", "ns", "http"), serviceListenerName))
+
+	// services sharing a name prefix, or in another namespace
+	require.False(t, api.IsResourceOf(api.ImportListenerName("svc2", "ns", ""), serviceListenerName))
+	require.False(t, api.IsResourceOf(api.ImportListenerName("svc2", "ns", "http"), serviceListenerName))
+	require.False(t, api.IsResourceOf(api.ImportListenerName("svc", "ns2", "http"), serviceListenerName))
+}
+
+func TestRevokedConnectionsMetadata(t *testing.T) {
+	revoked := []api.ConnectionID{
+		{Dataplane: "dp1", StreamID: "1"},
+		{Dataplane: "dp2", StreamID: "1"},
+	}
+	metadata, err := api.RevokedConnectionsMetadata(revoked)
+	require.Nil(t, err)
+	require.Equal(t, revoked, api.ParseRevokedConnections(metadata))
+
+	// metadata with no revoked connections
+	require.Empty(t, api.ParseRevokedConnections(nil))
+	metadata, err = api.RevokedConnectionsMetadata(nil)
+	require.Nil(t, err)
+	require.Empty(t, api.ParseRevokedConnections(metadata))
+}
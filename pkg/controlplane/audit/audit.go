@@ -0,0 +1,166 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Direction is the direction of an audited connection.
+type Direction string
+
+const (
+	// DirectionEgress marks connections from local clients to imported services.
+	DirectionEgress Direction = "egress"
+	// DirectionIngress marks connections from remote peers to exported services.
+	DirectionIngress Direction = "ingress"
+)
+
+const (
+	// DecisionAllow marks allowed connections.
+	DecisionAllow = "allow"
+	// DecisionDeny marks denied connections.
+	DecisionDeny = "deny"
+)
+
+const (
+	// TierPrivileged marks decisions taken by privileged access policies.
+	TierPrivileged = "privileged"
+	// TierRegular marks decisions taken by regular access policies.
+	TierRegular = "regular"
+)
+
+// Record is an audit record of a single authorization decision.
+type Record struct {
+	// Time of the decision.
+	Time time.Time `json:"time"`
+	// Direction of the connection.
+	Direction Direction `json:"direction"`
+	// SrcAttributes are the attributes of the client workload.
+	SrcAttributes map[string]string `json:"srcAttributes,omitempty"`
+	// DstAttributes are the attributes of the requested service.
+	DstAttributes map[string]string `json:"dstAttributes,omitempty"`
+	// Import is the requested import (namespace/name), for egress connections.
+	Import string `json:"import,omitempty"`
+	// Export is the requested export (namespace/name).
+	Export string `json:"export,omitempty"`
+	// Port is the requested port name, if any.
+	Port string `json:"port,omitempty"`
+	// Peer is the remote peer: the source peer of egress connections, or the requesting peer of ingress connections.
+	Peer string `json:"peer,omitempty"`
+	// Decision is either allow or deny.
+	Decision string `json:"decision"`
+	// Policy is the access policy which took the decision, if any.
+	Policy string `json:"policy,omitempty"`
+	// Tier is the tier (privileged or regular) of the access policy which took the decision, if any.
+	Tier string `json:"tier,omitempty"`
+	// Reason explains decisions not taken by an access policy (e.g. rate limiting).
+	Reason string `json:"reason,omitempty"`
+}
+
+// Sink stores audit records.
+type Sink interface {
+	// Write stores an audit record.
+	Write(record *Record) error
+}
+
+// JSONSink writes audit records to a writer, one JSON object per line.
+type JSONSink struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// Write writes an audit record as a single JSON line.
+func (s *JSONSink) Write(record *Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.encoder.Encode(record)
+}
+
+// NewJSONSink returns a sink writing audit records to the given writer.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{encoder: json.NewEncoder(w)}
+}
+
+// OpenFile opens a file for appending audit records.
+// The path "-" stands for the standard output.
+func OpenFile(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log file: %w", err)
+	}
+
+	return f, nil
+}
+
+// Logger records authorization decisions to a sink.
+// Denied decisions are always recorded, while allowed decisions may be sampled.
+// A nil Logger records nothing.
+type Logger struct {
+	sink       Sink
+	sampleRate float64
+
+	logger *logrus.Entry
+}
+
+// Log records an authorization decision.
+func (l *Logger) Log(record *Record) {
+	if l == nil {
+		return
+	}
+
+	if record.Decision == DecisionAllow && !l.sample() {
+		return
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	if err := l.sink.Write(record); err != nil {
+		l.logger.Warnf("Cannot write audit record: %v.", err)
+	}
+}
+
+// sample returns whether to record a sampled decision.
+func (l *Logger) sample() bool {
+	return l.sampleRate >= 1 || rand.Float64() < l.sampleRate //nolint:gosec // G404: use of weak random is fine for sampling
+}
+
+// NewLogger returns a logger recording to the given sink.
+// sampleRate is the fraction (between 0 and 1) of allowed decisions to record.
+func NewLogger(sink Sink, sampleRate float64) (*Logger, error) {
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("audit sample rate must be between 0 and 1, got %v", sampleRate)
+	}
+
+	return &Logger{
+		sink:       sink,
+		sampleRate: sampleRate,
+		logger:     logrus.WithField("component", "controlplane.audit"),
+	}, nil
+}
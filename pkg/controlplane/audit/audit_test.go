@@ -0,0 +1,107 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/audit"
+)
+
+func readRecords(t *testing.T, buf *bytes.Buffer) []audit.Record {
+	var records []audit.Record
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record audit.Record
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Nil(t, scanner.Err())
+
+	return records
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := audit.NewLogger(audit.NewJSONSink(&buf), 1)
+	require.Nil(t, err)
+
+	logger.Log(&audit.Record{
+		Direction:     audit.DirectionIngress,
+		SrcAttributes: map[string]string{"client.clusterlink.net/namespace": "ns1"},
+		Export:        "default/svc",
+		Peer:          "peer1",
+		Decision:      audit.DecisionAllow,
+		Policy:        "default/allow-all",
+		Tier:          audit.TierRegular,
+	})
+	logger.Log(&audit.Record{
+		Direction: audit.DirectionEgress,
+		Import:    "default/svc",
+		Export:    "default/svc",
+		Peer:      "peer2",
+		Decision:  audit.DecisionDeny,
+		Reason:    "export rate limit exceeded",
+	})
+
+	records := readRecords(t, &buf)
+	require.Len(t, records, 2)
+
+	require.False(t, records[0].Time.IsZero())
+	require.Equal(t, audit.DirectionIngress, records[0].Direction)
+	require.Equal(t, "ns1", records[0].SrcAttributes["client.clusterlink.net/namespace"])
+	require.Equal(t, "default/allow-all", records[0].Policy)
+	require.Equal(t, audit.TierRegular, records[0].Tier)
+
+	require.Equal(t, audit.DirectionEgress, records[1].Direction)
+	require.Equal(t, audit.DecisionDeny, records[1].Decision)
+	require.Equal(t, "export rate limit exceeded", records[1].Reason)
+}
+
+func TestSampling(t *testing.T) {
+	_, err := audit.NewLogger(audit.NewJSONSink(&bytes.Buffer{}), 1.5)
+	require.NotNil(t, err)
+
+	// denied decisions are always recorded
+	var buf bytes.Buffer
+	logger, err := audit.NewLogger(audit.NewJSONSink(&buf), 0)
+	require.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		logger.Log(&audit.Record{Decision: audit.DecisionAllow})
+		logger.Log(&audit.Record{Decision: audit.DecisionDeny})
+	}
+	records := readRecords(t, &buf)
+	require.Len(t, records, 100)
+	for _, record := range records {
+		require.Equal(t, audit.DecisionDeny, record.Decision)
+	}
+
+	// allowed decisions are sampled
+	const iterations = 10000
+	logger, err = audit.NewLogger(audit.NewJSONSink(&buf), 0.25)
+	require.Nil(t, err)
+	for i := 0; i < iterations; i++ {
+		logger.Log(&audit.Record{Decision: audit.DecisionAllow})
+	}
+	require.InDelta(t, 0.25, float64(len(readRecords(t, &buf)))/iterations, 0.03)
+
+	// a nil logger records nothing
+	var nilLogger *audit.Logger
+	nilLogger.Log(&audit.Record{Decision: audit.DecisionDeny})
+}
@@ -0,0 +1,293 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// connection identifies a connection reported by a dataplane: an egress connection to an imported service,
+// or an ingress connection to an exported service.
+type connection struct {
+	importName types.NamespacedName
+	port       string
+	peer       string
+	// export is the exported service of the import source. Empty if not reported by the dataplane.
+	export   types.NamespacedName
+	clientIP string
+	// filterChain is the import listener filter chain of an egress connection. Empty if not reported by the dataplane.
+	filterChain string
+	// ingress is the authorization of an ingress connection. Nil for egress connections.
+	ingress *ingressAuthorization
+}
+
+// source returns the import source of the connection.
+func (c *connection) source() *v1alpha1.ImportSource {
+	return &v1alpha1.ImportSource{
+		Peer:            c.peer,
+		ExportName:      c.export.Name,
+		ExportNamespace: c.export.Namespace,
+	}
+}
+
+// resourceName returns the name of the import listener of an egress connection,
+// or of the export cluster of an ingress connection.
+func (c *connection) resourceName() string {
+	if c.ingress != nil {
+		return api.ExportClusterName(c.export.Name, c.export.Namespace, c.port)
+	}
+	return api.ImportListenerName(c.importName.Name, c.importName.Namespace, c.port)
+}
+
+// revocationResource returns the resource of the connection revoked by the dataplanes.
+func (c *connection) revocationResource() revocationResource {
+	return revocationResource{name: c.resourceName(), filterChain: c.filterChain}
+}
+
+// accessLogServer receives access logs of egress connections, and of ingress connections, from dataplanes,
+// and feeds them to the authorization manager.
+type accessLogServer struct {
+	accesslogv3.UnimplementedAccessLogServiceServer
+
+	manager *Manager
+	logger  *logrus.Entry
+}
+
+// StreamAccessLogs receives a stream of access logs from a dataplane.
+func (s *accessLogServer) StreamAccessLogs(stream accesslogv3.AccessLogService_StreamAccessLogsServer) error {
+	// connections established over this stream, keyed by their stream ID
+	connections := make(map[string]connection)
+	var dataplaneID string
+	defer func() {
+		// dataplane is gone, consider all of its connections as closed
+		for streamID, conn := range connections {
+			if conn.ingress == nil {
+				s.manager.connectionClosed(&conn)
+			}
+			s.manager.untrackConnection(api.ConnectionID{Dataplane: dataplaneID, StreamID: streamID})
+		}
+	}()
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		// identifier is only sent on the first message of the stream
+		if id := msg.GetIdentifier().GetNode().GetId(); id != "" {
+			dataplaneID = id
+		}
+
+		httpLogs := msg.GetHttpLogs()
+		if httpLogs == nil {
+			continue
+		}
+
+		for _, entry := range httpLogs.LogEntry {
+			s.handleLogEntry(entry, dataplaneID, connections)
+		}
+	}
+}
+
+func (s *accessLogServer) handleLogEntry(
+	entry *accesslogdatav3.HTTPAccessLogEntry,
+	dataplaneID string,
+	connections map[string]connection,
+) {
+	common := entry.GetCommonProperties()
+	streamID := common.GetStreamId()
+	if streamID == "" {
+		return
+	}
+
+	if common.GetResponseFlags().GetUpstreamOverflow() &&
+		strings.HasPrefix(common.GetUpstreamCluster(), api.ExportClusterPrefix) {
+		s.handleRejectedConnection(common.GetUpstreamCluster())
+		return
+	}
+
+	if strings.HasPrefix(common.GetUpstreamCluster(), api.ExportClusterPrefix) {
+		s.handleIngressConnection(entry, dataplaneID, connections)
+		return
+	}
+
+	switch common.GetAccessLogType() {
+	case accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished:
+		if _, ok := connections[streamID]; ok {
+			return
+		}
+
+		conn, ok := s.parseConnection(entry)
+		if !ok {
+			return
+		}
+
+		connections[streamID] = conn
+		s.manager.connectionEstablished(&conn, common.GetTimeToFirstUpstreamRxByte().AsDuration())
+		s.manager.trackConnection(api.ConnectionID{Dataplane: dataplaneID, StreamID: streamID}, conn)
+	case accesslogdatav3.AccessLogType_DownstreamEnd:
+		if conn, ok := connections[streamID]; ok {
+			delete(connections, streamID)
+			s.manager.connectionClosed(&conn)
+			s.manager.untrackConnection(api.ConnectionID{Dataplane: dataplaneID, StreamID: streamID})
+			return
+		}
+
+		if isHTTPRequest(entry) {
+			s.handleHTTPRequest(entry)
+			return
+		}
+
+		// connection was never established, check if the remote peer failed it
+		if entry.GetResponse().GetResponseCode().GetValue() < http.StatusInternalServerError {
+			return
+		}
+
+		if conn, ok := s.parseConnection(entry); ok {
+			s.manager.connectionFailed(&conn)
+		}
+	}
+}
+
+// handleHTTPRequest handles a completed HTTP request to an imported service with appProtocol http,
+// accounting for it as a connection to the source peer it was routed to.
+func (s *accessLogServer) handleHTTPRequest(entry *accesslogdatav3.HTTPAccessLogEntry) {
+	conn, ok := s.parseConnection(entry)
+	if !ok {
+		return
+	}
+
+	responseCode := entry.GetResponse().GetResponseCode().GetValue()
+	if responseCode == 0 || responseCode >= http.StatusInternalServerError {
+		s.manager.connectionFailed(&conn)
+		return
+	}
+
+	s.manager.connectionEstablished(&conn, entry.GetCommonProperties().GetTimeToFirstUpstreamRxByte().AsDuration())
+	s.manager.connectionClosed(&conn)
+}
+
+// isHTTPRequest returns whether a log entry is of an HTTP request, rather than of a tunneled connection.
+// Entries of the Go dataplane carry no request method, as it reports HTTP requests as tunneled connections.
+func isHTTPRequest(entry *accesslogdatav3.HTTPAccessLogEntry) bool {
+	request := entry.GetRequest()
+	method := request.GetRequestMethod()
+	return method != corev3.RequestMethod_METHOD_UNSPECIFIED &&
+		method != corev3.RequestMethod_CONNECT &&
+		!strings.HasPrefix(request.GetPath(), api.ConnectUDPPathPrefix)
+}
+
+// handleIngressConnection handles an ingress connection to an export cluster, tracking it to be re-evaluated
+// on changes if reported with the ID of the access token authorizing it.
+func (s *accessLogServer) handleIngressConnection(
+	entry *accesslogdatav3.HTTPAccessLogEntry,
+	dataplaneID string,
+	connections map[string]connection,
+) {
+	common := entry.GetCommonProperties()
+	streamID := common.GetStreamId()
+	id := api.ConnectionID{Dataplane: dataplaneID, StreamID: streamID}
+
+	switch common.GetAccessLogType() {
+	case accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished:
+		if _, ok := connections[streamID]; ok {
+			return
+		}
+
+		tokenID := entry.GetRequest().GetRequestHeaders()[api.AccessTokenIDHeader]
+		authorization := s.manager.ingressAuthorizations.get(tokenID)
+		if authorization == nil {
+			s.logger.Debugf("Ignoring ingress connection with unknown access token: %v.", entry)
+			return
+		}
+
+		conn := connection{
+			port:    authorization.port,
+			peer:    authorization.peer,
+			export:  authorization.export,
+			ingress: authorization,
+		}
+		connections[streamID] = conn
+		s.manager.trackConnection(id, conn)
+	case accesslogdatav3.AccessLogType_DownstreamEnd:
+		if _, ok := connections[streamID]; ok {
+			delete(connections, streamID)
+			s.manager.untrackConnection(id)
+		}
+	}
+}
+
+// handleRejectedConnection handles an ingress connection to an export cluster,
+// which was rejected due to the export limit of concurrent connections.
+func (s *accessLogServer) handleRejectedConnection(upstreamCluster string) {
+	name, namespace, _, err := api.ParseExportClusterName(strings.TrimPrefix(upstreamCluster, api.ExportClusterPrefix))
+	if err != nil {
+		s.logger.Debugf("Ignoring rejected connection: %v.", err)
+		return
+	}
+
+	s.manager.connectionRejected(types.NamespacedName{Namespace: namespace, Name: name})
+}
+
+// parseConnection returns the import and source of an egress connection log entry.
+func (s *accessLogServer) parseConnection(entry *accesslogdatav3.HTTPAccessLogEntry) (connection, bool) {
+	upstreamCluster := entry.GetCommonProperties().GetUpstreamCluster()
+	if !strings.HasPrefix(upstreamCluster, api.RemotePeerClusterPrefix) {
+		return connection{}, false
+	}
+
+	headers := entry.GetRequest().GetRequestHeaders()
+	conn := connection{
+		importName: types.NamespacedName{
+			Namespace: headers[api.ImportNamespaceHeader],
+			Name:      headers[api.ImportNameHeader],
+		},
+		port:        headers[api.ImportPortHeader],
+		peer:        strings.TrimPrefix(upstreamCluster, api.RemotePeerClusterPrefix),
+		clientIP:    headers[api.ClientIPHeader],
+		filterChain: headers[api.FilterChainHeader],
+	}
+	if namespace, name, ok := strings.Cut(headers[api.ImportSourceHeader], "/"); ok {
+		conn.export = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	if conn.importName.Name == "" || conn.importName.Namespace == "" {
+		s.logger.Debugf("Ignoring access log entry with no import: %v.", entry)
+		return connection{}, false
+	}
+
+	return conn, true
+}
+
+func newAccessLogServer(manager *Manager) *accessLogServer {
+	return &accessLogServer{
+		manager: manager,
+		logger:  logrus.WithField("component", "controlplane.authz.accesslog"),
+	}
+}
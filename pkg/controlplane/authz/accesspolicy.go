@@ -0,0 +1,218 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+// updateAccessPolicyStatus updates the status of an access policy after adding it to the PDP.
+// generation is the handled policy generation, and addErr is the error returned when adding it, if any.
+// A rejected policy leaves the previously accepted version of the policy, if any, in force.
+func (m *Manager) updateAccessPolicyStatus(
+	ctx context.Context, name types.NamespacedName, privileged bool, generation int64, addErr error,
+) error {
+	accepted := metav1.Condition{
+		Type:               v1alpha1.AccessPolicyAccepted,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Accepted",
+		ObservedGeneration: generation,
+	}
+
+	if addErr != nil {
+		accepted.Status = metav1.ConditionFalse
+		accepted.Reason = "Invalid"
+		accepted.Message = addErr.Error()
+		if m.connectivityPDP.HasPolicy(name, privileged) {
+			accepted.Message += "; the previously accepted version remains in force"
+		}
+	}
+
+	return m.setAccessPolicyStatus(ctx, name, privileged,
+		func(spec *v1alpha1.AccessPolicySpec, status *v1alpha1.AccessPolicyStatus) {
+			status.ObservedGeneration = generation
+			meta.SetStatusCondition(&status.Conditions, accepted)
+			m.setWorkloadSetsResolvedCondition(status, name, privileged)
+			setExpiredCondition(spec, status)
+		})
+}
+
+// updateWorkloadSetsResolved updates the condition of an access policy indicating whether
+// all of its referenced workload sets exist.
+func (m *Manager) updateWorkloadSetsResolved(ctx context.Context, name types.NamespacedName, privileged bool) error {
+	return m.setAccessPolicyStatus(ctx, name, privileged,
+		func(_ *v1alpha1.AccessPolicySpec, status *v1alpha1.AccessPolicyStatus) {
+			m.setWorkloadSetsResolvedCondition(status, name, privileged)
+		})
+}
+
+// updateAccessPolicyExpiry handles the expiry of an access policy with a NotAfter time.
+// Before expiry, a re-check is scheduled to the expiry time.
+// Once expired, the policy is deleted if requested by the policy.
+func (m *Manager) updateAccessPolicyExpiry(
+	ctx context.Context, name types.NamespacedName, privileged bool, spec *v1alpha1.AccessPolicySpec,
+) error {
+	if spec.NotAfter == nil {
+		m.scheduleAccessPolicyExpiry(name, privileged, time.Time{})
+		return nil
+	}
+
+	if time.Now().Before(spec.NotAfter.Time) {
+		m.scheduleAccessPolicyExpiry(name, privileged, spec.NotAfter.Time)
+		return nil
+	}
+
+	m.scheduleAccessPolicyExpiry(name, privileged, time.Time{})
+	if !spec.DeleteAfterExpiry {
+		return nil
+	}
+
+	var policy client.Object = &v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace},
+	}
+	if privileged {
+		policy = &v1alpha1.PrivilegedAccessPolicy{ObjectMeta: metav1.ObjectMeta{Name: name.Name}}
+	}
+
+	m.logger.Infof("Deleting expired access policy '%s'.", name)
+	return client.IgnoreNotFound(m.client.Delete(ctx, policy))
+}
+
+// expireAccessPolicy marks an access policy as expired, and deletes it if requested by the policy.
+func (m *Manager) expireAccessPolicy(name types.NamespacedName, privileged bool) {
+	ctx := context.Background()
+
+	var policySpec *v1alpha1.AccessPolicySpec
+	err := m.setAccessPolicyStatus(ctx, name, privileged,
+		func(spec *v1alpha1.AccessPolicySpec, status *v1alpha1.AccessPolicyStatus) {
+			policySpec = spec
+			setExpiredCondition(spec, status)
+		})
+	if err == nil && policySpec != nil {
+		err = m.updateAccessPolicyExpiry(ctx, name, privileged, policySpec)
+	}
+	if err != nil {
+		m.logger.Errorf("Cannot expire access policy '%s': %v.", name, err)
+	}
+}
+
+// scheduleAccessPolicyExpiry schedules expiring an access policy at the given time.
+// A zero time cancels the scheduled expiry.
+func (m *Manager) scheduleAccessPolicyExpiry(name types.NamespacedName, privileged bool, at time.Time) {
+	m.expiryTimersLock.Lock()
+	defer m.expiryTimersLock.Unlock()
+
+	if timer, ok := m.expiryTimers[name]; ok {
+		timer.Stop()
+		delete(m.expiryTimers, name)
+	}
+
+	if at.IsZero() {
+		return
+	}
+
+	m.expiryTimers[name] = time.AfterFunc(time.Until(at), func() {
+		m.expireAccessPolicy(name, privileged)
+	})
+}
+
+// setExpiredCondition sets the condition indicating whether the NotAfter time of the access policy has passed.
+func setExpiredCondition(spec *v1alpha1.AccessPolicySpec, status *v1alpha1.AccessPolicyStatus) {
+	if spec.NotAfter == nil {
+		meta.RemoveStatusCondition(&status.Conditions, v1alpha1.AccessPolicyExpired)
+		return
+	}
+
+	cond := metav1.Condition{
+		Type:               v1alpha1.AccessPolicyExpired,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NotExpired",
+		Message:            fmt.Sprintf("expires at %s", spec.NotAfter.UTC().Format(time.RFC3339)),
+		ObservedGeneration: status.ObservedGeneration,
+	}
+
+	if !time.Now().Before(spec.NotAfter.Time) {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "Expired"
+		cond.Message = fmt.Sprintf("expired at %s", spec.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	meta.SetStatusCondition(&status.Conditions, cond)
+}
+
+// setWorkloadSetsResolvedCondition sets the condition indicating whether
+// all workload sets referenced by the access policy in force exist.
+func (m *Manager) setWorkloadSetsResolvedCondition(
+	status *v1alpha1.AccessPolicyStatus, name types.NamespacedName, privileged bool,
+) {
+	cond := metav1.Condition{
+		Type:               v1alpha1.AccessPolicyWorkloadSetsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		ObservedGeneration: status.ObservedGeneration,
+	}
+
+	if dangling := m.connectivityPDP.DanglingWorkloadSets(name, privileged); len(dangling) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "DanglingReferences"
+		cond.Message = fmt.Sprintf("missing workload sets: %s", strings.Join(dangling, ", "))
+	}
+
+	meta.SetStatusCondition(&status.Conditions, cond)
+}
+
+// setAccessPolicyStatus applies an update to the status of an access policy, and stores it if changed.
+func (m *Manager) setAccessPolicyStatus(
+	ctx context.Context, name types.NamespacedName, privileged bool,
+	update func(spec *v1alpha1.AccessPolicySpec, status *v1alpha1.AccessPolicyStatus),
+) error {
+	var policy client.Object
+	var spec *v1alpha1.AccessPolicySpec
+	var status *v1alpha1.AccessPolicyStatus
+	if privileged {
+		privilegedPolicy := &v1alpha1.PrivilegedAccessPolicy{}
+		policy = privilegedPolicy
+		spec = &privilegedPolicy.Spec
+		status = &privilegedPolicy.Status
+	} else {
+		regularPolicy := &v1alpha1.AccessPolicy{}
+		policy = regularPolicy
+		spec = &regularPolicy.Spec
+		status = &regularPolicy.Status
+	}
+
+	if err := m.client.Get(ctx, name, policy); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	current := status.DeepCopy()
+	update(spec, status)
+	if equality.Semantic.DeepEqual(current, status) {
+		return nil
+	}
+
+	m.logger.Infof("Updating access policy '%s' status: %v.", name, status.Conditions)
+	return m.client.Status().Update(ctx, policy)
+}
@@ -0,0 +1,303 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"k8s.io/apimachinery/pkg/types"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tls"
+)
+
+// srcAttributesJWTClaim holds the attributes of the source workload in a signed attributes assertion.
+const srcAttributesJWTClaim = "src_attributes"
+
+// attributesAssertion is an assertion by a peer of the attributes of a source workload,
+// requesting access to an exported service of another peer.
+type attributesAssertion struct {
+	// Issuer is the name of the peer asserting the attributes.
+	Issuer string
+	// Audience is the name of the peer the assertion is sent to.
+	Audience string
+	// ServiceName is the name of the requested exported service.
+	ServiceName types.NamespacedName
+	// ServicePort is the port name of the requested exported service.
+	ServicePort string
+	// SrcAttributes are the asserted attributes of the source workload.
+	SrcAttributes connectivitypdp.WorkloadAttrs
+}
+
+// signatureAlgorithm returns the JWS algorithm for signing using the given (private or public) key.
+func signatureAlgorithm(key any) (jwa.SignatureAlgorithm, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwa.RS256, nil
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwa.EdDSA, nil
+	case *ecdsa.PrivateKey:
+		return ecdsaSignatureAlgorithm(key.Curve)
+	case *ecdsa.PublicKey:
+		return ecdsaSignatureAlgorithm(key.Curve)
+	}
+
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+func ecdsaSignatureAlgorithm(curve elliptic.Curve) (jwa.SignatureAlgorithm, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwa.ES256, nil
+	case elliptic.P384():
+		return jwa.ES384, nil
+	case elliptic.P521():
+		return jwa.ES512, nil
+	}
+
+	return "", fmt.Errorf("unsupported elliptic curve %s", curve.Params().Name)
+}
+
+// signAttributesAssertion signs an attributes assertion using the peer certificate,
+// which is attached to the signed assertion.
+func signAttributesAssertion(assertion *attributesAssertion, peerTLS *tls.ParsedCertData) (string, error) {
+	alg, err := signatureAlgorithm(peerTLS.PrivateKey())
+	if err != nil {
+		return "", err
+	}
+
+	tokenBuilder := jwt.NewBuilder().
+		Issuer(assertion.Issuer).
+		Audience([]string{assertion.Audience}).
+		Expiration(time.Now().Add(time.Second*jwtExpirySeconds)).
+		Claim(cpapi.ExportNameJWTClaim, assertion.ServiceName.Name).
+		Claim(cpapi.ExportNamespaceJWTClaim, assertion.ServiceName.Namespace).
+		Claim(srcAttributesJWTClaim, assertion.SrcAttributes)
+	if assertion.ServicePort != "" {
+		tokenBuilder = tokenBuilder.Claim(cpapi.ExportPortJWTClaim, assertion.ServicePort)
+	}
+
+	token, err := tokenBuilder.Build()
+	if err != nil {
+		return "", fmt.Errorf("unable to build attributes assertion: %w", err)
+	}
+
+	chain := make([]string, 0, len(peerTLS.CertificateChain()))
+	for _, cert := range peerTLS.CertificateChain() {
+		chain = append(chain, base64.StdEncoding.EncodeToString(cert))
+	}
+
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.X509CertChainKey, chain); err != nil {
+		return "", err
+	}
+
+	signed, err := jwt.Sign(token, alg, peerTLS.PrivateKey(), jwt.WithHeaders(headers))
+	if err != nil {
+		return "", fmt.Errorf("unable to sign attributes assertion: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+// verifyAttributesAssertion verifies a signed attributes assertion, which must be signed by a peer certificate
+// of the issuer peer, issued by the fabric CA, and match the expected assertion.
+// On success, returns the asserted attributes.
+func verifyAttributesAssertion(
+	signed string,
+	expected *attributesAssertion,
+	peerTLS *tls.ParsedCertData,
+) (connectivitypdp.WorkloadAttrs, error) {
+	msg, err := jws.ParseString(signed)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse attributes assertion: %w", err)
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, fmt.Errorf("expected a single attributes assertion signature")
+	}
+
+	signer, err := verifyCertificateChain(msg.Signatures()[0].ProtectedHeaders().X509CertChain(), peerTLS)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(signer.DNSNames, expected.Issuer) {
+		return nil, fmt.Errorf("attributes assertion is not signed by peer '%s'", expected.Issuer)
+	}
+
+	// the algorithm is set by the signer key, rather than by the (unverified) assertion header
+	alg, err := signatureAlgorithm(signer.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseString(
+		signed,
+		jwt.WithVerify(alg, signer.PublicKey),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(expected.Issuer),
+		jwt.WithAudience(expected.Audience))
+	if err != nil {
+		return nil, fmt.Errorf("invalid attributes assertion: %w", err)
+	}
+
+	claims := token.PrivateClaims()
+	if claims[cpapi.ExportNameJWTClaim] != expected.ServiceName.Name ||
+		claims[cpapi.ExportNamespaceJWTClaim] != expected.ServiceName.Namespace {
+		return nil, fmt.Errorf("attributes assertion is not for exported service '%s'", expected.ServiceName)
+	}
+	if port, _ := claims[cpapi.ExportPortJWTClaim].(string); port != expected.ServicePort {
+		return nil, fmt.Errorf("attributes assertion is not for port '%s'", expected.ServicePort)
+	}
+
+	rawAttrs, ok := claims[srcAttributesJWTClaim].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("attributes assertion missing '%s' claim", srcAttributesJWTClaim)
+	}
+
+	attrs := make(connectivitypdp.WorkloadAttrs, len(rawAttrs))
+	for key, value := range rawAttrs {
+		if attrs[key], ok = value.(string); !ok {
+			return nil, fmt.Errorf("invalid value of attribute '%s'", key)
+		}
+	}
+
+	return attrs, nil
+}
+
+// verifyCertificateChain verifies a (base64 DER-encoded) certificate chain, returning its leaf certificate.
+func verifyCertificateChain(chain []string, peerTLS *tls.ParsedCertData) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("attributes assertion missing signer certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(chain))
+	for _, encoded := range chain {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode signer certificate: %w", err)
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse signer certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         peerTLS.CA(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("untrusted signer certificate: %w", err)
+	}
+
+	return certs[0], nil
+}
+
+// SetSignAttributes sets whether the source attributes sent to remote peers are signed using the peer certificate.
+func (m *Manager) SetSignAttributes(enabled bool) {
+	m.signAttributes = enabled
+}
+
+// SetRequireSignedAttributes sets whether ingress requests of remote peers must carry signed source attributes.
+func (m *Manager) SetRequireSignedAttributes(required bool) {
+	m.requireSignedAttributes = required
+}
+
+// signSrcAttributes signs the source attributes of an authorization request to the given remote peer.
+func (m *Manager) signSrcAttributes(req *cpapi.AuthorizationRequest, peerName string) (string, error) {
+	m.selfPeerLock.RLock()
+	peerTLS := m.peerTLS
+	selfName := m.peerName
+	m.selfPeerLock.RUnlock()
+
+	if peerTLS == nil {
+		return "", fmt.Errorf("peer certificate undefined")
+	}
+
+	return signAttributesAssertion(&attributesAssertion{
+		Issuer:        selfName,
+		Audience:      peerName,
+		ServiceName:   types.NamespacedName{Namespace: req.ServiceNamespace, Name: req.ServiceName},
+		ServicePort:   req.ServicePort,
+		SrcAttributes: req.SrcAttributes,
+	}, peerTLS)
+}
+
+// getIngressSrcAttributes returns the source attributes of an ingress request which can be trusted.
+// The peer name attribute must match the remote peer, as authenticated by its certificate.
+func (m *Manager) getIngressSrcAttributes(req *ingressAuthorizationRequest) (connectivitypdp.WorkloadAttrs, error) {
+	attrs := req.SrcAttributes
+	switch {
+	case req.SignedSrcAttributes != "":
+		m.selfPeerLock.RLock()
+		peerTLS := m.peerTLS
+		selfName := m.peerName
+		m.selfPeerLock.RUnlock()
+
+		if peerTLS == nil {
+			return nil, fmt.Errorf("peer certificate undefined")
+		}
+
+		var err error
+		attrs, err = verifyAttributesAssertion(req.SignedSrcAttributes, &attributesAssertion{
+			Issuer:      req.PeerName,
+			Audience:    selfName,
+			ServiceName: req.ServiceName,
+			ServicePort: req.ServicePort,
+		}, peerTLS)
+		if err != nil {
+			return nil, err
+		}
+	case m.requireSignedAttributes:
+		return nil, fmt.Errorf("source attributes are not signed")
+	}
+
+	if len(attrs) == 0 {
+		return attrs, nil
+	}
+
+	if peerName, ok := attrs[PeerNameLabel]; ok {
+		if peerName != req.PeerName {
+			return nil, fmt.Errorf("peer name attribute '%s' does not match the authenticated peer", peerName)
+		}
+		return attrs, nil
+	}
+
+	// bind attributes without a peer name to the authenticated peer
+	boundAttrs := make(connectivitypdp.WorkloadAttrs, len(attrs)+1)
+	for k, v := range attrs {
+		boundAttrs[k] = v
+	}
+	boundAttrs[PeerNameLabel] = req.PeerName
+	return boundAttrs, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/bootstrap"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tls"
+)
+
+// peerCertData returns the parsed certificate of a peer, issued by the given fabric CA.
+func peerCertData(t *testing.T, peerName string, fabricCert *bootstrap.Certificate) *tls.ParsedCertData {
+	peerCert, err := bootstrap.CreatePeerCertificate(peerName, fabricCert)
+	require.Nil(t, err)
+
+	certData, _, err := tls.Parse(fabricCert.RawCert(), peerCert.RawCert(), peerCert.RawKey())
+	require.Nil(t, err)
+	return certData
+}
+
+// newPeerManager returns an authorization manager of the given peer.
+func newPeerManager(t *testing.T, certData *tls.ParsedCertData) *Manager {
+	m := NewManager(nil, "default", nil)
+	require.Nil(t, m.SetPeerCertificates(certData, nil))
+	return m
+}
+
+func TestAttributesAssertion(t *testing.T) {
+	fabricCert, err := bootstrap.CreateFabricCertificate("fabric")
+	require.Nil(t, err)
+	clientTLS := peerCertData(t, "client", fabricCert)
+	serverTLS := peerCertData(t, "server", fabricCert)
+
+	assertion := &attributesAssertion{
+		Issuer:        "client",
+		Audience:      "server",
+		ServiceName:   types.NamespacedName{Namespace: "default", Name: "svc"},
+		ServicePort:   "http",
+		SrcAttributes: connectivitypdp.WorkloadAttrs{ClientNamespaceLabel: "default", PeerNameLabel: "client"},
+	}
+	signed, err := signAttributesAssertion(assertion, clientTLS)
+	require.Nil(t, err)
+
+	expected := *assertion
+	expected.SrcAttributes = nil
+	attrs, err := verifyAttributesAssertion(signed, &expected, serverTLS)
+	require.Nil(t, err)
+	require.Equal(t, assertion.SrcAttributes, attrs)
+
+	// assertions are bound to the issuer, audience and requested service
+	for _, mismatch := range []func(a *attributesAssertion){
+		func(a *attributesAssertion) { a.Issuer = "other" },
+		func(a *attributesAssertion) { a.Audience = "other" },
+		func(a *attributesAssertion) { a.ServiceName.Name = "other" },
+		func(a *attributesAssertion) { a.ServicePort = "" },
+	} {
+		other := expected
+		mismatch(&other)
+		_, err = verifyAttributesAssertion(signed, &other, serverTLS)
+		require.NotNil(t, err)
+	}
+
+	// tampered assertion
+	_, err = verifyAttributesAssertion(signed[:len(signed)-4]+"AAAA", &expected, serverTLS)
+	require.NotNil(t, err)
+
+	// assertion signed by a certificate of another fabric
+	otherFabricCert, err := bootstrap.CreateFabricCertificate("other")
+	require.Nil(t, err)
+	signed, err = signAttributesAssertion(assertion, peerCertData(t, "client", otherFabricCert))
+	require.Nil(t, err)
+	_, err = verifyAttributesAssertion(signed, &expected, serverTLS)
+	require.NotNil(t, err)
+}
+
+func TestIngressSrcAttributes(t *testing.T) {
+	fabricCert, err := bootstrap.CreateFabricCertificate("fabric")
+	require.Nil(t, err)
+	client := newPeerManager(t, peerCertData(t, "client", fabricCert))
+	server := newPeerManager(t, peerCertData(t, "server", fabricCert))
+
+	req := &ingressAuthorizationRequest{
+		PeerName:      "client",
+		ServiceName:   types.NamespacedName{Namespace: "default", Name: "svc"},
+		SrcAttributes: connectivitypdp.WorkloadAttrs{ClientNamespaceLabel: "default"},
+	}
+
+	// the peer name attribute is bound to the authenticated peer
+	attrs, err := server.getIngressSrcAttributes(req)
+	require.Nil(t, err)
+	require.Equal(t, "client", attrs[PeerNameLabel])
+	require.Equal(t, "default", attrs[ClientNamespaceLabel])
+
+	req.SrcAttributes[PeerNameLabel] = "other"
+	_, err = server.getIngressSrcAttributes(req)
+	require.NotNil(t, err)
+
+	// no attributes
+	req.SrcAttributes = nil
+	attrs, err = server.getIngressSrcAttributes(req)
+	require.Nil(t, err)
+	require.Empty(t, attrs)
+
+	// signed attributes
+	authzReq := &cpapi.AuthorizationRequest{
+		ServiceName:      "svc",
+		ServiceNamespace: "default",
+		SrcAttributes:    connectivitypdp.WorkloadAttrs{ClientNamespaceLabel: "signed", PeerNameLabel: "client"},
+	}
+	req.SignedSrcAttributes, err = client.signSrcAttributes(authzReq, "server")
+	require.Nil(t, err)
+	req.SrcAttributes = connectivitypdp.WorkloadAttrs{ClientNamespaceLabel: "unsigned"}
+	attrs, err = server.getIngressSrcAttributes(req)
+	require.Nil(t, err)
+	require.Equal(t, authzReq.SrcAttributes, attrs)
+
+	// signed attributes sent by another peer
+	req.PeerName = "other"
+	_, err = server.getIngressSrcAttributes(req)
+	require.NotNil(t, err)
+
+	// unsigned attributes, when signed attributes are required
+	req.PeerName = "client"
+	req.SignedSrcAttributes = ""
+	server.SetRequireSignedAttributes(true)
+	_, err = server.getIngressSrcAttributes(req)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/audit"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+// SetAuditLogger sets the logger used for auditing authorization decisions.
+// Must be called before the manager starts handling requests.
+func (m *Manager) SetAuditLogger(logger *audit.Logger) {
+	m.auditLogger = logger
+}
+
+// recordDecision audits an authorization decision, and counts it in the decisions metric.
+func (m *Manager) recordDecision(record *audit.Record) {
+	decisionsMetric.WithLabelValues(string(record.Direction), record.Decision, record.Policy, record.Tier).Inc()
+	m.auditLogger.Log(record)
+}
+
+// newAuditRecord returns an audit record of a connection to an export, denied unless decision allows it.
+func newAuditRecord(
+	direction audit.Direction,
+	srcAttributes, dstAttributes connectivitypdp.WorkloadAttrs,
+	exportName types.NamespacedName,
+	port, peer string,
+	decision *connectivitypdp.DestinationDecision,
+) *audit.Record {
+	record := &audit.Record{
+		Direction:     direction,
+		SrcAttributes: srcAttributes,
+		DstAttributes: dstAttributes,
+		Export:        exportName.String(),
+		Port:          port,
+		Peer:          peer,
+		Decision:      audit.DecisionDeny,
+	}
+
+	if decision == nil {
+		return record
+	}
+
+	if decision.Decision == connectivitypdp.DecisionAllow {
+		record.Decision = audit.DecisionAllow
+	}
+
+	record.Policy = decision.MatchedBy
+	switch {
+	case decision.MatchedBy == connectivitypdp.DefaultDenyPolicyName:
+		// not an actual access policy, hence no tier
+	case decision.PrivilegedMatch:
+		record.Tier = audit.TierPrivileged
+	default:
+		record.Tier = audit.TierRegular
+	}
+
+	return record
+}
@@ -0,0 +1,85 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/audit"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+func TestRecordDecision(t *testing.T) {
+	m := &Manager{}
+	exportName := types.NamespacedName{Namespace: "ns", Name: "svc"}
+
+	tests := []struct {
+		name      string
+		direction audit.Direction
+		decision  *connectivitypdp.DestinationDecision
+		labels    []string
+	}{
+		{
+			name:      "allowed by a regular policy",
+			direction: audit.DirectionEgress,
+			decision: &connectivitypdp.DestinationDecision{
+				Decision:  connectivitypdp.DecisionAllow,
+				MatchedBy: "ns/allow-all",
+			},
+			labels: []string{"egress", audit.DecisionAllow, "ns/allow-all", audit.TierRegular},
+		},
+		{
+			name:      "denied by a privileged policy",
+			direction: audit.DirectionIngress,
+			decision: &connectivitypdp.DestinationDecision{
+				Decision:        connectivitypdp.DecisionDeny,
+				MatchedBy:       "deny-peer",
+				PrivilegedMatch: true,
+			},
+			labels: []string{"ingress", audit.DecisionDeny, "deny-peer", audit.TierPrivileged},
+		},
+		{
+			name:      "denied by default",
+			direction: audit.DirectionIngress,
+			decision: &connectivitypdp.DestinationDecision{
+				Decision:  connectivitypdp.DecisionDeny,
+				MatchedBy: connectivitypdp.DefaultDenyPolicyName,
+			},
+			labels: []string{"ingress", audit.DecisionDeny, connectivitypdp.DefaultDenyPolicyName, ""},
+		},
+		{
+			name:      "denied without a policy decision",
+			direction: audit.DirectionEgress,
+			labels:    []string{"egress", audit.DecisionDeny, "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := decisionsMetric.WithLabelValues(tt.labels...)
+			before := testutil.ToFloat64(counter)
+
+			record := newAuditRecord(tt.direction, nil, nil, exportName, "http", "peer1", tt.decision)
+			m.recordDecision(record)
+			require.Equal(t, before+1, testutil.ToFloat64(counter))
+
+			m.recordDecision(record)
+			require.Equal(t, before+2, testutil.ToFloat64(counter))
+		})
+	}
+}
@@ -15,7 +15,9 @@ package connectivitypdp
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -33,9 +35,28 @@ const (
 	DecisionDeny
 )
 
+// String returns the name of the decision.
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionDeny:
+		return "deny"
+	default:
+		return "undecided"
+	}
+}
+
 // WorkloadAttrs are the actual key-value attributes attached to any given workload.
 type WorkloadAttrs map[string]string
 
+const (
+	// ServicePortAttr is the destination attribute holding the name of the accessed service port.
+	ServicePortAttr = "export.clusterlink.net/port"
+	// ServiceProtocolAttr is the destination attribute holding the protocol (TCP or UDP) of the accessed service.
+	ServiceProtocolAttr = "export.clusterlink.net/protocol"
+)
+
 // PDP is the main object to maintain a set of access policies and decide
 // whether a given connection is allowed or denied by these policies.
 type PDP struct {
@@ -43,13 +64,18 @@ type PDP struct {
 	regularPolicies    policyTier
 }
 
-// policyTier holds a set of AccessPolicies, split into deny policies and allow policies
+// policyTier holds a set of AccessPolicies, split into prioritized policies, deny policies and allow policies,
+// the schedules of time-restricted policies, and the WorkloadSets these policies may reference.
 // Within a tier, no two policies can have the same name, even if one is deny and the other is allow.
 type policyTier struct {
-	privileged    bool
-	denyPolicies  connPolicyMap
-	allowPolicies connPolicyMap
-	lock          sync.RWMutex
+	privileged          bool
+	prioritizedPolicies connPolicyMap
+	priorityOrder       []types.NamespacedName // prioritized policy names, sorted by priority and then by name
+	denyPolicies        connPolicyMap
+	allowPolicies       connPolicyMap
+	schedules           map[types.NamespacedName]*policySchedule
+	lock                sync.RWMutex
+	workloadSets        workloadSetMap
 }
 
 type connPolicyMap map[types.NamespacedName]*v1alpha1.AccessPolicySpec // map from policy name to the policy
@@ -117,11 +143,12 @@ func (pdp *PDP) AddOrUpdatePolicy(policy *AccessPolicy) error {
 		return err
 	}
 
-	if policy.privileged {
-		pdp.privilegedPolicies.addPolicy(policy.name, &policy.spec)
-	} else {
-		pdp.regularPolicies.addPolicy(policy.name, &policy.spec)
+	schedule, err := newPolicySchedule(&policy.spec)
+	if err != nil {
+		return err
 	}
+
+	pdp.getTier(policy.privileged).addPolicy(policy.name, &policy.spec, schedule)
 	return nil
 }
 
@@ -134,6 +161,51 @@ func (pdp *PDP) DeletePolicy(policyName types.NamespacedName, privileged bool) e
 	return pdp.regularPolicies.deletePolicy(policyName)
 }
 
+// AddOrUpdateWorkloadSet adds a WorkloadSet to the PDP.
+// If a WorkloadSet with the same name already exists in the PDP, it is updated.
+// Policies referencing the WorkloadSet immediately use its updated selectors.
+// Invalid WorkloadSets return an error.
+func (pdp *PDP) AddOrUpdateWorkloadSet(workloadSet *WorkloadSet) error {
+	if err := workloadSet.spec.Validate(); err != nil {
+		return err
+	}
+
+	pdp.getTier(workloadSet.privileged).workloadSets.add(workloadSet.name, &workloadSet.spec)
+	return nil
+}
+
+// DeleteWorkloadSet deletes a WorkloadSet with the given name and privilege from the PDP.
+// If no such WorkloadSet exists in the PDP, an error is returned.
+func (pdp *PDP) DeleteWorkloadSet(name types.NamespacedName, privileged bool) error {
+	return pdp.getTier(privileged).workloadSets.delete(name)
+}
+
+// HasPolicy returns whether an AccessPolicy with the given name and privilege is in the PDP.
+func (pdp *PDP) HasPolicy(policyName types.NamespacedName, privileged bool) bool {
+	tier := pdp.getTier(privileged)
+	tier.lock.RLock()
+	defer tier.lock.RUnlock()
+	return tier.getPolicy(policyName) != nil
+}
+
+// DanglingWorkloadSets returns the sorted names of the WorkloadSets referenced by the given AccessPolicy,
+// which do not exist in the PDP.
+func (pdp *PDP) DanglingWorkloadSets(policyName types.NamespacedName, privileged bool) []string {
+	return pdp.getTier(privileged).danglingWorkloadSets(policyName)
+}
+
+// PoliciesReferencingWorkloadSet returns the names of the AccessPolicies referencing the given WorkloadSet.
+func (pdp *PDP) PoliciesReferencingWorkloadSet(name types.NamespacedName, privileged bool) []types.NamespacedName {
+	return pdp.getTier(privileged).policiesReferencingWorkloadSet(name)
+}
+
+func (pdp *PDP) getTier(privileged bool) *policyTier {
+	if privileged {
+		return &pdp.privilegedPolicies
+	}
+	return &pdp.regularPolicies
+}
+
 // Decide makes allow/deny decisions for the queried connection between src and dest.
 // The decision, as well as the deciding policy, is recorded in the returned DestinationDecision struct.
 func (pdp *PDP) Decide(src, dest WorkloadAttrs, ns string) (*DestinationDecision, error) {
@@ -162,42 +234,171 @@ func (pdp *PDP) Decide(src, dest WorkloadAttrs, ns string) (*DestinationDecision
 	return &decision, nil
 }
 
+// NextScheduleTransition returns the first time after t, and no later than limit, at which any time-restricted
+// policy comes into effect or goes out of effect, possibly changing decisions taken at t.
+// Returns limit if no policy changes its effect until then.
+func (pdp *PDP) NextScheduleTransition(t, limit time.Time) time.Time {
+	return pdp.regularPolicies.nextScheduleTransition(t, pdp.privilegedPolicies.nextScheduleTransition(t, limit))
+}
+
 func newPolicyTier(privileged bool) policyTier {
 	return policyTier{
-		privileged:    privileged,
-		denyPolicies:  connPolicyMap{},
-		allowPolicies: connPolicyMap{},
+		privileged:          privileged,
+		prioritizedPolicies: connPolicyMap{},
+		denyPolicies:        connPolicyMap{},
+		allowPolicies:       connPolicyMap{},
+		schedules:           map[types.NamespacedName]*policySchedule{},
+		workloadSets:        newWorkloadSetMap(),
 	}
 }
 
 func (pt *policyTier) getPolicies() connPolicyMap {
-	res := pt.denyPolicies
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
 
-	for key, val := range pt.allowPolicies {
-		res[key] = val
+	res := connPolicyMap{}
+	for _, policies := range []connPolicyMap{pt.prioritizedPolicies, pt.denyPolicies, pt.allowPolicies} {
+		for key, val := range policies {
+			res[key] = val
+		}
 	}
 	return res
 }
 
 // dependsOnClientAttrs returns whether any of the tier's policies has a From field which depends on specific attributes.
 func (pt *policyTier) dependsOnClientAttrs() bool {
-	return pt.denyPolicies.dependsOnClientAttrs() || pt.allowPolicies.dependsOnClientAttrs()
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+	return pt.prioritizedPolicies.dependsOnClientAttrs(&pt.workloadSets) ||
+		pt.denyPolicies.dependsOnClientAttrs(&pt.workloadSets) ||
+		pt.allowPolicies.dependsOnClientAttrs(&pt.workloadSets)
+}
+
+// getPolicy returns the policy with the given name, or nil if it does not exist in the tier.
+func (pt *policyTier) getPolicy(policyName types.NamespacedName) *v1alpha1.AccessPolicySpec {
+	if policy, ok := pt.prioritizedPolicies[policyName]; ok {
+		return policy
+	}
+	if policy, ok := pt.denyPolicies[policyName]; ok {
+		return policy
+	}
+	return pt.allowPolicies[policyName]
+}
+
+// danglingWorkloadSets returns the sorted names of the WorkloadSets referenced by the given policy,
+// which do not exist in the tier.
+func (pt *policyTier) danglingWorkloadSets(policyName types.NamespacedName) []string {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+
+	policy := pt.getPolicy(policyName)
+	if policy == nil {
+		return nil
+	}
+
+	resolve := pt.workloadSets.resolver(policyName.Namespace)
+	dangling := map[string]bool{}
+	for _, name := range referencedWorkloadSets(policy) {
+		if resolve(name) == nil {
+			dangling[name] = true
+		}
+	}
+
+	res := make([]string, 0, len(dangling))
+	for name := range dangling {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// policiesReferencingWorkloadSet returns the names of the tier's policies referencing the given WorkloadSet.
+func (pt *policyTier) policiesReferencingWorkloadSet(setName types.NamespacedName) []types.NamespacedName {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+
+	var res []types.NamespacedName
+	for _, policies := range []connPolicyMap{pt.prioritizedPolicies, pt.denyPolicies, pt.allowPolicies} {
+		for policyName, policy := range policies {
+			if policyName.Namespace != setName.Namespace {
+				continue
+			}
+			for _, name := range referencedWorkloadSets(policy) {
+				if name == setName.Name {
+					res = append(res, policyName)
+					break
+				}
+			}
+		}
+	}
+	return res
 }
 
-// addPolicy adds an access policy to the given tier, based on its action.
+// nextScheduleTransition returns the first time after t, and no later than limit,
+// at which a time-restricted policy of the tier comes into effect or goes out of effect.
+func (pt *policyTier) nextScheduleTransition(t, limit time.Time) time.Time {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+
+	for _, schedule := range pt.schedules {
+		limit = schedule.nextTransition(t, limit)
+	}
+	return limit
+}
+
+// referencedWorkloadSets returns the names of all WorkloadSets referenced by a policy.
+func referencedWorkloadSets(policy *v1alpha1.AccessPolicySpec) []string {
+	var res []string
+	for _, wsl := range []v1alpha1.WorkloadSetOrSelectorList{policy.From, policy.To} {
+		for i := range wsl {
+			res = append(res, wsl[i].WorkloadSets...)
+		}
+	}
+	return res
+}
+
+// addPolicy adds an access policy to the given tier, based on its priority and action.
 // Note that within a tier, no two policies can have the same name, even if one is deny and the other is allow.
-func (pt *policyTier) addPolicy(policyName types.NamespacedName, policySpec *v1alpha1.AccessPolicySpec) {
+func (pt *policyTier) addPolicy(
+	policyName types.NamespacedName, policySpec *v1alpha1.AccessPolicySpec, schedule *policySchedule,
+) {
 	pt.lock.Lock()
 	defer pt.lock.Unlock()
 	//nolint:errcheck // ignore return value as we just want to make sure non exists
 	_ = pt.unsafeDeletePolicy(policyName) // delete an existing policy with the same name, if it exists
-	if policySpec.Action == v1alpha1.AccessPolicyActionDeny {
+	if schedule != nil {
+		pt.schedules[policyName] = schedule
+	}
+	switch {
+	case policySpec.Priority != nil:
+		pt.prioritizedPolicies[policyName] = policySpec
+		pt.sortPrioritizedPolicies()
+	case policySpec.Action == v1alpha1.AccessPolicyActionDeny:
 		pt.denyPolicies[policyName] = policySpec
-	} else {
+	default:
 		pt.allowPolicies[policyName] = policySpec
 	}
 }
 
+// sortPrioritizedPolicies updates the evaluation order of the prioritized policies:
+// by ascending priority, and then by name. Must be called with the tier lock held.
+func (pt *policyTier) sortPrioritizedPolicies() {
+	pt.priorityOrder = make([]types.NamespacedName, 0, len(pt.prioritizedPolicies))
+	for policyName := range pt.prioritizedPolicies {
+		pt.priorityOrder = append(pt.priorityOrder, policyName)
+	}
+
+	sort.Slice(pt.priorityOrder, func(i, j int) bool {
+		name1, name2 := pt.priorityOrder[i], pt.priorityOrder[j]
+		priority1 := *pt.prioritizedPolicies[name1].Priority
+		priority2 := *pt.prioritizedPolicies[name2].Priority
+		if priority1 != priority2 {
+			return priority1 < priority2
+		}
+		return name1.String() < name2.String()
+	})
+}
+
 // deletePolicy deletes a AccessPolicy with the given name from the given tier.
 // If no such AccessPolicy exists in the tier, an error is returned.
 func (pt *policyTier) deletePolicy(policyName types.NamespacedName) error {
@@ -209,6 +410,13 @@ func (pt *policyTier) deletePolicy(policyName types.NamespacedName) error {
 // unsafeDeletePolicy does the actual deleting of the given policy, but without locking.
 // Do not use directly.
 func (pt *policyTier) unsafeDeletePolicy(policyName types.NamespacedName) error {
+	delete(pt.schedules, policyName)
+	if _, ok := pt.prioritizedPolicies[policyName]; ok {
+		delete(pt.prioritizedPolicies, policyName)
+		pt.sortPrioritizedPolicies()
+		return nil
+	}
+
 	var okDeny, okAllow bool
 	if _, okDeny = pt.denyPolicies[policyName]; okDeny {
 		delete(pt.denyPolicies, policyName)
@@ -222,16 +430,27 @@ func (pt *policyTier) unsafeDeletePolicy(policyName types.NamespacedName) error
 	return nil
 }
 
-// decide first checks whether any of the tier's deny policies matches any of the not-yet-decided connection
-// between src and dest. If one policy does, the DestinationDecision will
+// decide first checks the tier's prioritized policies, by their order, and the first policy to match
+// the not-yet-decided connection between src and dest takes the decision.
+// If the connection is not decided, the function checks whether any of the tier's deny policies matches
+// the connection. If one policy does, the DestinationDecision will
 // be updated to reflect the connection been denied.
 // If the connection is not decided, the function then checks whether any of the tier's allow policies matches,
 // and will similarly update the DestinationDecision.
+// Policies which are not in effect at the time of the decision (see policySchedule) are ignored.
 // returns whether the destination was decided and an error (if occurred).
 func (pt *policyTier) decide(src WorkloadAttrs, dest *DestinationDecision, ns string) (bool, error) {
 	pt.lock.RLock() // allowing multiple simultaneous calls to decide() to be served
 	defer pt.lock.RUnlock()
-	decided, err := pt.denyPolicies.decide(src, dest, pt.privileged, ns)
+	now := time.Now()
+	for _, policyName := range pt.priorityOrder {
+		decided, err := pt.decideByPolicy(policyName, pt.prioritizedPolicies[policyName], src, dest, ns, now)
+		if err != nil || decided {
+			return decided, err
+		}
+	}
+
+	decided, err := pt.decideByPolicies(pt.denyPolicies, src, dest, ns, now)
 	if err != nil {
 		return false, err
 	}
@@ -239,59 +458,95 @@ func (pt *policyTier) decide(src WorkloadAttrs, dest *DestinationDecision, ns st
 		return true, nil
 	}
 
-	decided, err = pt.allowPolicies.decide(src, dest, pt.privileged, ns)
+	decided, err = pt.decideByPolicies(pt.allowPolicies, src, dest, ns, now)
 	if err != nil {
 		return false, err
 	}
 	return decided, nil
 }
 
-// decide iterates over all policies in a connPolicyMap and checks if they make a connectivity decision (allow/deny)
-// on the not-yet-decided connection between src and dest.
+// decideByPolicies iterates over all policies in a connPolicyMap and checks if they make a connectivity decision
+// (allow/deny) on the not-yet-decided connection between src and dest.
 // returns whether the destination was decided and an error (if occurred).
-func (cpm connPolicyMap) decide(src WorkloadAttrs, dest *DestinationDecision, privileged bool, ns string) (bool, error) {
+func (pt *policyTier) decideByPolicies(
+	cpm connPolicyMap, src WorkloadAttrs, dest *DestinationDecision, ns string, now time.Time,
+) (bool, error) {
 	// for when there are no policies in cpm (some destinations are undecided, otherwise we shouldn't be here)
 	for policyName, policy := range cpm {
-		if !privileged && policyName.Namespace != ns { // Only consider non-privileged policies from the given namespace
-			continue
+		decided, err := pt.decideByPolicy(policyName, policy, src, dest, ns, now)
+		if err != nil || decided {
+			return decided, err
 		}
+	}
 
-		decision, err := accessPolicyDecide(policy, src, dest.Destination)
-		if err != nil {
-			return false, err
-		}
-		if decision != DecisionUndecided { // policy matched - we now have a decision for dest
-			dest.Decision = decision
-			dest.MatchedBy = policyName.String()
-			dest.PrivilegedMatch = privileged
-			return true, nil
-		}
+	return false, nil
+}
+
+// decideByPolicy checks if a single policy makes a connectivity decision (allow/deny)
+// on the not-yet-decided connection between src and dest.
+// returns whether the destination was decided and an error (if occurred).
+func (pt *policyTier) decideByPolicy(
+	policyName types.NamespacedName, policy *v1alpha1.AccessPolicySpec,
+	src WorkloadAttrs, dest *DestinationDecision, ns string, now time.Time,
+) (bool, error) {
+	if !pt.privileged && policyName.Namespace != ns { // Only consider non-privileged policies from the given namespace
+		return false, nil
+	}
+	if !pt.schedules[policyName].inEffect(now) {
+		return false, nil
+	}
+
+	decision, err := accessPolicyDecide(policy, src, dest.Destination, pt.workloadSets.resolver(policyName.Namespace))
+	if err != nil {
+		return false, err
+	}
+	if decision != DecisionUndecided { // policy matched - we now have a decision for dest
+		dest.Decision = decision
+		dest.MatchedBy = policyName.String()
+		dest.PrivilegedMatch = pt.privileged
+		return true, nil
 	}
 
 	return false, nil
 }
 
 // dependsOnClientAttrs returns whether any of the policies has a From field which depends on specific attributes.
-func (cpm connPolicyMap) dependsOnClientAttrs() bool {
-	for _, policySpec := range cpm {
+func (cpm connPolicyMap) dependsOnClientAttrs(workloadSets *workloadSetMap) bool {
+	for policyName, policySpec := range cpm {
+		resolve := workloadSets.resolver(policyName.Namespace)
 		for i := range policySpec.From {
-			selector := policySpec.From[i].WorkloadSelector
-			if selector == nil {
-				continue
-			}
-			if len(selector.MatchExpressions) > 0 || len(selector.MatchLabels) > 0 {
+			if selectorDependsOnAttrs(policySpec.From[i].WorkloadSelector) {
 				return true
 			}
+
+			for _, name := range policySpec.From[i].WorkloadSets {
+				set := resolve(name)
+				if set == nil {
+					continue
+				}
+				for j := range set.WorkloadSelectors {
+					if selectorDependsOnAttrs(&set.WorkloadSelectors[j]) {
+						return true
+					}
+				}
+			}
 		}
 	}
 	return false
 }
 
+// selectorDependsOnAttrs returns whether a selector depends on specific attributes.
+func selectorDependsOnAttrs(selector *metav1.LabelSelector) bool {
+	return selector != nil && (len(selector.MatchExpressions) > 0 || len(selector.MatchLabels) > 0)
+}
+
 // accessPolicyDecide returns a policy's decision on a given connection.
 // If the policy matches the connection, a decision based on its Action is returned.
 // Otherwise, it returns an "undecided" value.
-func accessPolicyDecide(policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAttrs) (Decision, error) {
-	matches, err := accessPolicyMatches(policy, src, dest)
+func accessPolicyDecide(
+	policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAttrs, resolve workloadSetResolver,
+) (Decision, error) {
+	matches, err := accessPolicyMatches(policy, src, dest, resolve)
 	if err != nil {
 		return DecisionDeny, err
 	}
@@ -306,9 +561,14 @@ func accessPolicyDecide(policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAtt
 
 // accessPolicyMatches checks if a connection from a source with given labels
 // to a destination with given labels, matches an AccessPolicy.
-func accessPolicyMatches(policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAttrs) (bool, error) {
+// A deny policy referencing a WorkloadSet which cannot be resolved fails closed: the reference matches all workloads.
+func accessPolicyMatches(policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAttrs, resolve workloadSetResolver) (bool, error) {
+	if policy.Action == v1alpha1.AccessPolicyActionDeny {
+		resolve = matchAllUnresolved(resolve)
+	}
+
 	// Check if source matches any element of the policy's "From" field
-	matched, err := WorkloadSetOrSelectorListMatches(&policy.From, src)
+	matched, err := workloadSetOrSelectorListMatches(&policy.From, src, resolve)
 	if err != nil {
 		return false, err
 	}
@@ -317,17 +577,45 @@ func accessPolicyMatches(policy *v1alpha1.AccessPolicySpec, src, dest WorkloadAt
 	}
 
 	// Check if destination matches any element of the policy's "To" field
-	matched, err = WorkloadSetOrSelectorListMatches(&policy.To, dest)
+	matched, err = workloadSetOrSelectorListMatches(&policy.To, dest, resolve)
 	if err != nil {
 		return false, err
 	}
-	return matched, nil
+	if !matched {
+		return false, nil
+	}
+
+	// Check if destination port matches any element of the policy's "Ports" field
+	return portsMatch(policy.Ports, dest), nil
+}
+
+// portsMatch checks whether the port and protocol attributes of a destination match any of the given ports.
+// An empty list of ports matches all destinations.
+func portsMatch(ports []v1alpha1.AccessPolicyPort, dest WorkloadAttrs) bool {
+	if len(ports) == 0 {
+		return true
+	}
+
+	for i := range ports {
+		if ports[i].Name != "" && ports[i].Name != dest[ServicePortAttr] {
+			continue
+		}
+		if ports[i].Protocol != "" && string(ports[i].Protocol) != dest[ServiceProtocolAttr] {
+			continue
+		}
+		return true
+	}
+
+	return false
 }
 
-// checks whether a workload with the given labels matches any item in a slice of WorkloadSetOrSelectors.
-func WorkloadSetOrSelectorListMatches(wsl *v1alpha1.WorkloadSetOrSelectorList, workloadAttrs WorkloadAttrs) (bool, error) {
+// checks whether a workload with the given labels matches any item in a slice of WorkloadSetOrSelectors,
+// using the given resolver for WorkloadSets.
+func workloadSetOrSelectorListMatches(
+	wsl *v1alpha1.WorkloadSetOrSelectorList, workloadAttrs WorkloadAttrs, resolve workloadSetResolver,
+) (bool, error) {
 	for i := range *wsl {
-		matched, err := workloadSetOrSelectorMatches(&(*wsl)[i], workloadAttrs)
+		matched, err := workloadSetOrSelectorMatches(&(*wsl)[i], workloadAttrs, resolve)
 		if err != nil {
 			return false, err
 		}
@@ -339,9 +627,37 @@ func WorkloadSetOrSelectorListMatches(wsl *v1alpha1.WorkloadSetOrSelectorList, w
 }
 
 // checks whether a workload with the given labels matches a WorkloadSetOrSelectors.
-func workloadSetOrSelectorMatches(wss *v1alpha1.WorkloadSetOrSelector, workloadAttrs WorkloadAttrs) (bool, error) {
-	// TODO: implement logic for WorkloadSet matching
-	selector, err := metav1.LabelSelectorAsSelector(wss.WorkloadSelector)
+// A reference to a WorkloadSet which cannot be resolved by the resolver matches no workload.
+func workloadSetOrSelectorMatches(
+	wss *v1alpha1.WorkloadSetOrSelector, workloadAttrs WorkloadAttrs, resolve workloadSetResolver,
+) (bool, error) {
+	if len(wss.WorkloadSets) == 0 {
+		return selectorMatches(wss.WorkloadSelector, workloadAttrs)
+	}
+
+	for _, name := range wss.WorkloadSets {
+		set := resolve(name)
+		if set == nil {
+			continue
+		}
+
+		for i := range set.WorkloadSelectors {
+			matched, err := selectorMatches(&set.WorkloadSelectors[i], workloadAttrs)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// checks whether a workload with the given labels matches a label selector.
+func selectorMatches(labelSelector *metav1.LabelSelector, workloadAttrs WorkloadAttrs) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
 	if err != nil {
 		return false, err
 	}
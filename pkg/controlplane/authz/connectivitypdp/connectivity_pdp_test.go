@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -89,6 +90,12 @@ func TestPrivilegedVsRegular(t *testing.T) {
 	require.Equal(t, true, decision.PrivilegedMatch)
 }
 
+func TestDecisionString(t *testing.T) {
+	require.Equal(t, "undecided", connectivitypdp.DecisionUndecided.String())
+	require.Equal(t, "allow", connectivitypdp.DecisionAllow.String())
+	require.Equal(t, "deny", connectivitypdp.DecisionDeny.String())
+}
+
 // TestAllLayers starts with one policy per layer (allow/deny X privileged/non-privileged)
 // Policies are set s.t., they capture more connections as their priority is lower.
 // We then test connections that should match the policy in a specific layer,
@@ -241,6 +248,285 @@ func TestDependsOnClientAttrs(t *testing.T) {
 	require.True(t, pdp.DependsOnClientAttrs())
 }
 
+func TestPolicyPriority(t *testing.T) {
+	narrowLabel := connectivitypdp.WorkloadAttrs{"key": "val", "narrow": "true"}
+	narrowSelector := metav1.LabelSelector{MatchLabels: narrowLabel}
+	narrowWorkloadSet := v1alpha1.WorkloadSetOrSelector{WorkloadSelector: &narrowSelector}
+	newPolicy := func(name string, action v1alpha1.AccessPolicyAction,
+		from v1alpha1.WorkloadSetOrSelector, priority *int32,
+	) *v1alpha1.AccessPolicy {
+		return &v1alpha1.AccessPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNS},
+			Spec: v1alpha1.AccessPolicySpec{
+				Action:   action,
+				From:     []v1alpha1.WorkloadSetOrSelector{from},
+				To:       []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+				Priority: priority,
+			},
+		}
+	}
+	priority := func(p int32) *int32 { return &p }
+	matchedBy := func(name string) string {
+		return types.NamespacedName{Name: name, Namespace: defaultNS}.String()
+	}
+
+	pdp := connectivitypdp.NewPDP()
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("broad-deny", v1alpha1.AccessPolicyActionDeny, trivialWorkloadSet, nil))))
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("narrow-allow", v1alpha1.AccessPolicyActionAllow, narrowWorkloadSet, nil))))
+
+	// with no priorities, deny takes precedence
+	decision, err := pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	require.Equal(t, matchedBy("broad-deny"), decision.MatchedBy)
+
+	// a prioritized allow policy is evaluated before the deny policy
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("narrow-allow", v1alpha1.AccessPolicyActionAllow, narrowWorkloadSet, priority(10)))))
+	decision, err = pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+	require.Equal(t, matchedBy("narrow-allow"), decision.MatchedBy)
+	decision, err = pdp.Decide(trivialLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	require.Equal(t, matchedBy("broad-deny"), decision.MatchedBy)
+
+	// a lower priority value takes precedence
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("narrow-deny", v1alpha1.AccessPolicyActionDeny, narrowWorkloadSet, priority(5)))))
+	decision, err = pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	require.Equal(t, matchedBy("narrow-deny"), decision.MatchedBy)
+
+	// equal priorities are ordered by name
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("narrow-deny", v1alpha1.AccessPolicyActionDeny, narrowWorkloadSet, priority(10)))))
+	decision, err = pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+	require.Equal(t, matchedBy("narrow-allow"), decision.MatchedBy)
+
+	// deleting a prioritized policy
+	require.Nil(t, pdp.DeletePolicy(types.NamespacedName{Name: "narrow-allow", Namespace: defaultNS}, false))
+	decision, err = pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	require.Equal(t, matchedBy("narrow-deny"), decision.MatchedBy)
+	require.Len(t, pdp.GetPolicies(), 2)
+
+	// privileged policies take precedence over prioritized regular policies
+	privilegedDeny := v1alpha1.PrivilegedAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "priv-deny"},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionDeny,
+			From:   []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(
+		newPolicy("narrow-allow", v1alpha1.AccessPolicyActionAllow, narrowWorkloadSet, priority(0)))))
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromPrivilegedCR(&privilegedDeny)))
+	decision, err = pdp.Decide(narrowLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	require.True(t, decision.PrivilegedMatch)
+}
+
+func TestPortRestrictions(t *testing.T) {
+	policy := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin-only", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			Ports: []v1alpha1.AccessPolicyPort{
+				{Name: "admin", Protocol: v1alpha1.ProtocolTCP},
+				{Protocol: v1alpha1.ProtocolUDP},
+			},
+		},
+	}
+	dest := func(port, protocol string) connectivitypdp.WorkloadAttrs {
+		return connectivitypdp.WorkloadAttrs{
+			"key":                               "val",
+			connectivitypdp.ServicePortAttr:     port,
+			connectivitypdp.ServiceProtocolAttr: protocol,
+		}
+	}
+
+	pdp := connectivitypdp.NewPDP()
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+
+	tests := []struct {
+		dest     connectivitypdp.WorkloadAttrs
+		decision connectivitypdp.Decision
+	}{
+		{dest: dest("admin", "TCP"), decision: connectivitypdp.DecisionAllow},
+		{dest: dest("http", "TCP"), decision: connectivitypdp.DecisionDeny},
+		{dest: dest("admin", "UDP"), decision: connectivitypdp.DecisionAllow}, // matches the UDP port
+		{dest: dest("dns", "UDP"), decision: connectivitypdp.DecisionAllow},
+		{dest: dest("", "TCP"), decision: connectivitypdp.DecisionDeny},
+		{dest: trivialLabel, decision: connectivitypdp.DecisionDeny}, // no port attributes
+	}
+	for _, test := range tests {
+		decision, err := pdp.Decide(trivialLabel, test.dest, defaultNS)
+		require.Nil(t, err)
+		require.Equal(t, test.decision, decision.Decision, "destination: %v", test.dest)
+	}
+
+	// no ports - all ports match
+	policy.Spec.Ports = nil
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+	decision, err := pdp.Decide(trivialLabel, dest("http", "TCP"), defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+}
+
+func TestTimeRestrictedPolicies(t *testing.T) {
+	policy := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "temporary", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+	now := time.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	inHour := metav1.NewTime(now.Add(time.Hour))
+
+	tests := []struct {
+		name      string
+		notBefore *metav1.Time
+		notAfter  *metav1.Time
+		schedule  *v1alpha1.AccessPolicySchedule
+		decision  connectivitypdp.Decision
+	}{
+		{name: "within validity period", notBefore: &hourAgo, notAfter: &inHour, decision: connectivitypdp.DecisionAllow},
+		{name: "not yet valid", notBefore: &inHour, decision: connectivitypdp.DecisionDeny},
+		{name: "expired", notAfter: &hourAgo, decision: connectivitypdp.DecisionDeny},
+		{
+			name:     "in schedule",
+			schedule: &v1alpha1.AccessPolicySchedule{Cron: "* * * * *", TimeZone: "Asia/Tokyo"},
+			decision: connectivitypdp.DecisionAllow,
+		},
+		{
+			name:     "out of schedule",
+			schedule: &v1alpha1.AccessPolicySchedule{Cron: fmt.Sprintf("%d * * * *", (now.UTC().Minute()+30)%60)},
+			decision: connectivitypdp.DecisionDeny,
+		},
+		{
+			name:     "in schedule, but expired",
+			notAfter: &hourAgo,
+			schedule: &v1alpha1.AccessPolicySchedule{Cron: "* * * * *"},
+			decision: connectivitypdp.DecisionDeny,
+		},
+	}
+
+	for _, test := range tests {
+		policy.Spec.NotBefore = test.notBefore
+		policy.Spec.NotAfter = test.notAfter
+		policy.Spec.Schedule = test.schedule
+
+		pdp := connectivitypdp.NewPDP()
+		require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)), test.name)
+		decision, err := pdp.Decide(trivialLabel, trivialLabel, defaultNS)
+		require.Nil(t, err)
+		require.Equal(t, test.decision, decision.Decision, test.name)
+	}
+
+	// invalid time restrictions
+	pdp := connectivitypdp.NewPDP()
+	policy.Spec.NotBefore = &inHour
+	policy.Spec.NotAfter = &hourAgo
+	policy.Spec.Schedule = nil
+	require.NotNil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+
+	policy.Spec.NotBefore = nil
+	policy.Spec.NotAfter = nil
+	policy.Spec.Schedule = &v1alpha1.AccessPolicySchedule{Cron: "* * * *"}
+	require.NotNil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+
+	policy.Spec.Schedule = &v1alpha1.AccessPolicySchedule{Cron: "* * * * *", TimeZone: "No/Such_Zone"}
+	require.NotNil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+}
+
+func TestNextScheduleTransition(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 10, 0, 30, 0, time.UTC)
+	limit := now.Add(time.Hour)
+	newPolicy := func(name string) *v1alpha1.AccessPolicy {
+		return &v1alpha1.AccessPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNS},
+			Spec: v1alpha1.AccessPolicySpec{
+				Action: v1alpha1.AccessPolicyActionAllow,
+				From:   []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+				To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			},
+		}
+	}
+
+	// policies which are not time-restricted never change their effect
+	pdp := connectivitypdp.NewPDP()
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(newPolicy("permanent"))))
+	require.Equal(t, limit, pdp.NextScheduleTransition(now, limit))
+
+	inMinute := metav1.NewTime(now.Add(time.Minute))
+	inHalfHour := metav1.NewTime(now.Add(30 * time.Minute))
+	tests := []struct {
+		name       string
+		notBefore  *metav1.Time
+		notAfter   *metav1.Time
+		schedule   *v1alpha1.AccessPolicySchedule
+		transition time.Time
+	}{
+		{name: "comes into effect", notBefore: &inHalfHour, transition: inHalfHour.Time},
+		{name: "goes out of effect", notAfter: &inMinute, transition: inMinute.Add(time.Nanosecond)},
+		{
+			name:       "in schedule until the next minute",
+			schedule:   &v1alpha1.AccessPolicySchedule{Cron: "0 * * * *"},
+			transition: time.Date(2026, time.January, 1, 10, 1, 0, 0, time.UTC),
+		},
+		{
+			name:       "out of schedule until the next hour",
+			schedule:   &v1alpha1.AccessPolicySchedule{Cron: "0 * * * *", TimeZone: "Asia/Kolkata"},
+			transition: time.Date(2026, time.January, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "out of schedule until after the limit",
+			schedule:   &v1alpha1.AccessPolicySchedule{Cron: "0 0 * * *"},
+			transition: limit,
+		},
+		{
+			name:       "schedule starting after the validity period",
+			notAfter:   &inMinute,
+			schedule:   &v1alpha1.AccessPolicySchedule{Cron: "30 * * * *"},
+			transition: limit,
+		},
+	}
+
+	for _, test := range tests {
+		policy := newPolicy("temporary")
+		policy.Spec.NotBefore = test.notBefore
+		policy.Spec.NotAfter = test.notAfter
+		policy.Spec.Schedule = test.schedule
+		require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(policy)), test.name)
+		require.Equal(t, test.transition, pdp.NextScheduleTransition(now, limit), test.name)
+	}
+
+	// the earliest transition of any tier
+	privilegedPolicy := v1alpha1.PrivilegedAccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "privileged"},
+		Spec:       newPolicy("privileged").Spec,
+	}
+	privilegedPolicy.Spec.NotAfter = &inMinute
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromPrivilegedCR(&privilegedPolicy)))
+	require.Equal(t, inMinute.Add(time.Nanosecond), pdp.NextScheduleTransition(now, limit))
+	require.Equal(t, now.Add(time.Second), pdp.NextScheduleTransition(now, now.Add(time.Second)))
+}
+
 func TestDeleteNonexistingPolicies(t *testing.T) {
 	pdp := connectivitypdp.NewPDP()
 	err := pdp.DeletePolicy(types.NamespacedName{Name: "no-such-policy"}, true)
@@ -265,6 +551,37 @@ func TestBadSelector(t *testing.T) {
 	pdp := connectivitypdp.NewPDP()
 	err := pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&badSelectorPol))
 	require.NotNil(t, err)
+	require.False(t, pdp.HasPolicy(types.NamespacedName{Name: "aBadPolicy"}, false))
+}
+
+func TestInvalidPolicyUpdate(t *testing.T) {
+	policy := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pol", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+	policyName := types.NamespacedName{Name: "pol", Namespace: defaultNS}
+
+	pdp := connectivitypdp.NewPDP()
+	require.False(t, pdp.HasPolicy(policyName, false))
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+	require.True(t, pdp.HasPolicy(policyName, false))
+	require.False(t, pdp.HasPolicy(policyName, true))
+
+	// an invalid update is rejected, leaving the previous version in force
+	policy.Spec.Action = "reject"
+	require.NotNil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+	require.True(t, pdp.HasPolicy(policyName, false))
+	decision, err := pdp.Decide(trivialLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+	require.Equal(t, policyName.String(), decision.MatchedBy)
+
+	require.Nil(t, pdp.DeletePolicy(policyName, false))
+	require.False(t, pdp.HasPolicy(policyName, false))
 }
 
 func TestNonexistingPolicyFile(t *testing.T) {
@@ -324,3 +641,117 @@ func addPoliciesFromFile(pdp *connectivitypdp.PDP, filename string) error {
 		}
 	}
 }
+
+func TestWorkloadSets(t *testing.T) {
+	frontendLabel := connectivitypdp.WorkloadAttrs{"app": "frontend"}
+	backendLabel := connectivitypdp.WorkloadAttrs{"app": "backend"}
+	frontendSet := v1alpha1.WorkloadSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontends", Namespace: defaultNS},
+		Spec: v1alpha1.WorkloadSetSpec{
+			WorkloadSelectors: []metav1.LabelSelector{{MatchLabels: frontendLabel}},
+		},
+	}
+	setRef := []v1alpha1.WorkloadSetOrSelector{{WorkloadSets: []string{"frontends"}}}
+	policy := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontends", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   setRef,
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+	policyName := types.NamespacedName{Name: policy.Name, Namespace: policy.Namespace}
+	setName := types.NamespacedName{Name: frontendSet.Name, Namespace: frontendSet.Namespace}
+
+	pdp := connectivitypdp.NewPDP()
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&policy)))
+	require.Equal(t, []types.NamespacedName{policyName}, pdp.PoliciesReferencingWorkloadSet(setName, false))
+	require.Empty(t, pdp.PoliciesReferencingWorkloadSet(setName, true))
+
+	// a dangling reference matches no workload
+	require.Equal(t, []string{"frontends"}, pdp.DanglingWorkloadSets(policyName, false))
+	require.False(t, pdp.DependsOnClientAttrs())
+	decision, err := pdp.Decide(frontendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+
+	require.Nil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromCR(&frontendSet)))
+	require.Empty(t, pdp.DanglingWorkloadSets(policyName, false))
+	require.True(t, pdp.DependsOnClientAttrs())
+	decision, err = pdp.Decide(frontendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+	decision, err = pdp.Decide(backendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+
+	// updating the set applies to the referencing policy
+	frontendSet.Spec.WorkloadSelectors = append(frontendSet.Spec.WorkloadSelectors,
+		metav1.LabelSelector{MatchLabels: backendLabel})
+	require.Nil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromCR(&frontendSet)))
+	decision, err = pdp.Decide(backendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+
+	// sets are not visible across namespaces and tiers
+	otherNSSet := frontendSet
+	otherNSSet.Namespace = "other"
+	privilegedSet := v1alpha1.PrivilegedWorkloadSet{ObjectMeta: metav1.ObjectMeta{Name: "frontends"}, Spec: frontendSet.Spec}
+	require.Nil(t, pdp.DeleteWorkloadSet(setName, false))
+	require.Nil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromCR(&otherNSSet)))
+	require.Nil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromPrivilegedCR(&privilegedSet)))
+	require.Equal(t, []string{"frontends"}, pdp.DanglingWorkloadSets(policyName, false))
+	decision, err = pdp.Decide(frontendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+
+	require.NotNil(t, pdp.DeleteWorkloadSet(setName, false))
+	require.NotNil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromCR(&v1alpha1.WorkloadSet{})))
+}
+
+func TestDenyPolicyDanglingWorkloadSet(t *testing.T) {
+	frontendLabel := connectivitypdp.WorkloadAttrs{"app": "frontend"}
+	backendLabel := connectivitypdp.WorkloadAttrs{"app": "backend"}
+	allowAll := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-all", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+	denyFrontends := v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-frontends", Namespace: defaultNS},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionDeny,
+			From:   []v1alpha1.WorkloadSetOrSelector{{WorkloadSets: []string{"frontends"}}},
+			To:     []v1alpha1.WorkloadSetOrSelector{trivialWorkloadSet},
+		},
+	}
+
+	pdp := connectivitypdp.NewPDP()
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&allowAll)))
+	require.Nil(t, pdp.AddOrUpdatePolicy(connectivitypdp.PolicyFromCR(&denyFrontends)))
+
+	// a dangling reference of a deny policy matches all workloads
+	for _, label := range []connectivitypdp.WorkloadAttrs{frontendLabel, backendLabel} {
+		decision, err := pdp.Decide(label, trivialLabel, defaultNS)
+		require.Nil(t, err)
+		require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+		require.Equal(t, "default/deny-frontends", decision.MatchedBy)
+	}
+
+	// once resolved, the reference matches only the workloads in the set
+	require.Nil(t, pdp.AddOrUpdateWorkloadSet(connectivitypdp.WorkloadSetFromCR(&v1alpha1.WorkloadSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontends", Namespace: defaultNS},
+		Spec: v1alpha1.WorkloadSetSpec{
+			WorkloadSelectors: []metav1.LabelSelector{{MatchLabels: frontendLabel}},
+		},
+	})))
+	decision, err := pdp.Decide(frontendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionDeny, decision.Decision)
+	decision, err = pdp.Decide(backendLabel, trivialLabel, defaultNS)
+	require.Nil(t, err)
+	require.Equal(t, connectivitypdp.DecisionAllow, decision.Decision)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivitypdp
+
+import (
+	"sort"
+	"time"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/util/cron"
+)
+
+// policySchedule is the parsed validity period and recurring schedule of an AccessPolicy.
+type policySchedule struct {
+	notBefore *time.Time
+	notAfter  *time.Time
+	cron      *cron.Schedule
+	location  *time.Location
+}
+
+// newPolicySchedule parses the validity period and schedule of an AccessPolicy.
+// Returns nil if the policy is not time-restricted.
+func newPolicySchedule(spec *v1alpha1.AccessPolicySpec) (*policySchedule, error) {
+	if spec.NotBefore == nil && spec.NotAfter == nil && spec.Schedule == nil {
+		return nil, nil
+	}
+
+	schedule := &policySchedule{}
+	if spec.NotBefore != nil {
+		schedule.notBefore = &spec.NotBefore.Time
+	}
+	if spec.NotAfter != nil {
+		schedule.notAfter = &spec.NotAfter.Time
+	}
+
+	if spec.Schedule != nil {
+		var err error
+		if schedule.cron, err = cron.Parse(spec.Schedule.Cron); err != nil {
+			return nil, err
+		}
+		if schedule.location, err = spec.Schedule.Location(); err != nil {
+			return nil, err
+		}
+	}
+
+	return schedule, nil
+}
+
+// inEffect returns whether a policy with the given schedule is in effect at the given time.
+// A nil schedule is always in effect.
+func (s *policySchedule) inEffect(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+
+	if s.notBefore != nil && t.Before(*s.notBefore) {
+		return false
+	}
+	if s.notAfter != nil && t.After(*s.notAfter) {
+		return false
+	}
+
+	return s.cron == nil || s.cron.Matches(t.In(s.location))
+}
+
+// nextTransition returns the first time after t, and no later than limit, at which a policy with the given
+// schedule comes into effect or goes out of effect. Returns limit if the policy effect does not change until then.
+// A nil schedule never changes its effect.
+func (s *policySchedule) nextTransition(t, limit time.Time) time.Time {
+	if s == nil {
+		return limit
+	}
+
+	// the policy effect may only change at the start or the end of its validity period,
+	// or (if it has a recurring schedule) at the start of a minute
+	var candidates []time.Time
+	if s.notBefore != nil && s.notBefore.After(t) {
+		candidates = append(candidates, *s.notBefore)
+	}
+	if s.notAfter != nil && !s.notAfter.Before(t) {
+		candidates = append(candidates, s.notAfter.Add(time.Nanosecond))
+	}
+	if s.cron != nil {
+		for minute := t.Truncate(time.Minute).Add(time.Minute); !minute.After(limit); minute = minute.Add(time.Minute) {
+			candidates = append(candidates, minute)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Before(candidates[j])
+	})
+
+	inEffect := s.inEffect(t)
+	for _, candidate := range candidates {
+		if candidate.After(limit) {
+			break
+		}
+		if s.inEffect(candidate) != inEffect {
+			return candidate
+		}
+	}
+
+	return limit
+}
@@ -0,0 +1,101 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectivitypdp
+
+import (
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+// WorkloadSet is an opaque, PDP-internal, generalized representation of WorkloadSet and PrivilegedWorkloadSet CRDs.
+type WorkloadSet struct {
+	name       types.NamespacedName
+	privileged bool
+	spec       v1alpha1.WorkloadSetSpec
+}
+
+// WorkloadSetFromCR converts the WorkloadSet Custom Resource into the PDP's WorkloadSet.
+func WorkloadSetFromCR(ws *v1alpha1.WorkloadSet) *WorkloadSet {
+	return &WorkloadSet{
+		name:       types.NamespacedName{Namespace: ws.Namespace, Name: ws.Name},
+		privileged: false,
+		spec:       ws.Spec,
+	}
+}
+
+// WorkloadSetFromPrivilegedCR converts the PrivilegedWorkloadSet Custom Resource into the PDP's WorkloadSet.
+func WorkloadSetFromPrivilegedCR(ws *v1alpha1.PrivilegedWorkloadSet) *WorkloadSet {
+	return &WorkloadSet{
+		name:       types.NamespacedName{Name: ws.Name},
+		privileged: true,
+		spec:       ws.Spec,
+	}
+}
+
+// workloadSetResolver returns the spec of a workload set referenced by name, or nil if it does not exist.
+type workloadSetResolver func(name string) *v1alpha1.WorkloadSetSpec
+
+// allWorkloads is the spec of a workload set which contains all workloads.
+var allWorkloads = &v1alpha1.WorkloadSetSpec{WorkloadSelectors: []metav1.LabelSelector{{}}}
+
+// matchAllUnresolved returns a workloadSetResolver which resolves the workload sets not resolved by the given resolver
+// as containing all workloads.
+func matchAllUnresolved(resolve workloadSetResolver) workloadSetResolver {
+	return func(name string) *v1alpha1.WorkloadSetSpec {
+		if set := resolve(name); set != nil {
+			return set
+		}
+		return allWorkloads
+	}
+}
+
+// workloadSetMap holds the workload sets of a policy tier, keyed by their names.
+type workloadSetMap struct {
+	sets map[types.NamespacedName]*v1alpha1.WorkloadSetSpec
+	lock sync.RWMutex
+}
+
+func newWorkloadSetMap() workloadSetMap {
+	return workloadSetMap{sets: make(map[types.NamespacedName]*v1alpha1.WorkloadSetSpec)}
+}
+
+func (wsm *workloadSetMap) add(name types.NamespacedName, spec *v1alpha1.WorkloadSetSpec) {
+	wsm.lock.Lock()
+	defer wsm.lock.Unlock()
+	wsm.sets[name] = spec
+}
+
+func (wsm *workloadSetMap) delete(name types.NamespacedName) error {
+	wsm.lock.Lock()
+	defer wsm.lock.Unlock()
+	if _, ok := wsm.sets[name]; !ok {
+		return fmt.Errorf("failed deleting WorkloadSet %s", name)
+	}
+	delete(wsm.sets, name)
+	return nil
+}
+
+// resolver returns a workloadSetResolver for policies in the given namespace.
+func (wsm *workloadSetMap) resolver(namespace string) workloadSetResolver {
+	return func(name string) *v1alpha1.WorkloadSetSpec {
+		wsm.lock.RLock()
+		defer wsm.lock.RUnlock()
+		return wsm.sets[types.NamespacedName{Namespace: namespace, Name: name}]
+	}
+}
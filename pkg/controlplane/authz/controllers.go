@@ -15,6 +15,7 @@ package authz
 
 import (
 	"context"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,10 +32,17 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 		Name:   "authz.access-policy",
 		Object: &v1alpha1.AccessPolicy{},
 		AddHandler: func(ctx context.Context, object any) error {
-			accPolicy := connectivitypdp.PolicyFromCR(object.(*v1alpha1.AccessPolicy))
-			return mgr.AddAccessPolicy(accPolicy)
+			policy := object.(*v1alpha1.AccessPolicy)
+			err := mgr.AddAccessPolicy(connectivitypdp.PolicyFromCR(policy))
+			name := types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}
+			if err := mgr.updateAccessPolicyStatus(ctx, name, false, policy.Generation, err); err != nil {
+				return err
+			}
+
+			return mgr.updateAccessPolicyExpiry(ctx, name, false, &policy.Spec)
 		},
 		DeleteHandler: func(ctx context.Context, name types.NamespacedName) error {
+			mgr.scheduleAccessPolicyExpiry(name, false, time.Time{})
 			return mgr.DeleteAccessPolicy(name, false)
 		},
 	})
@@ -45,11 +53,18 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 	err = controller.AddToManager(controllerManager, &controller.Spec{
 		Name:   "authz.privileged-access-policy",
 		Object: &v1alpha1.PrivilegedAccessPolicy{},
-		AddHandler: func(_ context.Context, object any) error {
-			accPolicy := connectivitypdp.PolicyFromPrivilegedCR(object.(*v1alpha1.PrivilegedAccessPolicy))
-			return mgr.AddAccessPolicy(accPolicy)
+		AddHandler: func(ctx context.Context, object any) error {
+			policy := object.(*v1alpha1.PrivilegedAccessPolicy)
+			err := mgr.AddAccessPolicy(connectivitypdp.PolicyFromPrivilegedCR(policy))
+			name := types.NamespacedName{Name: policy.Name}
+			if err := mgr.updateAccessPolicyStatus(ctx, name, true, policy.Generation, err); err != nil {
+				return err
+			}
+
+			return mgr.updateAccessPolicyExpiry(ctx, name, true, &policy.Spec)
 		},
 		DeleteHandler: func(_ context.Context, name types.NamespacedName) error {
+			mgr.scheduleAccessPolicyExpiry(name, true, time.Time{})
 			return mgr.DeleteAccessPolicy(name, true)
 		},
 	})
@@ -57,6 +72,53 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 		return err
 	}
 
+	err = controller.AddToManager(controllerManager, &controller.Spec{
+		Name:   "authz.workload-set",
+		Object: &v1alpha1.WorkloadSet{},
+		AddHandler: func(ctx context.Context, object any) error {
+			workloadSet := object.(*v1alpha1.WorkloadSet)
+			if err := mgr.AddWorkloadSet(connectivitypdp.WorkloadSetFromCR(workloadSet)); err != nil {
+				return err
+			}
+
+			name := types.NamespacedName{Namespace: workloadSet.Namespace, Name: workloadSet.Name}
+			return mgr.updateWorkloadSetReferences(ctx, name, false)
+		},
+		DeleteHandler: func(ctx context.Context, name types.NamespacedName) error {
+			if err := mgr.DeleteWorkloadSet(name, false); err != nil {
+				return err
+			}
+
+			return mgr.updateWorkloadSetReferences(ctx, name, false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = controller.AddToManager(controllerManager, &controller.Spec{
+		Name:   "authz.privileged-workload-set",
+		Object: &v1alpha1.PrivilegedWorkloadSet{},
+		AddHandler: func(ctx context.Context, object any) error {
+			workloadSet := object.(*v1alpha1.PrivilegedWorkloadSet)
+			if err := mgr.AddWorkloadSet(connectivitypdp.WorkloadSetFromPrivilegedCR(workloadSet)); err != nil {
+				return err
+			}
+
+			return mgr.updateWorkloadSetReferences(ctx, types.NamespacedName{Name: workloadSet.Name}, true)
+		},
+		DeleteHandler: func(ctx context.Context, name types.NamespacedName) error {
+			if err := mgr.DeleteWorkloadSet(name, true); err != nil {
+				return err
+			}
+
+			return mgr.updateWorkloadSetReferences(ctx, name, true)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
 	err = controller.AddToManager(controllerManager, &controller.Spec{
 		Name:   "authz.peer",
 		Object: &v1alpha1.Peer{},
@@ -77,9 +139,15 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 		Name:   "authz.import",
 		Object: &v1alpha1.Import{},
 		AddHandler: func(ctx context.Context, object any) error {
+			mgr.decisionCache.Invalidate()
+			mgr.connectionsChanged()
 			return nil
 		},
 		DeleteHandler: func(ctx context.Context, name types.NamespacedName) error {
+			mgr.loadBalancer.DeleteImport(name)
+			mgr.scheduleSourcesHealthUpdate(name, time.Time{})
+			mgr.decisionCache.Invalidate()
+			mgr.connectionsChanged()
 			return nil
 		},
 	})
@@ -91,9 +159,12 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 		Name:   "authz.export",
 		Object: &v1alpha1.Export{},
 		AddHandler: func(ctx context.Context, object any) error {
+			mgr.decisionCache.Invalidate()
 			return nil
 		},
-		DeleteHandler: func(_ context.Context, _ types.NamespacedName) error {
+		DeleteHandler: func(_ context.Context, name types.NamespacedName) error {
+			mgr.rateLimiter.DeleteExport(name)
+			mgr.decisionCache.Invalidate()
 			return nil
 		},
 	})
@@ -0,0 +1,273 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+const (
+	// decisionCacheTTL is the maximal time a decision is cached, bounding the use of decisions missing an invalidation.
+	decisionCacheTTL = 30 * time.Second
+	// decisionCachePruneInterval is the interval for removing expired decisions from the cache.
+	decisionCachePruneInterval = 10 * time.Second
+)
+
+// DecisionCacheKey identifies a cached egress decision.
+type DecisionCacheKey struct {
+	// Client is the IP address of the client.
+	Client string
+	// Import is the name of the requested imported service.
+	Import types.NamespacedName
+	// Port is the port name of the requested imported service.
+	Port string
+	// Peer is the import source peer.
+	Peer string
+	// Export is the exported service of the import source, as specified by the import.
+	// Distinguishes between sources sharing the same peer.
+	Export types.NamespacedName
+}
+
+// EgressDecision is a cached decision on an egress connection of a client to an import source.
+type EgressDecision struct {
+	// PeerReachable is true if the source peer is reachable.
+	PeerReachable bool
+	// DstAttributes are the attributes of the import source.
+	DstAttributes connectivitypdp.WorkloadAttrs
+	// Decision is the decision of the access policies.
+	Decision *connectivitypdp.DestinationDecision
+}
+
+type decisionCacheEntry struct {
+	decision *EgressDecision
+	expiry   time.Time
+}
+
+// tokenCacheEntry holds spare single-use access tokens issued by a remote peer, for further connections.
+type tokenCacheEntry struct {
+	tokens []string
+	expiry time.Time
+}
+
+type importCacheEntry struct {
+	imp    *v1alpha1.Import
+	expiry time.Time
+}
+
+// DecisionCache caches imports and egress decisions, saving their lookup and evaluation on repeated connections.
+// Since each access token may be used once, it also caches the spare tokens of a batch issued by a remote peer,
+// each used by a single further connection.
+// Cached entries are invalidated on changes to the objects they depend on,
+// and decisions computed before an invalidation (of an older generation) are not cached.
+type DecisionCache struct {
+	lock       sync.Mutex
+	generation uint64
+	decisions  map[DecisionCacheKey]decisionCacheEntry
+	tokens     map[DecisionCacheKey]*tokenCacheEntry
+	imports    map[types.NamespacedName]importCacheEntry
+	nextPrune  time.Time
+
+	hits   uint64
+	misses uint64
+}
+
+// Generation returns the current generation of the cache, to be passed when adding entries.
+func (c *DecisionCache) Generation() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.generation
+}
+
+// GetImport returns a cached import. The returned import must not be modified.
+func (c *DecisionCache) GetImport(name types.NamespacedName) (*v1alpha1.Import, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.imports[name]
+	if !ok || !time.Now().Before(entry.expiry) {
+		return nil, false
+	}
+
+	return entry.imp, true
+}
+
+// AddImport caches an import, read at the given cache generation.
+func (c *DecisionCache) AddImport(imp *v1alpha1.Import, generation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	name := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+	c.imports[name] = importCacheEntry{imp: imp, expiry: time.Now().Add(decisionCacheTTL)}
+}
+
+// Get returns a cached egress decision, counting the cache hit or miss.
+func (c *DecisionCache) Get(key DecisionCacheKey) (*EgressDecision, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.decisions[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		c.misses++
+		decisionCacheLookupsMetric.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	c.hits++
+	decisionCacheLookupsMetric.WithLabelValues("hit").Inc()
+	return entry.decision, true
+}
+
+// Add caches an egress decision, computed at the given cache generation.
+// The decision is cached until the given time (e.g. a time-restricted policy changing its effect),
+// and no longer than decisionCacheTTL.
+func (c *DecisionCache) Add(key DecisionCacheKey, decision *EgressDecision, generation uint64, validUntil time.Time) {
+	now := time.Now()
+	expiry := now.Add(decisionCacheTTL)
+	if validUntil.Before(expiry) {
+		expiry = validUntil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if !now.Before(c.nextPrune) {
+		for k, entry := range c.decisions {
+			if !now.Before(entry.expiry) {
+				delete(c.decisions, k)
+			}
+		}
+		for k, entry := range c.tokens {
+			if !now.Before(entry.expiry) {
+				delete(c.tokens, k)
+			}
+		}
+		for name, entry := range c.imports {
+			if !now.Before(entry.expiry) {
+				delete(c.imports, name)
+			}
+		}
+		c.nextPrune = now.Add(decisionCachePruneInterval)
+	}
+
+	if !now.Before(expiry) {
+		return
+	}
+
+	c.decisions[key] = decisionCacheEntry{decision: decision, expiry: expiry}
+}
+
+// GetToken returns a cached access token for a connection, removing it from the cache.
+func (c *DecisionCache) GetToken(key DecisionCacheKey) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.tokens[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		tokenCacheLookupsMetric.WithLabelValues("miss").Inc()
+		return "", false
+	}
+
+	token := entry.tokens[0]
+	entry.tokens = entry.tokens[1:]
+	if len(entry.tokens) == 0 {
+		delete(c.tokens, key)
+	}
+
+	tokenCacheLookupsMetric.WithLabelValues("hit").Inc()
+	return token, true
+}
+
+// AddTokens caches access tokens for further connections, until the given expiry time.
+// The tokens are requested at the given cache generation, and replace any tokens cached for the same key.
+func (c *DecisionCache) AddTokens(key DecisionCacheKey, tokens []string, expiry time.Time, generation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation || len(tokens) == 0 || !time.Now().Before(expiry) {
+		return
+	}
+
+	c.tokens[key] = &tokenCacheEntry{tokens: tokens, expiry: expiry}
+}
+
+// Invalidate removes all cached entries.
+func (c *DecisionCache) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	clear(c.decisions)
+	clear(c.tokens)
+	clear(c.imports)
+}
+
+// InvalidateClients removes the cached decisions and access tokens of the clients with the given IP addresses.
+func (c *DecisionCache) InvalidateClients(ips ...string) {
+	if len(ips) == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	for key := range c.decisions {
+		if slices.Contains(ips, key.Client) {
+			delete(c.decisions, key)
+		}
+	}
+	for key := range c.tokens {
+		if slices.Contains(ips, key.Client) {
+			delete(c.tokens, key)
+		}
+	}
+}
+
+// Stats returns the number of cache hits and misses of egress decisions.
+func (c *DecisionCache) Stats() (hits, misses uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached egress decisions.
+func (c *DecisionCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.decisions)
+}
+
+// NewDecisionCache returns a new empty decision cache.
+func NewDecisionCache() *DecisionCache {
+	return &DecisionCache{
+		decisions: make(map[DecisionCacheKey]decisionCacheEntry),
+		tokens:    make(map[DecisionCacheKey]*tokenCacheEntry),
+		imports:   make(map[types.NamespacedName]importCacheEntry),
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+func TestDecisionCache(t *testing.T) {
+	cache := authz.NewDecisionCache()
+	importName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	key1 := authz.DecisionCacheKey{Client: "10.0.0.1", Import: importName, Port: "http", Peer: "peer1"}
+	key2 := authz.DecisionCacheKey{Client: "10.0.0.2", Import: importName, Port: "http", Peer: "peer1"}
+	decision := &authz.EgressDecision{
+		PeerReachable: true,
+		Decision:      &connectivitypdp.DestinationDecision{Decision: connectivitypdp.DecisionAllow},
+	}
+
+	// miss, followed by a hit once cached
+	_, ok := cache.Get(key1)
+	require.False(t, ok)
+	cache.Add(key1, decision, cache.Generation(), time.Now().Add(time.Hour))
+	cached, ok := cache.Get(key1)
+	require.True(t, ok)
+	require.Equal(t, decision, cached)
+	hits, misses := cache.Stats()
+	require.Equal(t, uint64(1), hits)
+	require.Equal(t, uint64(1), misses)
+
+	// imports are cached separately
+	imp := &v1alpha1.Import{ObjectMeta: metav1.ObjectMeta{Namespace: importName.Namespace, Name: importName.Name}}
+	cache.AddImport(imp, cache.Generation())
+	cachedImport, ok := cache.GetImport(importName)
+	require.True(t, ok)
+	require.Equal(t, imp, cachedImport)
+
+	// invalidating a client keeps the decisions of other clients and the imports
+	cache.Add(key2, decision, cache.Generation(), time.Now().Add(time.Hour))
+	require.Equal(t, 2, cache.Len())
+	cache.InvalidateClients("10.0.0.1")
+	_, ok = cache.Get(key1)
+	require.False(t, ok)
+	_, ok = cache.Get(key2)
+	require.True(t, ok)
+	_, ok = cache.GetImport(importName)
+	require.True(t, ok)
+
+	// decisions computed before an invalidation are not cached
+	generation := cache.Generation()
+	cache.Invalidate()
+	cache.Add(key1, decision, generation, time.Now().Add(time.Hour))
+	cache.AddImport(imp, generation)
+	require.Equal(t, 0, cache.Len())
+	_, ok = cache.GetImport(importName)
+	require.False(t, ok)
+
+	hits, misses = cache.Stats()
+	require.Equal(t, uint64(2), hits)
+	require.Equal(t, uint64(2), misses)
+}
+
+func TestDecisionCacheValidity(t *testing.T) {
+	cache := authz.NewDecisionCache()
+	key := authz.DecisionCacheKey{
+		Client: "10.0.0.1",
+		Import: types.NamespacedName{Namespace: "default", Name: "svc"},
+		Peer:   "peer1",
+	}
+	decision := &authz.EgressDecision{
+		PeerReachable: true,
+		Decision:      &connectivitypdp.DestinationDecision{Decision: connectivitypdp.DecisionAllow},
+	}
+
+	// decisions no longer valid (e.g. a time-restricted policy just changed its effect) are not cached
+	cache.Add(key, decision, cache.Generation(), time.Now())
+	require.Equal(t, 0, cache.Len())
+
+	// decisions are cached until they are no longer valid
+	cache.Add(key, decision, cache.Generation(), time.Now().Add(50*time.Millisecond))
+	_, ok := cache.Get(key)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		_, ok := cache.Get(key)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDecisionCacheSamePeerSources(t *testing.T) {
+	cache := authz.NewDecisionCache()
+	importName := types.NamespacedName{Namespace: "default", Name: "svc"}
+
+	// two sources of the same peer, with different decisions
+	key1 := authz.DecisionCacheKey{
+		Client: "10.0.0.1",
+		Import: importName,
+		Port:   "http",
+		Peer:   "peer1",
+		Export: types.NamespacedName{Namespace: "ns1", Name: "svc"},
+	}
+	key2 := key1
+	key2.Export = types.NamespacedName{Namespace: "ns2", Name: "svc"}
+
+	allow := &authz.EgressDecision{
+		PeerReachable: true,
+		Decision:      &connectivitypdp.DestinationDecision{Decision: connectivitypdp.DecisionAllow},
+	}
+	deny := &authz.EgressDecision{
+		PeerReachable: true,
+		Decision:      &connectivitypdp.DestinationDecision{Decision: connectivitypdp.DecisionDeny},
+	}
+
+	cache.Add(key1, allow, cache.Generation(), time.Now().Add(time.Hour))
+	_, ok := cache.Get(key2)
+	require.False(t, ok)
+
+	cache.Add(key2, deny, cache.Generation(), time.Now().Add(time.Hour))
+	require.Equal(t, 2, cache.Len())
+
+	cached, ok := cache.Get(key1)
+	require.True(t, ok)
+	require.Equal(t, allow, cached)
+	cached, ok = cache.Get(key2)
+	require.True(t, ok)
+	require.Equal(t, deny, cached)
+}
+
+func TestDecisionCacheTokens(t *testing.T) {
+	cache := authz.NewDecisionCache()
+	importName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	key1 := authz.DecisionCacheKey{Client: "10.0.0.1", Import: importName, Peer: "peer1"}
+	key2 := authz.DecisionCacheKey{Client: "10.0.0.2", Import: importName, Peer: "peer1"}
+	expiry := time.Now().Add(time.Minute)
+
+	// each cached token is returned once
+	_, ok := cache.GetToken(key1)
+	require.False(t, ok)
+	cache.AddTokens(key1, []string{"token1", "token2"}, expiry, cache.Generation())
+	for _, expected := range []string{"token1", "token2"} {
+		token, ok := cache.GetToken(key1)
+		require.True(t, ok)
+		require.Equal(t, expected, token)
+	}
+	_, ok = cache.GetToken(key1)
+	require.False(t, ok)
+
+	// expired, or requested before an invalidation tokens are not cached
+	cache.AddTokens(key1, []string{"token1"}, time.Now(), cache.Generation())
+	generation := cache.Generation()
+	cache.Invalidate()
+	cache.AddTokens(key1, []string{"token1"}, expiry, generation)
+	_, ok = cache.GetToken(key1)
+	require.False(t, ok)
+
+	// tokens are removed by invalidations
+	cache.AddTokens(key1, []string{"token1"}, expiry, cache.Generation())
+	cache.AddTokens(key2, []string{"token2", "token3"}, expiry, cache.Generation())
+	cache.InvalidateClients("10.0.0.1")
+	_, ok = cache.GetToken(key1)
+	require.False(t, ok)
+	_, ok = cache.GetToken(key2)
+	require.True(t, ok)
+
+	cache.Invalidate()
+	_, ok = cache.GetToken(key2)
+	require.False(t, ok)
+}
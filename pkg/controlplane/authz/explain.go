@@ -0,0 +1,143 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// ExplainEgress explains the access policy decisions on connections from a pod to each of the sources of an import.
+// Decisions are computed exactly as for egress authorization requests, without connecting to any remote peer.
+// The port name may be left empty for imports with a single port.
+func (m *Manager) ExplainEgress(
+	ctx context.Context, podName, importName types.NamespacedName, port string,
+) (*cpapi.PolicyExplanation, error) {
+	podInfo := m.getPodInfoByName(podName)
+	if podInfo == nil {
+		return nil, k8serrors.NewNotFound(v1.Resource("pods"), podName.String())
+	}
+
+	var imp v1alpha1.Import
+	if err := m.client.Get(ctx, importName, &imp); err != nil {
+		return nil, fmt.Errorf("cannot get import %v: %w", importName, err)
+	}
+
+	servicePorts := imp.Spec.ServicePorts()
+	if port == "" && len(servicePorts) == 1 {
+		port = servicePorts[0].Name
+	}
+	if _, ok := imp.Spec.ServicePort(port); !ok {
+		return nil, k8serrors.NewBadRequest(fmt.Sprintf("import %v has no port named '%s'", importName, port))
+	}
+
+	explanation := &cpapi.PolicyExplanation{
+		SrcAttributes: m.getPodAttributes(podInfo),
+		Sources:       make([]cpapi.SourcePolicyDecision, 0, len(imp.Spec.Sources)),
+	}
+
+	for _, importSource := range imp.Spec.Sources {
+		sourceDecision := cpapi.SourcePolicyDecision{
+			Peer:            importSource.Peer,
+			ExportName:      importSource.ExportName,
+			ExportNamespace: importSource.ExportNamespace,
+		}
+
+		var pr v1alpha1.Peer
+		peerName := types.NamespacedName{Name: importSource.Peer, Namespace: m.namespace}
+		if err := m.client.Get(ctx, peerName, &pr); err != nil {
+			sourceDecision.Error = fmt.Sprintf("cannot get peer '%s': %v", importSource.Peer, err)
+			explanation.Sources = append(explanation.Sources, sourceDecision)
+			continue
+		}
+
+		sourceDecision.DstAttributes = m.getDstAttributes(
+			importSource.ExportName, importSource.ExportNamespace, port, imp.Spec.Protocol,
+			importSource.Peer, imp.Labels, pr.Status.Labels,
+		)
+		decision, err := m.connectivityPDP.Decide(explanation.SrcAttributes, sourceDecision.DstAttributes, importName.Namespace)
+		if err != nil {
+			sourceDecision.Error = fmt.Sprintf("error deciding on an egress connection: %v", err)
+		} else {
+			sourceDecision.Decision = decision.Decision.String()
+			sourceDecision.MatchedBy = decision.MatchedBy
+			sourceDecision.Privileged = decision.PrivilegedMatch
+		}
+
+		explanation.Sources = append(explanation.Sources, sourceDecision)
+	}
+
+	return explanation, nil
+}
+
+// parseNamespacedName parses a "namespace/name" string.
+func parseNamespacedName(s string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(s, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("'%s' is not in the form of namespace/name", s)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// RegisterExplainHandler registers the access policy explain handler on an HTTP router.
+func RegisterExplainHandler(manager *Manager, router chi.Router) {
+	logger := logrus.WithField("component", "controlplane.authz.explain")
+
+	router.Get(cpapi.PolicyExplainPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		podName, err := parseNamespacedName(query.Get(cpapi.PolicyExplainFromPodParam))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid source pod: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		importName, err := parseNamespacedName(query.Get(cpapi.PolicyExplainToImportParam))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid import: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		port := query.Get(cpapi.PolicyExplainPortParam)
+		explanation, err := manager.ExplainEgress(r.Context(), podName, importName, port)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case k8serrors.IsNotFound(err):
+				status = http.StatusNotFound
+			case k8serrors.IsBadRequest(err):
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(explanation); err != nil {
+			logger.Errorf("Cannot encode policy explanation: %v", err)
+		}
+	})
+}
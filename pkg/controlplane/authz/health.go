@@ -0,0 +1,144 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+// connectionEstablished handles a dataplane report of an established egress connection.
+func (m *Manager) connectionEstablished(conn *connection, latency time.Duration) {
+	m.loadBalancer.ConnectionEstablished(conn.importName, conn.source(), latency)
+	egressConnectionsMetric.WithLabelValues(conn.importName.Namespace, conn.importName.Name, conn.peer).Inc()
+	m.updateSourcesHealth(conn.importName)
+}
+
+// connectionClosed handles a dataplane report of a closed egress connection.
+func (m *Manager) connectionClosed(conn *connection) {
+	m.loadBalancer.ConnectionClosed(conn.importName, conn.source())
+	egressConnectionsMetric.WithLabelValues(conn.importName.Namespace, conn.importName.Name, conn.peer).Dec()
+	m.updateSourcesHealth(conn.importName)
+}
+
+// connectionFailed handles a dataplane report of a failed egress connection attempt.
+func (m *Manager) connectionFailed(conn *connection) {
+	m.loadBalancer.ConnectionFailed(conn.importName, conn.source())
+	m.updateSourcesHealth(conn.importName)
+}
+
+// updateSourcesHealth updates the ImportSourcesHealthy condition of an import,
+// if it uses outlier detection or a circuit breaker.
+func (m *Manager) updateSourcesHealth(importName types.NamespacedName) {
+	ctx := context.Background()
+
+	var imp v1alpha1.Import
+	if err := m.client.Get(ctx, importName, &imp); err != nil {
+		m.logger.Debugf("Cannot get import %v: %v.", importName, err)
+		return
+	}
+
+	if imp.Spec.OutlierDetection == nil && imp.Spec.CircuitBreaker == nil {
+		return
+	}
+
+	ejected, overloaded := m.loadBalancer.sourcesHealth(&imp)
+
+	// re-check once the earliest ejection ends
+	var nextCheck time.Time
+	for _, end := range ejected {
+		if nextCheck.IsZero() || end.Before(nextCheck) {
+			nextCheck = end
+		}
+	}
+	m.scheduleSourcesHealthUpdate(importName, nextCheck)
+
+	cond := metav1.Condition{
+		Type:   v1alpha1.ImportSourcesHealthy,
+		Status: metav1.ConditionTrue,
+		Reason: "Healthy",
+	}
+
+	var problems []string
+	for _, source := range sortedKeys(ejected) {
+		problems = append(problems, fmt.Sprintf("source %s ejected by outlier detection", source))
+	}
+	for _, source := range sortedKeys(overloaded) {
+		problems = append(problems, fmt.Sprintf("source %s reached the circuit breaker connections limit", source))
+	}
+
+	if len(problems) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "Unhealthy"
+		cond.Message = strings.Join(problems, "; ")
+	}
+
+	if !conditionChanged(imp.Status.Conditions, &cond) {
+		return
+	}
+
+	meta.SetStatusCondition(&imp.Status.Conditions, cond)
+
+	m.logger.Infof("Updating import '%s' sources health: %s.", importName, cond.Message)
+	if err := m.client.Status().Update(ctx, &imp); err != nil {
+		m.logger.Warnf("Cannot update import '%s' status: %v.", importName, err)
+	}
+}
+
+// scheduleSourcesHealthUpdate schedules an update of the sources health of an import at a given time,
+// replacing any previously scheduled update. A zero time cancels the scheduled update.
+func (m *Manager) scheduleSourcesHealthUpdate(importName types.NamespacedName, at time.Time) {
+	m.healthTimersLock.Lock()
+	defer m.healthTimersLock.Unlock()
+
+	if timer, ok := m.healthTimers[importName]; ok {
+		timer.Stop()
+		delete(m.healthTimers, importName)
+	}
+
+	if at.IsZero() {
+		return
+	}
+
+	m.healthTimers[importName] = time.AfterFunc(time.Until(at), func() {
+		m.updateSourcesHealth(importName)
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// conditionChanged returns true if the given condition differs from the matching existing condition.
+func conditionChanged(conditions []metav1.Condition, cond *metav1.Condition) bool {
+	current := meta.FindStatusCondition(conditions, cond.Type)
+	return current == nil ||
+		current.Status != cond.Status ||
+		current.Reason != cond.Reason ||
+		current.Message != cond.Message
+}
@@ -18,26 +18,184 @@ import (
 	"math/rand"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	crds "github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
 )
 
+const (
+	// latencyAverageWeight is the weight of a new sample in the moving average of connection setup latencies.
+	latencyAverageWeight = 0.2
+	// affinityPurgeInterval is the minimal interval between purges of expired session affinities.
+	affinityPurgeInterval = time.Minute
+	// maxEjectionBackoffExponent bounds the doubling of ejection times.
+	maxEjectionBackoffExponent = 16
+)
+
+// sessionAffinity binds a client to an import source.
+type sessionAffinity struct {
+	peer            string
+	exportName      string
+	exportNamespace string
+	expiry          time.Time
+}
+
+// sourceID identifies an import source: an exported service of a remote peer.
+type sourceID struct {
+	peer   string
+	export types.NamespacedName
+}
+
+// String returns a description of the source, for status messages.
+func (id sourceID) String() string {
+	return fmt.Sprintf("'%s' of peer '%s'", id.export, id.peer)
+}
+
+// newSourceID returns the ID of a source of the given import.
+// A source with no export name or namespace refers to the import name or namespace.
+func newSourceID(importName types.NamespacedName, source *crds.ImportSource) sourceID {
+	id := sourceID{
+		peer:   source.Peer,
+		export: types.NamespacedName{Namespace: source.ExportNamespace, Name: source.ExportName},
+	}
+	if id.export.Name == "" {
+		id.export.Name = importName.Name
+	}
+	if id.export.Namespace == "" {
+		id.export.Namespace = importName.Namespace
+	}
+	return id
+}
+
+// sourceStats holds the dataplane feedback on connections to an import source.
+type sourceStats struct {
+	activeConnections int64
+	// latency is a moving average of connection setup latencies. Zero if unknown.
+	latency time.Duration
+	// consecutiveFailures is the number of connection failures since the last successful connection.
+	consecutiveFailures uint32
+	lastFailure         time.Time
+}
+
+// ejectionEnd returns the time until which the source is ejected by outlier detection.
+// Returns the zero time if the source was not ejected.
+func (s *sourceStats) ejectionEnd(outlierDetection *crds.OutlierDetection) time.Time {
+	threshold := outlierDetection.FailureThreshold()
+	if s.consecutiveFailures < threshold {
+		return time.Time{}
+	}
+
+	ejection := outlierDetection.MaxEjectionTime()
+	if exponent := s.consecutiveFailures - threshold; exponent < maxEjectionBackoffExponent {
+		ejection = min(outlierDetection.BaseEjectionTime()<<exponent, ejection)
+	}
+
+	return s.lastFailure.Add(ejection)
+}
+
 type importState struct {
 	roundRobinCounter atomic.Uint32
+
+	sourceStatsLock sync.RWMutex
+	sourceStats     map[sourceID]*sourceStats
+
+	affinityLock      sync.Mutex
+	affinities        map[string]*sessionAffinity
+	lastAffinityPurge time.Time
+}
+
+// getAffinity returns the index of the source a client is bound to, or -1 if there is none.
+func (s *importState) getAffinity(client string, sources []crds.ImportSource) int {
+	s.affinityLock.Lock()
+	defer s.affinityLock.Unlock()
+
+	affinity, ok := s.affinities[client]
+	if !ok {
+		return -1
+	}
+
+	if time.Now().After(affinity.expiry) {
+		delete(s.affinities, client)
+		return -1
+	}
+
+	for i := range sources {
+		if sources[i].Peer == affinity.peer &&
+			sources[i].ExportName == affinity.exportName &&
+			sources[i].ExportNamespace == affinity.exportNamespace {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// setAffinity binds a client to an import source, purging expired bindings of other clients.
+func (s *importState) setAffinity(client string, source *crds.ImportSource, timeout time.Duration) {
+	s.affinityLock.Lock()
+	defer s.affinityLock.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastAffinityPurge) > affinityPurgeInterval {
+		for key, affinity := range s.affinities {
+			if now.After(affinity.expiry) {
+				delete(s.affinities, key)
+			}
+		}
+		s.lastAffinityPurge = now
+	}
+
+	s.affinities[client] = &sessionAffinity{
+		peer:            source.Peer,
+		exportName:      source.ExportName,
+		exportNamespace: source.ExportNamespace,
+		expiry:          now.Add(timeout),
+	}
+}
+
+// getSourceStats returns a copy of the connection stats of a source.
+func (s *importState) getSourceStats(id sourceID) sourceStats {
+	s.sourceStatsLock.RLock()
+	defer s.sourceStatsLock.RUnlock()
+
+	if stats, ok := s.sourceStats[id]; ok {
+		return *stats
+	}
+	return sourceStats{}
+}
+
+// updateSourceStats updates the connection stats of a source.
+func (s *importState) updateSourceStats(id sourceID, update func(stats *sourceStats)) {
+	s.sourceStatsLock.Lock()
+	defer s.sourceStatsLock.Unlock()
+
+	stats, ok := s.sourceStats[id]
+	if !ok {
+		stats = &sourceStats{}
+		s.sourceStats[id] = stats
+	}
+	update(stats)
 }
 
 type LoadBalancer struct {
 	lock   sync.RWMutex
 	states map[types.NamespacedName]*importState
 
+	// localPeerLabels are the labels of the local peer, used by the locality scheme.
+	localPeerLabels map[string]string
+	peerLabelsLock  sync.RWMutex
+	peerLabels      map[string]map[string]string
+
 	logger *logrus.Entry
 }
 
 type LoadBalancingResult struct {
 	imp          *crds.Import
+	client       string
 	currentIndex int
 	failed       map[int]interface{}
 	delayed      []int
@@ -58,6 +216,22 @@ func (r *LoadBalancingResult) Delay() {
 	r.delayed = append(r.delayed, r.currentIndex)
 }
 
+// SetClient sets the identity of the client, used for session affinity.
+func (r *LoadBalancingResult) SetClient(client string) {
+	r.client = client
+}
+
+// candidates returns the indices of all sources which were not tried yet.
+func (r *LoadBalancingResult) candidates() []int {
+	var candidates []int
+	for i := range r.imp.Spec.Sources {
+		if _, ok := r.failed[i]; !ok {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
 func NewLoadBalancingResult(imp *crds.Import) *LoadBalancingResult {
 	return &LoadBalancingResult{
 		imp:          imp,
@@ -67,15 +241,31 @@ func NewLoadBalancingResult(imp *crds.Import) *LoadBalancingResult {
 }
 
 // NewLoadBalancer returns a new instance of a LoadBalancer object.
-func NewLoadBalancer() *LoadBalancer {
+func NewLoadBalancer(localPeerLabels map[string]string) *LoadBalancer {
 	logger := logrus.WithField("component", "controlplane.authz.loadbalancer")
 
 	return &LoadBalancer{
-		states: make(map[types.NamespacedName]*importState),
-		logger: logger,
+		states:          make(map[types.NamespacedName]*importState),
+		localPeerLabels: localPeerLabels,
+		peerLabels:      make(map[string]map[string]string),
+		logger:          logger,
 	}
 }
 
+// AddPeer sets the labels of a remote peer, used by the locality scheme.
+func (lb *LoadBalancer) AddPeer(pr *crds.Peer) {
+	lb.peerLabelsLock.Lock()
+	defer lb.peerLabelsLock.Unlock()
+	lb.peerLabels[pr.Name] = pr.Status.Labels
+}
+
+// DeletePeer removes the labels of a remote peer.
+func (lb *LoadBalancer) DeletePeer(name string) {
+	lb.peerLabelsLock.Lock()
+	defer lb.peerLabelsLock.Unlock()
+	delete(lb.peerLabels, name)
+}
+
 func (lb *LoadBalancer) selectRandom(result *LoadBalancingResult) {
 	sources := &result.imp.Spec.Sources
 	candidateCount := len(*sources)
@@ -93,15 +283,8 @@ func (lb *LoadBalancer) selectRandom(result *LoadBalancingResult) {
 	}
 }
 
-func (lb *LoadBalancer) selectRoundRobin(result *LoadBalancingResult) {
-	imp := result.imp
-	sourceCount := len(imp.Spec.Sources)
-
-	name := types.NamespacedName{
-		Namespace: imp.Namespace,
-		Name:      imp.Name,
-	}
-
+// getState returns the load-balancing state of an import, creating it if it does not exist.
+func (lb *LoadBalancer) getState(name types.NamespacedName) *importState {
 	lb.lock.RLock()
 	state := lb.states[name]
 	lb.lock.RUnlock()
@@ -110,12 +293,129 @@ func (lb *LoadBalancer) selectRoundRobin(result *LoadBalancingResult) {
 		lb.lock.Lock()
 		state = lb.states[name]
 		if state == nil {
-			state = &importState{}
+			state = &importState{
+				sourceStats: make(map[sourceID]*sourceStats),
+				affinities:  make(map[string]*sessionAffinity),
+			}
 			lb.states[name] = state
 		}
 		lb.lock.Unlock()
 	}
 
+	return state
+}
+
+// DeleteImport removes the load-balancing state of a deleted import.
+func (lb *LoadBalancer) DeleteImport(name types.NamespacedName) {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	delete(lb.states, name)
+}
+
+// ConnectionEstablished records a new active connection to a source of an import,
+// together with its connection setup latency, as reported by a dataplane.
+func (lb *LoadBalancer) ConnectionEstablished(
+	importName types.NamespacedName, source *crds.ImportSource, latency time.Duration,
+) {
+	lb.getState(importName).updateSourceStats(newSourceID(importName, source), func(stats *sourceStats) {
+		stats.activeConnections++
+		stats.consecutiveFailures = 0
+		if stats.latency == 0 {
+			stats.latency = latency
+			return
+		}
+		stats.latency += time.Duration(latencyAverageWeight * float64(latency-stats.latency))
+	})
+}
+
+// ConnectionClosed records the end of an active connection to a source of an import.
+// Connections of deleted imports are ignored.
+func (lb *LoadBalancer) ConnectionClosed(importName types.NamespacedName, source *crds.ImportSource) {
+	lb.lock.RLock()
+	state := lb.states[importName]
+	lb.lock.RUnlock()
+	if state == nil {
+		return
+	}
+
+	state.updateSourceStats(newSourceID(importName, source), func(stats *sourceStats) {
+		if stats.activeConnections > 0 {
+			stats.activeConnections--
+		}
+	})
+}
+
+// ConnectionFailed records a failed connection attempt to a source of an import.
+func (lb *LoadBalancer) ConnectionFailed(importName types.NamespacedName, source *crds.ImportSource) {
+	lb.getState(importName).updateSourceStats(newSourceID(importName, source), func(stats *sourceStats) {
+		stats.consecutiveFailures++
+		stats.lastFailure = time.Now()
+	})
+}
+
+// IsEjected returns true if the selected source is currently ejected by outlier detection.
+func (lb *LoadBalancer) IsEjected(result *LoadBalancingResult) bool {
+	imp := result.imp
+	source := result.Get()
+	if imp.Spec.OutlierDetection == nil || source == nil {
+		return false
+	}
+
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+	stats := lb.getState(importName).getSourceStats(newSourceID(importName, source))
+	return time.Now().Before(stats.ejectionEnd(imp.Spec.OutlierDetection))
+}
+
+// IsOverloaded returns true if the selected source reached the circuit breaker connections limit.
+func (lb *LoadBalancer) IsOverloaded(result *LoadBalancingResult) bool {
+	imp := result.imp
+	source := result.Get()
+	if imp.Spec.CircuitBreaker == nil || source == nil {
+		return false
+	}
+
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+	stats := lb.getState(importName).getSourceStats(newSourceID(importName, source))
+	return stats.activeConnections >= int64(imp.Spec.CircuitBreaker.MaxConnections)
+}
+
+// sourcesHealth returns the sources of an import which are currently ejected (with their ejection end),
+// and the sources which reached the circuit breaker connections limit, keyed by their description.
+func (lb *LoadBalancer) sourcesHealth(imp *crds.Import) (ejected map[string]time.Time, overloaded map[string]bool) {
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+	state := lb.getState(importName)
+
+	ejected = make(map[string]time.Time)
+	overloaded = make(map[string]bool)
+	now := time.Now()
+	for i := range imp.Spec.Sources {
+		id := newSourceID(importName, &imp.Spec.Sources[i])
+		stats := state.getSourceStats(id)
+
+		if imp.Spec.OutlierDetection != nil {
+			if end := stats.ejectionEnd(imp.Spec.OutlierDetection); now.Before(end) {
+				ejected[id.String()] = end
+			}
+		}
+
+		if imp.Spec.CircuitBreaker != nil &&
+			stats.activeConnections >= int64(imp.Spec.CircuitBreaker.MaxConnections) {
+			overloaded[id.String()] = true
+		}
+	}
+
+	return ejected, overloaded
+}
+
+func (lb *LoadBalancer) selectRoundRobin(result *LoadBalancingResult) {
+	imp := result.imp
+	sourceCount := len(imp.Spec.Sources)
+
+	state := lb.getState(types.NamespacedName{
+		Namespace: imp.Namespace,
+		Name:      imp.Name,
+	})
+
 	counter := state.roundRobinCounter.Add(1)
 
 	if result.currentIndex != -1 {
@@ -133,6 +433,116 @@ func (lb *LoadBalancer) selectStatic(result *LoadBalancingResult) {
 	result.currentIndex++
 }
 
+// selectWeighted randomly selects a source which was not tried yet, with a probability proportional to its weight.
+func (lb *LoadBalancer) selectWeighted(result *LoadBalancingResult, candidates []int) {
+	totalWeight := uint64(0)
+	for _, index := range candidates {
+		totalWeight += uint64(sourceWeight(&result.imp.Spec.Sources[index]))
+	}
+
+	//nolint:gosec // G404: use of weak random is fine for load balancing
+	target := rand.Int63n(int64(totalWeight))
+	for _, index := range candidates {
+		target -= int64(sourceWeight(&result.imp.Spec.Sources[index]))
+		if target < 0 {
+			result.currentIndex = index
+			return
+		}
+	}
+}
+
+// selectLeastConnections selects a source which was not tried yet, which has the least active connections.
+func (lb *LoadBalancer) selectLeastConnections(result *LoadBalancingResult) {
+	lb.selectMinimal(result, func(stats *sourceStats) int64 {
+		return stats.activeConnections
+	})
+}
+
+// selectLeastLatency selects a source which was not tried yet, which has the lowest connection setup latency.
+// Sources with unknown latency are preferred, in order to measure their latency.
+func (lb *LoadBalancer) selectLeastLatency(result *LoadBalancingResult) {
+	lb.selectMinimal(result, func(stats *sourceStats) int64 {
+		return int64(stats.latency)
+	})
+}
+
+// selectMinimal randomly selects one of the sources which were not tried yet,
+// whose stats has the minimal value.
+func (lb *LoadBalancer) selectMinimal(result *LoadBalancingResult, value func(stats *sourceStats) int64) {
+	imp := result.imp
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+	state := lb.getState(importName)
+
+	var candidates []int
+	var minValue int64
+	for _, index := range result.candidates() {
+		stats := state.getSourceStats(newSourceID(importName, &imp.Spec.Sources[index]))
+		v := value(&stats)
+		switch {
+		case len(candidates) == 0 || v < minValue:
+			minValue = v
+			candidates = []int{index}
+		case v == minValue:
+			candidates = append(candidates, index)
+		}
+	}
+
+	//nolint:gosec // G404: use of weak random is fine for load balancing
+	result.currentIndex = candidates[rand.Intn(len(candidates))]
+}
+
+// selectLocality selects a source which was not tried yet, preferring sources
+// whose peer shares the local peer zone, and then the local peer region.
+// Sources sharing the same locality are selected according to their weights.
+func (lb *LoadBalancer) selectLocality(result *LoadBalancingResult) {
+	lb.peerLabelsLock.RLock()
+	bestScore := -1
+	var candidates []int
+	for i := range result.imp.Spec.Sources {
+		if _, ok := result.failed[i]; ok {
+			continue
+		}
+
+		score := lb.localityScore(lb.peerLabels[result.imp.Spec.Sources[i].Peer])
+		switch {
+		case score > bestScore:
+			bestScore = score
+			candidates = []int{i}
+		case score == bestScore:
+			candidates = append(candidates, i)
+		}
+	}
+	lb.peerLabelsLock.RUnlock()
+
+	lb.selectWeighted(result, candidates)
+}
+
+// localityScore returns how close a peer is to the local peer, based on the peer labels:
+// 2 for the same zone, 1 for the same region, and 0 otherwise.
+func (lb *LoadBalancer) localityScore(peerLabels map[string]string) int {
+	sameLabel := func(key string) bool {
+		value, ok := lb.localPeerLabels[key]
+		return ok && value != "" && peerLabels[key] == value
+	}
+
+	switch {
+	case sameLabel(v1.LabelTopologyZone):
+		return 2
+	case sameLabel(v1.LabelTopologyRegion):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sourceWeight(source *crds.ImportSource) uint32 {
+	if source.Weight == 0 {
+		return 1
+	}
+
+	return source.Weight
+}
+
 // Select one of the import sources, based on the set load balancing scheme.
 func (lb *LoadBalancer) Select(result *LoadBalancingResult) error {
 	if result.currentIndex != -1 {
@@ -156,6 +566,10 @@ func (lb *LoadBalancer) Select(result *LoadBalancingResult) error {
 		return fmt.Errorf("tried out all %d sources", len(imp.Spec.Sources))
 	}
 
+	if lb.selectAffinity(result) {
+		return nil
+	}
+
 	scheme := getScheme(imp)
 	switch scheme {
 	case crds.LBSchemeRandom:
@@ -164,6 +578,14 @@ func (lb *LoadBalancer) Select(result *LoadBalancingResult) error {
 		lb.selectRoundRobin(result)
 	case crds.LBSchemeStatic:
 		lb.selectStatic(result)
+	case crds.LBSchemeWeighted:
+		lb.selectWeighted(result, result.candidates())
+	case crds.LBSchemeLocality:
+		lb.selectLocality(result)
+	case crds.LBSchemeLeastConnections:
+		lb.selectLeastConnections(result)
+	case crds.LBSchemeLeastLatency:
+		lb.selectLeastLatency(result)
 	}
 
 	lb.logger.WithFields(logrus.Fields{
@@ -177,6 +599,50 @@ func (lb *LoadBalancer) Select(result *LoadBalancingResult) error {
 	return nil
 }
 
+// selectAffinity selects the source the client is bound to, if this is the first selection for the client.
+// Returns true if a source was selected.
+func (lb *LoadBalancer) selectAffinity(result *LoadBalancingResult) bool {
+	imp := result.imp
+	if imp.Spec.SessionAffinity == nil || result.client == "" || len(result.failed) > 0 {
+		return false
+	}
+
+	state := lb.getState(types.NamespacedName{
+		Namespace: imp.Namespace,
+		Name:      imp.Name,
+	})
+
+	index := state.getAffinity(result.client, imp.Spec.Sources)
+	if index == -1 {
+		return false
+	}
+
+	result.currentIndex = index
+
+	lb.logger.WithFields(logrus.Fields{
+		"import-name":      imp.Name,
+		"import-namespace": imp.Namespace,
+		"result-index":     result.currentIndex,
+	}).Info("Select by session affinity")
+
+	return true
+}
+
+// Commit binds the client of a load-balancing result to its selected source, if session affinity is enabled.
+func (lb *LoadBalancer) Commit(result *LoadBalancingResult) {
+	imp := result.imp
+	source := result.Get()
+	if imp.Spec.SessionAffinity == nil || result.client == "" || source == nil {
+		return
+	}
+
+	state := lb.getState(types.NamespacedName{
+		Namespace: imp.Namespace,
+		Name:      imp.Name,
+	})
+	state.setAffinity(result.client, source, imp.Spec.SessionAffinity.Timeout())
+}
+
 func getScheme(imp *crds.Import) crds.LBScheme {
 	if imp.Spec.LBScheme == "" {
 		return crds.LBSchemeDefault
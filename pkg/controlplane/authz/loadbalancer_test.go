@@ -0,0 +1,349 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz"
+)
+
+func newImport(scheme v1alpha1.LBScheme, sources ...v1alpha1.ImportSource) *v1alpha1.Import {
+	return &v1alpha1.Import{
+		ObjectMeta: metav1.ObjectMeta{Name: "imp", Namespace: "default"},
+		Spec: v1alpha1.ImportSpec{
+			Port:     80,
+			Sources:  sources,
+			LBScheme: scheme,
+		},
+	}
+}
+
+// sourceOf returns the (first) source of an import on the given peer.
+func sourceOf(imp *v1alpha1.Import, peer string) *v1alpha1.ImportSource {
+	for i := range imp.Spec.Sources {
+		if imp.Spec.Sources[i].Peer == peer {
+			return &imp.Spec.Sources[i]
+		}
+	}
+	return nil
+}
+
+func newPeer(name string, labels map[string]string) *v1alpha1.Peer {
+	return &v1alpha1.Peer{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1alpha1.PeerStatus{Labels: labels},
+	}
+}
+
+func TestWeightedLoadBalancing(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeWeighted,
+		v1alpha1.ImportSource{Peer: "near", ExportName: "svc", ExportNamespace: "default", Weight: 4},
+		v1alpha1.ImportSource{Peer: "far", ExportName: "svc", ExportNamespace: "default", Weight: 1},
+	)
+
+	const iterations = 10000
+	counts := make(map[string]int)
+	for i := 0; i < iterations; i++ {
+		result := authz.NewLoadBalancingResult(imp)
+		require.Nil(t, lb.Select(result))
+		counts[result.Get().Peer]++
+	}
+
+	// expect ~80% of connections routed to the near peer
+	require.InDelta(t, 0.8, float64(counts["near"])/iterations, 0.03)
+
+	// all sources are tried before failing
+	result := authz.NewLoadBalancingResult(imp)
+	tried := make(map[string]bool)
+	for i := 0; i < len(imp.Spec.Sources); i++ {
+		require.Nil(t, lb.Select(result))
+		tried[result.Get().Peer] = true
+	}
+	require.Len(t, tried, 2)
+	require.NotNil(t, lb.Select(result))
+}
+
+func TestLocalityLoadBalancing(t *testing.T) {
+	lb := authz.NewLoadBalancer(map[string]string{
+		v1.LabelTopologyRegion: "us-east",
+		v1.LabelTopologyZone:   "us-east-1a",
+	})
+	lb.AddPeer(newPeer("other-region", map[string]string{
+		v1.LabelTopologyRegion: "eu-west",
+		v1.LabelTopologyZone:   "eu-west-1a",
+	}))
+	lb.AddPeer(newPeer("same-region", map[string]string{
+		v1.LabelTopologyRegion: "us-east",
+		v1.LabelTopologyZone:   "us-east-1b",
+	}))
+	lb.AddPeer(newPeer("same-zone", map[string]string{
+		v1.LabelTopologyRegion: "us-east",
+		v1.LabelTopologyZone:   "us-east-1a",
+	}))
+
+	imp := newImport(
+		v1alpha1.LBSchemeLocality,
+		v1alpha1.ImportSource{Peer: "other-region", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "unlabeled", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "same-region", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "same-zone", ExportName: "svc", ExportNamespace: "default"},
+	)
+
+	for i := 0; i < 100; i++ {
+		result := authz.NewLoadBalancingResult(imp)
+		require.Nil(t, lb.Select(result))
+		require.Equal(t, "same-zone", result.Get().Peer)
+
+		// fall back to the same region, and then to all other sources
+		require.Nil(t, lb.Select(result))
+		require.Equal(t, "same-region", result.Get().Peer)
+
+		remaining := make(map[string]bool)
+		require.Nil(t, lb.Select(result))
+		remaining[result.Get().Peer] = true
+		require.Nil(t, lb.Select(result))
+		remaining[result.Get().Peer] = true
+		require.Equal(t, map[string]bool{"other-region": true, "unlabeled": true}, remaining)
+
+		require.NotNil(t, lb.Select(result))
+	}
+
+	// without any preferred sources, fall back to all sources
+	lb.DeletePeer("same-zone")
+	lb.DeletePeer("same-region")
+	result := authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.NotNil(t, result.Get())
+}
+
+func TestLeastConnectionsLoadBalancing(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeLeastConnections,
+		v1alpha1.ImportSource{Peer: "busy", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "idle", ExportName: "svc", ExportNamespace: "default"},
+	)
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+
+	lb.ConnectionEstablished(importName, sourceOf(imp, "busy"), time.Millisecond)
+	lb.ConnectionEstablished(importName, sourceOf(imp, "busy"), time.Millisecond)
+	lb.ConnectionEstablished(importName, sourceOf(imp, "idle"), time.Millisecond)
+
+	result := authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "idle", result.Get().Peer)
+
+	// fall back to the busier source
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "busy", result.Get().Peer)
+	require.NotNil(t, lb.Select(result))
+
+	lb.ConnectionClosed(importName, sourceOf(imp, "busy"))
+	lb.ConnectionClosed(importName, sourceOf(imp, "busy"))
+	lb.ConnectionEstablished(importName, sourceOf(imp, "idle"), time.Millisecond)
+
+	result = authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "busy", result.Get().Peer)
+}
+
+func TestLeastLatencyLoadBalancing(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeLeastLatency,
+		v1alpha1.ImportSource{Peer: "far", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "near", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "new", ExportName: "svc", ExportNamespace: "default"},
+	)
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+
+	lb.ConnectionEstablished(importName, sourceOf(imp, "far"), 100*time.Millisecond)
+	lb.ConnectionEstablished(importName, sourceOf(imp, "near"), 10*time.Millisecond)
+
+	// sources with unknown latency are tried first
+	result := authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "new", result.Get().Peer)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "near", result.Get().Peer)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "far", result.Get().Peer)
+	require.NotNil(t, lb.Select(result))
+
+	lb.ConnectionEstablished(importName, sourceOf(imp, "new"), 50*time.Millisecond)
+
+	// latency is averaged over connections
+	for i := 0; i < 20; i++ {
+		lb.ConnectionEstablished(importName, sourceOf(imp, "near"), 200*time.Millisecond)
+	}
+
+	result = authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "new", result.Get().Peer)
+}
+
+func TestSessionAffinity(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeRoundRobin,
+		v1alpha1.ImportSource{Peer: "peer1", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "peer2", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "peer3", ExportName: "svc", ExportNamespace: "default"},
+	)
+	imp.Spec.SessionAffinity = &v1alpha1.SessionAffinity{Type: v1alpha1.SessionAffinityClientIP}
+
+	connect := func(client string) string {
+		result := authz.NewLoadBalancingResult(imp)
+		result.SetClient(client)
+		require.Nil(t, lb.Select(result))
+		lb.Commit(result)
+		return result.Get().Peer
+	}
+
+	// consecutive connections of a client are routed to the same source
+	peer := connect("10.0.0.1")
+	for i := 0; i < 10; i++ {
+		require.Equal(t, peer, connect("10.0.0.1"))
+	}
+
+	// other clients are still balanced
+	peers := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		peers[connect("10.0.1."+strconv.Itoa(i))] = true
+	}
+	require.Len(t, peers, 3)
+
+	// fall back to other sources if the bound source fails, and re-bind the client
+	result := authz.NewLoadBalancingResult(imp)
+	result.SetClient("10.0.0.1")
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, peer, result.Get().Peer)
+	require.Nil(t, lb.Select(result))
+	newPeer := result.Get().Peer
+	require.NotEqual(t, peer, newPeer)
+	lb.Commit(result)
+	require.Equal(t, newPeer, connect("10.0.0.1"))
+
+	// clients without identity are balanced
+	peers = make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		peers[connect("")] = true
+	}
+	require.Len(t, peers, 3)
+}
+
+func TestOutlierDetection(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeStatic,
+		v1alpha1.ImportSource{Peer: "failing", ExportName: "svc", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "healthy", ExportName: "svc", ExportNamespace: "default"},
+	)
+	imp.Spec.OutlierDetection = &v1alpha1.OutlierDetection{ConsecutiveFailures: 3}
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+
+	isEjected := func() bool {
+		result := authz.NewLoadBalancingResult(imp)
+		require.Nil(t, lb.Select(result))
+		require.Equal(t, "failing", result.Get().Peer)
+		return lb.IsEjected(result)
+	}
+
+	lb.ConnectionFailed(importName, sourceOf(imp, "failing"))
+	lb.ConnectionFailed(importName, sourceOf(imp, "failing"))
+	require.False(t, isEjected())
+
+	// a successful connection resets the consecutive failures
+	lb.ConnectionEstablished(importName, sourceOf(imp, "failing"), time.Millisecond)
+	lb.ConnectionFailed(importName, sourceOf(imp, "failing"))
+	lb.ConnectionFailed(importName, sourceOf(imp, "failing"))
+	require.False(t, isEjected())
+
+	lb.ConnectionFailed(importName, sourceOf(imp, "failing"))
+	require.True(t, isEjected())
+
+	// sources are not ejected without outlier detection
+	imp.Spec.OutlierDetection = nil
+	require.False(t, isEjected())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeStatic,
+		v1alpha1.ImportSource{Peer: "peer1", ExportName: "svc", ExportNamespace: "default"},
+	)
+	imp.Spec.CircuitBreaker = &v1alpha1.CircuitBreaker{MaxConnections: 2}
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+
+	isOverloaded := func() bool {
+		result := authz.NewLoadBalancingResult(imp)
+		require.Nil(t, lb.Select(result))
+		return lb.IsOverloaded(result)
+	}
+
+	lb.ConnectionEstablished(importName, sourceOf(imp, "peer1"), time.Millisecond)
+	require.False(t, isOverloaded())
+	lb.ConnectionEstablished(importName, sourceOf(imp, "peer1"), time.Millisecond)
+	require.True(t, isOverloaded())
+	lb.ConnectionClosed(importName, sourceOf(imp, "peer1"))
+	require.False(t, isOverloaded())
+}
+
+func TestSourceStats(t *testing.T) {
+	lb := authz.NewLoadBalancer(nil)
+	imp := newImport(
+		v1alpha1.LBSchemeStatic,
+		v1alpha1.ImportSource{Peer: "peer1", ExportName: "failing", ExportNamespace: "default"},
+		v1alpha1.ImportSource{Peer: "peer1", ExportName: "healthy", ExportNamespace: "default"},
+	)
+	imp.Spec.OutlierDetection = &v1alpha1.OutlierDetection{ConsecutiveFailures: 1}
+	imp.Spec.CircuitBreaker = &v1alpha1.CircuitBreaker{MaxConnections: 1}
+	importName := types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}
+
+	// sources of the same peer are tracked separately
+	lb.ConnectionFailed(importName, &imp.Spec.Sources[0])
+	lb.ConnectionEstablished(importName, &imp.Spec.Sources[0], time.Millisecond)
+	lb.ConnectionFailed(importName, &imp.Spec.Sources[0])
+
+	result := authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "failing", result.Get().ExportName)
+	require.True(t, lb.IsEjected(result))
+	require.True(t, lb.IsOverloaded(result))
+
+	require.Nil(t, lb.Select(result))
+	require.Equal(t, "healthy", result.Get().ExportName)
+	require.False(t, lb.IsEjected(result))
+	require.False(t, lb.IsOverloaded(result))
+
+	// the state of a deleted import is removed, ignoring connections closed later
+	lb.DeleteImport(importName)
+	lb.ConnectionClosed(importName, &imp.Spec.Sources[0])
+	result = authz.NewLoadBalancingResult(imp)
+	require.Nil(t, lb.Select(result))
+	require.False(t, lb.IsEjected(result))
+	require.False(t, lb.IsOverloaded(result))
+}
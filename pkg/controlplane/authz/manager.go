@@ -15,13 +15,18 @@ package authz
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/lestrrat-go/jwx/jwt"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -30,15 +35,25 @@ import (
 
 	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
 	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/audit"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/control"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/peer"
 	"github.com/clusterlink-net/clusterlink/pkg/util/tls"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 const (
 	// the number of seconds a JWT access token is valid before it expires.
 	jwtExpirySeconds = 5
+	// accessTokenBatchSize is the number of single-use access tokens requested at once from a remote peer,
+	// for repeated connections of a client. The spare tokens are cached for the further connections.
+	accessTokenBatchSize = 8
+	// maxAccessTokenBatchSize is the maximal number of access tokens issued at once to a remote peer.
+	maxAccessTokenBatchSize = 16
+	// accessTokenReuseMargin is the time before its expiry a cached access token is no longer used,
+	// leaving time for the connection using it to be established.
+	accessTokenReuseMargin = time.Second
 
 	ClientNamespaceLabel  = "client.clusterlink.net/namespace"
 	ClientSALabel         = "client.clusterlink.net/service-account"
@@ -46,6 +61,8 @@ const (
 	ServiceNameLabel      = "export.clusterlink.net/name"
 	ServiceNamespaceLabel = "export.clusterlink.net/namespace"
 	ServiceLabelsPrefix   = "export.clusterlink.net/labels."
+	ServicePortLabel      = connectivitypdp.ServicePortAttr
+	ServiceProtocolLabel  = connectivitypdp.ServiceProtocolAttr
 	PeerNameLabel         = "peer.clusterlink.net/name"
 	PeerLabelsPrefix      = "peer.clusterlink.net/labels."
 )
@@ -55,8 +72,13 @@ const (
 type egressAuthorizationRequest struct {
 	// ImportName is the name of the requested imported service.
 	ImportName types.NamespacedName
+	// ImportPort is the port name of the requested imported service.
+	ImportPort string
 	// IP address of the client connecting to the service.
 	IP string
+	// Headers of the HTTP request, for imported services with appProtocol http.
+	// Header names are lowercase.
+	Headers map[string]string
 }
 
 // egressAuthorizationResponse (to local dataplane) represents a response for an egressAuthorizationRequest.
@@ -65,16 +87,28 @@ type egressAuthorizationResponse struct {
 	Allowed bool
 	// RemotePeerCluster is the cluster name of the remote peer where the connection should be routed to.
 	RemotePeerCluster string
+	// SourceExport is the exported service of the selected import source.
+	SourceExport types.NamespacedName
 	// AccessToken is a token that allows accessing the requested service.
 	AccessToken string
+	// AppProtocol is the application protocol of the imported service.
+	AppProtocol v1alpha1.AppProtocol
 }
 
 // ingressAuthorizationRequest (to remote peer controlplane) represents a request for accessing an exported service.
 type ingressAuthorizationRequest struct {
+	// PeerName is the name of the remote peer requesting the connection, as authenticated by its certificate.
+	PeerName string
 	// Service is the name of the requested exported service.
 	ServiceName types.NamespacedName
+	// ServicePort is the port name of the requested exported service.
+	ServicePort string
 	// Attributes of the source workload, to be used by the PDP on the remote peer
 	SrcAttributes connectivitypdp.WorkloadAttrs
+	// SignedSrcAttributes is a signed assertion of the source workload attributes, if sent by the remote peer.
+	SignedSrcAttributes string
+	// AccessTokens is the number of access tokens requested by the remote peer.
+	AccessTokens int
 }
 
 // ingressAuthorizationResponse (from remote peer controlplane) represents a response for an ingressAuthorizationRequest.
@@ -83,8 +117,10 @@ type ingressAuthorizationResponse struct {
 	ServiceExists bool
 	// Allowed is true if the request is allowed.
 	Allowed bool
-	// AccessToken is a token that allows accessing the requested service.
-	AccessToken string
+	// RateLimited is true if the request is rejected due to the export rate limit.
+	RateLimited bool
+	// AccessTokens are single-use tokens that allow accessing the requested service.
+	AccessTokens []string
 }
 
 type podInfo struct {
@@ -100,13 +136,22 @@ type Manager struct {
 	namespace string
 
 	loadBalancer    *LoadBalancer
+	rateLimiter     *RateLimiter
+	replayCache     *ReplayCache
+	decisionCache   *DecisionCache
 	connectivityPDP *connectivitypdp.PDP
+	auditLogger     *audit.Logger
 
 	selfPeerLock sync.RWMutex
 	peerTLS      *tls.ParsedCertData
 	peerName     string
 	peerLabels   map[string]string
 
+	// signAttributes is set if source attributes sent to remote peers are signed
+	signAttributes bool
+	// requireSignedAttributes is set if source attributes sent by remote peers must be signed
+	requireSignedAttributes bool
+
 	peerClientLock sync.RWMutex
 	peerClient     map[string]*peer.Client
 
@@ -115,7 +160,28 @@ type Manager struct {
 	podList map[types.NamespacedName]podInfo
 
 	jwksLock sync.RWMutex
-	jwkKey   jwk.Key
+	jwks     *control.JWKS
+
+	// connections are the active egress and ingress connections, re-evaluated on changes
+	connections           *connectionTracker
+	revocationGracePeriod time.Duration
+	connectionRevoker     ConnectionRevoker
+
+	connectionsTimerLock sync.Mutex
+	connectionsTimer     *time.Timer
+	connectionsCheckAt   time.Time
+
+	// ingressAuthorizations are the authorizations of issued access tokens, keyed by their token IDs
+	ingressAuthorizations *ingressAuthorizations
+
+	healthTimersLock sync.Mutex
+	healthTimers     map[types.NamespacedName]*time.Timer
+
+	expiryTimersLock sync.Mutex
+	expiryTimers     map[types.NamespacedName]*time.Timer
+
+	rejectedConnectionsLock sync.Mutex
+	rejectedConnections     map[types.NamespacedName]int64
 
 	logger *logrus.Entry
 }
@@ -132,6 +198,10 @@ func (m *Manager) AddPeer(pr *v1alpha1.Peer) {
 	m.peerClientLock.Lock()
 	m.peerClient[pr.Name] = cl
 	m.peerClientLock.Unlock()
+
+	m.loadBalancer.AddPeer(pr)
+	m.decisionCache.Invalidate()
+	m.connectionsChanged()
 }
 
 // DeletePeer removes the possibility for egress dataplane connections to be routed to a given peer.
@@ -141,15 +211,23 @@ func (m *Manager) DeletePeer(name string) {
 	m.peerClientLock.Lock()
 	delete(m.peerClient, name)
 	m.peerClientLock.Unlock()
+
+	m.loadBalancer.DeletePeer(name)
+	m.decisionCache.Invalidate()
+	m.connectionsChanged()
 }
 
 // AddAccessPolicy adds an access policy to allow/deny specific connections.
 func (m *Manager) AddAccessPolicy(policy *connectivitypdp.AccessPolicy) error {
+	defer m.connectionsChanged()
+	defer m.decisionCache.Invalidate()
 	return m.connectivityPDP.AddOrUpdatePolicy(policy)
 }
 
 // DeleteAccessPolicy removes an access policy to allow/deny specific connections.
 func (m *Manager) DeleteAccessPolicy(name types.NamespacedName, privileged bool) error {
+	defer m.connectionsChanged()
+	defer m.decisionCache.Invalidate()
 	return m.connectivityPDP.DeletePolicy(name, privileged)
 }
 
@@ -159,11 +237,19 @@ func (m *Manager) deletePod(podID types.NamespacedName) {
 	defer m.podLock.Unlock()
 
 	delete(m.podList, podID)
+	var ips []string
 	for key, pod := range m.ipToPod {
 		if pod.Name == podID.Name && pod.Namespace == podID.Namespace {
 			delete(m.ipToPod, key)
+			ips = append(ips, key)
 		}
 	}
+
+	// decisions of the pod clients depend on the pod attributes
+	m.decisionCache.InvalidateClients(ips...)
+	if m.connections.hasClients(ips...) {
+		m.connectionsChanged()
+	}
 }
 
 // addPod adds or updates pod to ipToPod and podList.
@@ -178,12 +264,20 @@ func (m *Manager) addPod(pod *v1.Pod) {
 		labels:         pod.Labels,
 		serviceAccount: pod.Spec.ServiceAccountName,
 	}
+	var ips []string
 	for _, ip := range pod.Status.PodIPs {
 		// ignoring host-networked Pod IPs
 		if ip.IP != pod.Status.HostIP {
 			m.ipToPod[ip.IP] = podID
+			ips = append(ips, ip.IP)
 		}
 	}
+
+	// decisions of the pod clients depend on the pod attributes
+	m.decisionCache.InvalidateClients(ips...)
+	if m.connections.hasClients(ips...) {
+		m.connectionsChanged()
+	}
 }
 
 // addSecret adds a new secret.
@@ -192,19 +286,14 @@ func (m *Manager) addSecret(secret *v1.Secret) error {
 		return nil
 	}
 
-	key, err := control.ParseJWKSSecret(secret)
+	jwks, err := control.ParseJWKSSecret(secret)
 	if err != nil {
 		return fmt.Errorf("cannot parse JWKS secret: %w", err)
 	}
 
-	jwkKey, err := jwk.New(key)
-	if err != nil {
-		return fmt.Errorf("unable to create JWK key: %w", err)
-	}
-
 	m.jwksLock.Lock()
 	defer m.jwksLock.Unlock()
-	m.jwkKey = jwkKey
+	m.jwks = jwks
 
 	return nil
 }
@@ -222,6 +311,17 @@ func (m *Manager) getPodInfoByIP(ip string) *podInfo {
 	return nil
 }
 
+// getPodInfoByName returns the information about the Pod with the specified name.
+func (m *Manager) getPodInfoByName(name types.NamespacedName) *podInfo {
+	m.podLock.RLock()
+	defer m.podLock.RUnlock()
+
+	if pInfo, podExist := m.podList[name]; podExist {
+		return &pInfo
+	}
+	return nil
+}
+
 func (m *Manager) getSrcAttributes(req *egressAuthorizationRequest) connectivitypdp.WorkloadAttrs {
 	podInfo := m.getPodInfoByIP(req.IP)
 	if podInfo == nil {
@@ -229,6 +329,11 @@ func (m *Manager) getSrcAttributes(req *egressAuthorizationRequest) connectivity
 		return nil
 	}
 
+	return m.getPodAttributes(podInfo)
+}
+
+// getPodAttributes returns the attributes of a client pod, used by the PDP.
+func (m *Manager) getPodAttributes(podInfo *podInfo) connectivitypdp.WorkloadAttrs {
 	clientAttrs := connectivitypdp.WorkloadAttrs{
 		PeerNameLabel:        m.getPeerName(),
 		ClientNamespaceLabel: podInfo.namespace,
@@ -248,12 +353,40 @@ func (m *Manager) getSrcAttributes(req *egressAuthorizationRequest) connectivity
 	return clientAttrs
 }
 
-func (m *Manager) getDstAttributes(svcName, svcNS, peerName string,
+// getClientIdentity returns the identity of the client of an egress request, used for session affinity.
+// Returns an empty identity if the client cannot be identified.
+func (m *Manager) getClientIdentity(req *egressAuthorizationRequest, affinityType v1alpha1.SessionAffinityType) string {
+	if affinityType == v1alpha1.SessionAffinityClientIP {
+		return req.IP
+	}
+
+	podInfo := m.getPodInfoByIP(req.IP)
+	if podInfo == nil {
+		return ""
+	}
+
+	switch affinityType {
+	case v1alpha1.SessionAffinityClientPod:
+		return types.NamespacedName{Namespace: podInfo.namespace, Name: podInfo.name}.String()
+	case v1alpha1.SessionAffinityClientServiceAccount:
+		return types.NamespacedName{Namespace: podInfo.namespace, Name: podInfo.serviceAccount}.String()
+	}
+
+	return ""
+}
+
+func (m *Manager) getDstAttributes(svcName, svcNS, svcPort string, svcProtocol v1alpha1.Protocol, peerName string,
 	svcLabels, peerLabels map[string]string,
 ) connectivitypdp.WorkloadAttrs {
+	if svcProtocol == "" {
+		svcProtocol = v1alpha1.ProtocolDefault
+	}
+
 	dstAttributes := connectivitypdp.WorkloadAttrs{
 		ServiceNameLabel:      svcName,
 		ServiceNamespaceLabel: svcNS,
+		ServicePortLabel:      svcPort,
+		ServiceProtocolLabel:  string(svcProtocol),
 		PeerNameLabel:         peerName,
 	}
 	for k, v := range svcLabels {
@@ -269,20 +402,51 @@ func (m *Manager) getDstAttributes(svcName, svcNS, peerName string,
 }
 
 // authorizeEgress authorizes a request for accessing an imported service.
-func (m *Manager) authorizeEgress(ctx context.Context, req *egressAuthorizationRequest) (*egressAuthorizationResponse, error) {
+func (m *Manager) authorizeEgress(
+	ctx context.Context,
+	req *egressAuthorizationRequest,
+) (resp *egressAuthorizationResponse, err error) {
 	m.logger.Infof("Received egress authorization request: %v.", req)
 
+	ctx, span := tracing.Start(ctx, "authz.authorizeEgress",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("clusterlink.import", req.ImportName.String()),
+			attribute.String("clusterlink.port", req.ImportPort)))
+	defer func() { tracing.End(span, err) }()
+
 	srcAttributes := m.getSrcAttributes(req)
 	if len(srcAttributes) == 0 && m.connectivityPDP.DependsOnClientAttrs() {
 		return nil, fmt.Errorf("failed to extract client attributes, however, access policies depend on such attributes")
 	}
 
+	// entries read from now on are cached only if not invalidated meanwhile
+	cacheGeneration := m.decisionCache.Generation()
+
 	var imp v1alpha1.Import
-	if err := m.client.Get(ctx, req.ImportName, &imp); err != nil {
-		return nil, fmt.Errorf("cannot get import %v: %w", req.ImportName, err)
+	if cached, ok := m.decisionCache.GetImport(req.ImportName); ok {
+		imp = *cached
+	} else {
+		if err := m.client.Get(ctx, req.ImportName, &imp); err != nil {
+			return nil, fmt.Errorf("cannot get import %v: %w", req.ImportName, err)
+		}
+		m.decisionCache.AddImport(imp.DeepCopy(), cacheGeneration)
+	}
+
+	if _, ok := imp.Spec.ServicePort(req.ImportPort); !ok {
+		return nil, fmt.Errorf("import %v has no port named '%s'", req.ImportName, req.ImportPort)
+	}
+
+	if imp.Spec.AppProtocol == v1alpha1.AppProtocolHTTP {
+		// consider only the sources the HTTP request is routed to
+		imp.Spec.Sources = imp.Spec.RouteSources(req.Headers)
 	}
 
 	lbResult := NewLoadBalancingResult(&imp)
+	if imp.Spec.SessionAffinity != nil {
+		lbResult.SetClient(m.getClientIdentity(req, imp.Spec.SessionAffinity.Type))
+	}
+
 	for {
 		if err := m.loadBalancer.Select(lbResult); err != nil {
 			return nil, fmt.Errorf("cannot select import source: %w", err)
@@ -294,26 +458,65 @@ func (m *Manager) authorizeEgress(ctx context.Context, req *egressAuthorizationR
 			Namespace: m.namespace,
 		}
 
-		var pr v1alpha1.Peer
-		if err := m.client.Get(ctx, peerName, &pr); err != nil {
-			return nil, fmt.Errorf("cannot get peer '%s': %w", importSource.Peer, err)
+		cacheKey := DecisionCacheKey{
+			Client: req.IP,
+			Import: req.ImportName,
+			Port:   req.ImportPort,
+			Peer:   importSource.Peer,
+			Export: types.NamespacedName{Namespace: importSource.ExportNamespace, Name: importSource.ExportName},
+		}
+		cachedDecision, cached := m.decisionCache.Get(cacheKey)
+		if !cached {
+			var pr v1alpha1.Peer
+			if err := m.client.Get(ctx, peerName, &pr); err != nil {
+				return nil, fmt.Errorf("cannot get peer '%s': %w", importSource.Peer, err)
+			}
+
+			cachedDecision = &EgressDecision{
+				PeerReachable: meta.IsStatusConditionTrue(pr.Status.Conditions, v1alpha1.PeerReachable),
+				DstAttributes: m.getDstAttributes(
+					importSource.ExportName, importSource.ExportNamespace, req.ImportPort, imp.Spec.Protocol,
+					importSource.Peer, imp.Labels, pr.Status.Labels,
+				),
+			}
 		}
 
-		if !meta.IsStatusConditionTrue(pr.Status.Conditions, v1alpha1.PeerReachable) {
+		if !cachedDecision.PeerReachable || m.loadBalancer.IsEjected(lbResult) {
 			if !lbResult.IsDelayed() {
 				lbResult.Delay()
 				continue
 			}
 		}
 
-		dstAttributes := m.getDstAttributes(
-			importSource.ExportName, importSource.ExportNamespace,
-			importSource.Peer, imp.Labels, pr.Status.Labels,
-		)
-		decision, err := m.connectivityPDP.Decide(srcAttributes, dstAttributes, req.ImportName.Namespace)
-		if err != nil {
-			return nil, fmt.Errorf("error deciding on an egress connection: %w", err)
+		if m.loadBalancer.IsOverloaded(lbResult) {
+			m.logger.Infof("Circuit breaker limiting connections to source peer '%s'.", importSource.Peer)
+			continue
+		}
+
+		dstAttributes := cachedDecision.DstAttributes
+		if !cached {
+			// the decision is cached until any time-restricted policy changes its effect
+			now := time.Now()
+			validUntil := m.connectivityPDP.NextScheduleTransition(now, now.Add(decisionCacheTTL))
+			cachedDecision.Decision, err = m.connectivityPDP.Decide(srcAttributes, dstAttributes, req.ImportName.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("error deciding on an egress connection: %w", err)
+			}
+			m.decisionCache.Add(cacheKey, cachedDecision, cacheGeneration, validUntil)
 		}
+		decision := cachedDecision.Decision
+
+		auditRecord := newAuditRecord(
+			audit.DirectionEgress, srcAttributes, dstAttributes,
+			types.NamespacedName{Namespace: importSource.ExportNamespace, Name: importSource.ExportName},
+			req.ImportPort, importSource.Peer, decision,
+		)
+		auditRecord.Import = req.ImportName.String()
+		m.recordDecision(auditRecord)
+		span.AddEvent("access policy decision", trace.WithAttributes(
+			attribute.String("clusterlink.peer", importSource.Peer),
+			attribute.String("clusterlink.decision", auditRecord.Decision),
+			attribute.String("clusterlink.policy", auditRecord.Policy)))
 
 		if decision.Decision != connectivitypdp.DecisionAllow {
 			m.logger.Infof("PDP not allowing connection: src:%v, dst:%v, decision: %+v", srcAttributes, dstAttributes, decision)
@@ -338,69 +541,189 @@ func (m *Manager) authorizeEgress(ctx context.Context, req *egressAuthorizationR
 			DstNamespace = req.ImportName.Namespace
 		}
 
-		m.logger.Infof("Egress authorized. Sending authorization request to %s", importSource.Peer)
-		accessToken, err := cl.Authorize(
-			ctx,
-			&cpapi.AuthorizationRequest{
+		// use a spare access token issued by the remote peer for a former connection, if possible
+		accessToken, spare := m.decisionCache.GetToken(cacheKey)
+		if !spare {
+			authzReq := &cpapi.AuthorizationRequest{
 				ServiceName:      DstName,
 				ServiceNamespace: DstNamespace,
+				ServicePort:      req.ImportPort,
 				SrcAttributes:    srcAttributes,
-			})
-		if err != nil {
-			m.logger.Infof("Unable to get access token from peer: %v", err)
-			continue
+			}
+			// a cached decision indicates repeated connections of the client, which may use spare tokens
+			if cached {
+				authzReq.AccessTokens = accessTokenBatchSize
+			}
+			if m.signAttributes && len(srcAttributes) > 0 {
+				authzReq.SignedSrcAttributes, err = m.signSrcAttributes(authzReq, importSource.Peer)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			m.logger.Infof("Egress authorized. Sending authorization request to %s", importSource.Peer)
+			var accessTokens []string
+			accessTokens, err = cl.Authorize(ctx, authzReq)
+			if err != nil {
+				m.logger.Infof("Unable to get access token from peer: %v", err)
+
+				// record the remote peer refusal, following the local decision allowing the connection
+				m.recordDecision(&audit.Record{
+					Direction:     audit.DirectionEgress,
+					SrcAttributes: srcAttributes,
+					DstAttributes: dstAttributes,
+					Import:        req.ImportName.String(),
+					Export:        auditRecord.Export,
+					Port:          req.ImportPort,
+					Peer:          importSource.Peer,
+					Decision:      audit.DecisionDeny,
+					Reason:        fmt.Sprintf("remote peer refused the connection: %v", err),
+				})
+				continue
+			}
+			accessToken = accessTokens[0]
+			m.cacheAccessTokens(cacheKey, accessTokens[1:], cacheGeneration)
 		}
 
+		m.loadBalancer.Commit(lbResult)
+		selectionsMetric.WithLabelValues(req.ImportName.Namespace, req.ImportName.Name, importSource.Peer).Inc()
+		span.SetAttributes(attribute.String("clusterlink.peer", importSource.Peer))
+
 		return &egressAuthorizationResponse{
 			Allowed:           true,
 			RemotePeerCluster: cpapi.RemotePeerClusterName(importSource.Peer),
+			SourceExport:      types.NamespacedName{Namespace: DstNamespace, Name: DstName},
 			AccessToken:       accessToken,
+			AppProtocol:       imp.Spec.AppProtocol,
 		}, nil
 	}
 }
 
-// parseAuthorizationHeader verifies an access token for an ingress dataplane connection.
-// On success, returns the parsed target cluster name.
-func (m *Manager) parseAuthorizationHeader(token string) (string, error) {
+// cacheAccessTokens caches spare access tokens issued by a remote peer, for further connections
+// (of the same client, to the same import source).
+func (m *Manager) cacheAccessTokens(key DecisionCacheKey, accessTokens []string, generation uint64) {
+	if len(accessTokens) == 0 {
+		return
+	}
+
+	// the tokens are verified by the remote peer on use, hence their claims are only parsed.
+	// Tokens of a batch share the same expiry.
+	token, err := jwt.ParseString(accessTokens[0])
+	if err != nil {
+		m.logger.Warnf("Cannot parse access token: %v.", err)
+		return
+	}
+
+	m.decisionCache.AddTokens(key, accessTokens, token.Expiration().Add(-accessTokenReuseMargin), generation)
+}
+
+// parseAuthorizationHeader verifies an access token for an ingress dataplane connection of the given peer.
+// Each token may be used once. On success, returns the parsed target cluster name, and the ID of the token.
+func (m *Manager) parseAuthorizationHeader(token, peerName string) (string, string, error) {
 	m.logger.Debug("Parsing access token.")
 
 	m.jwksLock.RLock()
-	jwkkey := m.jwkKey
+	jwks := m.jwks
 	m.jwksLock.RUnlock()
 
-	if jwkkey == nil {
-		return "", fmt.Errorf("jwk key undefined")
+	if jwks == nil {
+		return "", "", fmt.Errorf("jwk key undefined")
 	}
 
-	parsedToken, err := jwt.ParseString(
-		token, jwt.WithVerify(cpapi.JWTSignatureAlgorithm, jwkkey), jwt.WithValidate(true))
+	// accept tokens signed by any non-retired key, so that tokens issued just before a rotation remain valid
+	keys, err := jwks.VerificationKeys(time.Now())
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	// TODO: verify client name
+	// the token must be issued to the peer opening the connection, as authenticated by its certificate
+	parsedToken, err := jwt.ParseString(
+		token, jwt.WithKeySet(keys), jwt.WithValidate(true), jwt.WithSubject(peerName))
+	if err != nil {
+		return "", "", err
+	}
 
 	exportName, ok := parsedToken.PrivateClaims()[cpapi.ExportNameJWTClaim]
 	if !ok {
-		return "", fmt.Errorf("token missing '%s' claim", cpapi.ExportNameJWTClaim)
+		return "", "", fmt.Errorf("token missing '%s' claim", cpapi.ExportNameJWTClaim)
 	}
 
 	exportNamespace, ok := parsedToken.PrivateClaims()[cpapi.ExportNamespaceJWTClaim]
 	if !ok {
-		return "", fmt.Errorf("token missing '%s' claim", cpapi.ExportNamespaceJWTClaim)
+		return "", "", fmt.Errorf("token missing '%s' claim", cpapi.ExportNamespaceJWTClaim)
+	}
+
+	// tokens for the single unnamed port of an exported service carry no port claim
+	var exportPort string
+	if port, ok := parsedToken.PrivateClaims()[cpapi.ExportPortJWTClaim]; ok {
+		if exportPort, ok = port.(string); !ok {
+			return "", "", fmt.Errorf("invalid '%s' claim", cpapi.ExportPortJWTClaim)
+		}
+	}
+
+	if parsedToken.JwtID() == "" {
+		return "", "", fmt.Errorf("token missing '%s' claim", jwt.JwtIDKey)
+	}
+	if !m.replayCache.Use(parsedToken.JwtID(), parsedToken.Expiration()) {
+		return "", "", fmt.Errorf("token already used")
+	}
+
+	return cpapi.ExportClusterName(exportName.(string), exportNamespace.(string), exportPort), parsedToken.JwtID(), nil
+}
+
+// checkSpareAccessToken re-evaluates the authorization of a spare access token, which was issued in a batch
+// ahead of its use, in case the access policies changed since. Other tokens are not re-evaluated.
+func (m *Manager) checkSpareAccessToken(ctx context.Context, tokenID string) error {
+	authorization := m.ingressAuthorizations.get(tokenID)
+	if authorization == nil || !authorization.spare {
+		return nil
+	}
+
+	return m.checkIngressAccess(ctx, authorization.export, authorization.port, authorization.srcAttributes)
+}
+
+// checkHTTPExport verifies that the exported service of the given export cluster accepts HTTP requests.
+func (m *Manager) checkHTTPExport(ctx context.Context, exportCluster string) error {
+	name, namespace, _, err := cpapi.ParseExportClusterName(strings.TrimPrefix(exportCluster, cpapi.ExportClusterPrefix))
+	if err != nil {
+		return err
 	}
 
-	return cpapi.ExportClusterName(exportName.(string), exportNamespace.(string)), nil
+	var export v1alpha1.Export
+	if err := m.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &export); err != nil {
+		return fmt.Errorf("cannot get export '%s/%s': %w", namespace, name, err)
+	}
+
+	if export.Spec.AppProtocol != v1alpha1.AppProtocolHTTP {
+		return fmt.Errorf("exported service '%s/%s' does not accept HTTP requests", namespace, name)
+	}
+
+	return nil
 }
 
 // authorizeIngress authorizes a request for accessing an exported service.
 func (m *Manager) authorizeIngress(
 	ctx context.Context,
 	req *ingressAuthorizationRequest,
-) (*ingressAuthorizationResponse, error) {
+) (resp *ingressAuthorizationResponse, err error) {
 	m.logger.Infof("Received ingress authorization request: %v.", req)
 
-	resp := &ingressAuthorizationResponse{}
+	ctx, span := tracing.Start(ctx, "authz.authorizeIngress",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("clusterlink.export", req.ServiceName.String()),
+			attribute.String("clusterlink.port", req.ServicePort),
+			attribute.String("clusterlink.peer", req.PeerName)))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(
+				attribute.Bool("clusterlink.allowed", resp.Allowed),
+				attribute.Bool("clusterlink.rate_limited", resp.RateLimited))
+		}
+		tracing.End(span, err)
+	}()
+
+	resp = &ingressAuthorizationResponse{}
 
 	// check that a corresponding export exists
 	exportName := types.NamespacedName{
@@ -416,53 +739,120 @@ func (m *Manager) authorizeIngress(
 		return nil, fmt.Errorf("cannot get export %v: %w", exportName, err)
 	}
 
+	if _, ok := export.Spec.ServicePort(req.ServicePort); !ok {
+		return resp, nil
+	}
+
 	resp.ServiceExists = true
 
+	srcAttributes, err := m.getIngressSrcAttributes(req)
+	if err != nil {
+		m.logger.Infof("Rejecting source attributes sent by peer '%s': %v.", req.PeerName, err)
+		auditRecord := newAuditRecord(
+			audit.DirectionIngress, req.SrcAttributes, nil, exportName, req.ServicePort, req.PeerName, nil)
+		auditRecord.Reason = err.Error()
+		m.recordDecision(auditRecord)
+		resp.Allowed = false
+		return resp, nil
+	}
+
 	// do not allow requests from clients with no attributes if the PDP has attribute-dependent policies
-	if len(req.SrcAttributes) == 0 && m.connectivityPDP.DependsOnClientAttrs() {
+	if len(srcAttributes) == 0 && m.connectivityPDP.DependsOnClientAttrs() {
 		m.logger.Infof("PDP not allowing connection: No client attributes")
+		auditRecord := newAuditRecord(audit.DirectionIngress, nil, nil, exportName, req.ServicePort, req.PeerName, nil)
+		auditRecord.Reason = "no client attributes, however, access policies depend on such attributes"
+		m.recordDecision(auditRecord)
 		resp.Allowed = false
 		return resp, nil
 	}
 
-	dstAttributes := m.getDstAttributes(export.Name, export.Namespace, m.getPeerName(), export.Labels, m.peerLabels)
-	decision, err := m.connectivityPDP.Decide(req.SrcAttributes, dstAttributes, req.ServiceName.Namespace)
+	dstAttributes := m.getDstAttributes(
+		export.Name, export.Namespace, req.ServicePort, export.Spec.Protocol,
+		m.getPeerName(), export.Labels, m.peerLabels,
+	)
+	decision, err := m.connectivityPDP.Decide(srcAttributes, dstAttributes, req.ServiceName.Namespace)
 	if err != nil {
 		return nil, fmt.Errorf("error deciding on an ingress connection: %w", err)
 	}
 
+	auditRecord := newAuditRecord(
+		audit.DirectionIngress, srcAttributes, dstAttributes, exportName, req.ServicePort, req.PeerName, decision)
 	if decision.Decision != connectivitypdp.DecisionAllow {
-		m.logger.Infof("PDP not allowing connection: src:%v, dst:%v, decision: %+v", req.SrcAttributes, dstAttributes, decision)
+		m.logger.Infof("PDP not allowing connection: src:%v, dst:%v, decision: %+v", srcAttributes, dstAttributes, decision)
+		m.recordDecision(auditRecord)
 		resp.Allowed = false
 		return resp, nil
 	}
+
+	clientNamespace := srcAttributes[ClientNamespaceLabel]
+	if !m.rateLimiter.Allow(exportName, export.Spec.RateLimit, req.PeerName, clientNamespace) {
+		m.logger.Infof("Rate limit exceeded for export '%v' by peer '%s'.", exportName, req.PeerName)
+		auditRecord.Decision = audit.DecisionDeny
+		auditRecord.Reason = "export rate limit exceeded"
+		m.recordDecision(auditRecord)
+		m.connectionRejected(exportName)
+		resp.RateLimited = true
+		return resp, nil
+	}
+	m.recordDecision(auditRecord)
 	resp.Allowed = true
 
-	// create access token
-	// TODO: include client name as a claim
-	token, err := jwt.NewBuilder().
-		Expiration(time.Now().Add(time.Second*jwtExpirySeconds)).
-		Claim(cpapi.ExportNameJWTClaim, req.ServiceName.Name).
-		Claim(cpapi.ExportNamespaceJWTClaim, req.ServiceName.Namespace).
-		Build()
-	if err != nil {
-		return nil, fmt.Errorf("unable to generate access token: %w", err)
+	// create single-use access tokens for the requesting peer. Multiple tokens are issued only if requested,
+	// and only if connections are not rate limited, so that each connection is counted by the rate limiter
+	tokens := 1
+	if export.Spec.RateLimit == nil || export.Spec.RateLimit.ConnectionsPerSecond == 0 {
+		tokens = min(max(req.AccessTokens, 1), maxAccessTokenBatchSize)
 	}
 
 	m.jwksLock.RLock()
-	jwkKey := m.jwkKey
+	jwks := m.jwks
 	m.jwksLock.RUnlock()
 
-	if jwkKey == nil {
+	if jwks == nil {
 		return nil, fmt.Errorf("jwk key undefined")
 	}
 
-	// sign access token
-	signed, err := jwt.Sign(token, cpapi.JWTSignatureAlgorithm, jwkKey)
-	if err != nil {
-		return nil, fmt.Errorf("unable to sign access token: %w", err)
+	expiry := time.Now().Add(time.Second * jwtExpirySeconds)
+	for i := 0; i < tokens; i++ {
+		tokenID := make([]byte, 16)
+		if _, err := rand.Read(tokenID); err != nil {
+			return nil, fmt.Errorf("unable to generate access token ID: %w", err)
+		}
+
+		tokenBuilder := jwt.NewBuilder().
+			Subject(req.PeerName).
+			JwtID(hex.EncodeToString(tokenID)).
+			Expiration(expiry).
+			Claim(cpapi.ExportNameJWTClaim, req.ServiceName.Name).
+			Claim(cpapi.ExportNamespaceJWTClaim, req.ServiceName.Namespace)
+		if req.ServicePort != "" {
+			tokenBuilder = tokenBuilder.Claim(cpapi.ExportPortJWTClaim, req.ServicePort)
+		}
+
+		token, err := tokenBuilder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate access token: %w", err)
+		}
+
+		// sign access token (identified by the key ID of the signing key)
+		jwkKey := jwks.SigningKey(time.Now())
+		signed, err := jwt.Sign(token, jwa.SignatureAlgorithm(jwkKey.Algorithm()), jwkKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign access token: %w", err)
+		}
+		resp.AccessTokens = append(resp.AccessTokens, string(signed))
+
+		// keep the authorization, to re-evaluate the connections it authorizes on changes.
+		// Spare tokens are used by later connections, hence are also re-evaluated when used
+		m.ingressAuthorizations.add(hex.EncodeToString(tokenID), &ingressAuthorization{
+			peer:          req.PeerName,
+			export:        exportName,
+			port:          req.ServicePort,
+			srcAttributes: srcAttributes,
+			expiry:        expiry,
+			spare:         i > 0,
+		})
 	}
-	resp.AccessToken = string(signed)
 
 	m.logger.Infof("Ingress authorized. Sending authorization response: %v", resp)
 	return resp, nil
@@ -502,20 +892,29 @@ func (m *Manager) SetPeerCertificates(peerTLS *tls.ParsedCertData, _ *tls.RawCer
 func (m *Manager) IsReady() bool {
 	m.jwksLock.RLock()
 	defer m.jwksLock.RUnlock()
-	return m.jwkKey != nil
+	return m.jwks != nil
 }
 
 // NewManager returns a new authorization manager.
 func NewManager(cl client.Client, namespace string, peerLabels map[string]string) *Manager {
 	return &Manager{
-		client:          cl,
-		namespace:       namespace,
-		peerLabels:      peerLabels,
-		connectivityPDP: connectivitypdp.NewPDP(),
-		loadBalancer:    NewLoadBalancer(),
-		peerClient:      make(map[string]*peer.Client),
-		ipToPod:         make(map[string]types.NamespacedName),
-		podList:         make(map[types.NamespacedName]podInfo),
-		logger:          logrus.WithField("component", "controlplane.authz.manager"),
+		client:                cl,
+		namespace:             namespace,
+		peerLabels:            peerLabels,
+		connectivityPDP:       connectivitypdp.NewPDP(),
+		loadBalancer:          NewLoadBalancer(peerLabels),
+		rateLimiter:           NewRateLimiter(),
+		replayCache:           NewReplayCache(),
+		decisionCache:         NewDecisionCache(),
+		connections:           newConnectionTracker(),
+		ingressAuthorizations: newIngressAuthorizations(),
+		revocationGracePeriod: DefaultRevocationGracePeriod,
+		peerClient:            make(map[string]*peer.Client),
+		ipToPod:               make(map[string]types.NamespacedName),
+		podList:               make(map[types.NamespacedName]podInfo),
+		healthTimers:          make(map[types.NamespacedName]*time.Timer),
+		expiryTimers:          make(map[types.NamespacedName]*time.Timer),
+		rejectedConnections:   make(map[types.NamespacedName]int64),
+		logger:                logrus.WithField("component", "controlplane.authz.manager"),
 	}
 }
@@ -0,0 +1,168 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/control"
+)
+
+// jwkSecret returns a JWKS secret holding a single signing key.
+func jwkSecret(t *testing.T, namespace string) *v1.Secret {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	key, err := jwk.New(privateKey)
+	require.Nil(t, err)
+	require.Nil(t, jwk.AssignKeyID(key))
+	require.Nil(t, key.Set(jwk.AlgorithmKey, cpapi.JWTSignatureAlgorithm))
+	require.Nil(t, key.Set(control.JWKCreatedParam, time.Now().Add(-time.Hour).Unix()))
+
+	set := jwk.NewSet()
+	set.Add(key)
+	data, err := json.Marshal(set)
+	require.Nil(t, err)
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: control.JWKSecretName},
+		Data:       map[string][]byte{control.JWKSecretKeyName: data},
+	}
+}
+
+// newIngressManager returns an authorization manager of a peer exporting the given services,
+// and allowing all connections to them.
+func newIngressManager(t *testing.T, exports ...client.Object) *Manager {
+	scheme := runtime.NewScheme()
+	require.Nil(t, v1alpha1.AddToScheme(scheme))
+
+	m := NewManager(fake.NewClientBuilder().WithScheme(scheme).WithObjects(exports...).Build(), "default", nil)
+	require.Nil(t, m.addSecret(jwkSecret(t, "default")))
+	require.Nil(t, m.AddAccessPolicy(connectivitypdp.PolicyFromCR(&v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-all", Namespace: "default"},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+			To:     []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+		},
+	})))
+
+	return m
+}
+
+// issueAccessTokens returns the access tokens issued for connections of a peer to an exported service,
+// requesting the given number of tokens.
+func issueAccessTokens(
+	t *testing.T, m *Manager, peerName string, exportName types.NamespacedName, requested int,
+) []string {
+	resp, err := m.authorizeIngress(context.Background(), &ingressAuthorizationRequest{
+		PeerName:      peerName,
+		ServiceName:   exportName,
+		SrcAttributes: connectivitypdp.WorkloadAttrs{ClientNamespaceLabel: "default"},
+		AccessTokens:  requested,
+	})
+	require.Nil(t, err)
+	require.True(t, resp.Allowed)
+	require.NotEmpty(t, resp.AccessTokens)
+	return resp.AccessTokens
+}
+
+// issueAccessToken returns an access token issued for a connection of a peer to an exported service.
+func issueAccessToken(t *testing.T, m *Manager, peerName string, exportName types.NamespacedName) string {
+	tokens := issueAccessTokens(t, m, peerName, exportName, 0)
+	require.Len(t, tokens, 1)
+	return tokens[0]
+}
+
+func TestAccessTokenBatch(t *testing.T) {
+	exportName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	m := newIngressManager(t, &v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: exportName.Namespace, Name: exportName.Name},
+		Spec:       v1alpha1.ExportSpec{Port: 80},
+	})
+	exportCluster := cpapi.ExportClusterName(exportName.Name, exportName.Namespace, "")
+	ctx := context.Background()
+
+	// a batch of distinct tokens is issued, and the spare tokens are cached by the requesting peer
+	tokens := issueAccessTokens(t, m, "peer1", exportName, accessTokenBatchSize)
+	require.Len(t, tokens, accessTokenBatchSize)
+	key := DecisionCacheKey{Client: "10.0.0.1", Import: exportName, Peer: "server"}
+	m.cacheAccessTokens(key, tokens[1:], m.decisionCache.Generation())
+
+	for i, token := range tokens {
+		if i > 0 {
+			cached, ok := m.decisionCache.GetToken(key)
+			require.True(t, ok)
+			require.Equal(t, token, cached)
+		}
+
+		targetCluster, tokenID, err := m.parseAuthorizationHeader(token, "peer1")
+		require.Nil(t, err)
+		require.Equal(t, exportCluster, targetCluster)
+		require.Nil(t, m.checkSpareAccessToken(ctx, tokenID))
+	}
+
+	// cached tokens are used by a single connection each
+	_, ok := m.decisionCache.GetToken(key)
+	require.False(t, ok)
+	for _, token := range tokens {
+		_, _, err := m.parseAuthorizationHeader(token, "peer1")
+		require.NotNil(t, err)
+	}
+
+	// the number of issued tokens is bounded
+	require.Len(t, issueAccessTokens(t, m, "peer1", exportName, 100), maxAccessTokenBatchSize)
+
+	// spare tokens are re-evaluated when used, following changes to the access policies
+	tokens = issueAccessTokens(t, m, "peer1", exportName, 2)
+	_, firstID, err := m.parseAuthorizationHeader(tokens[0], "peer1")
+	require.Nil(t, err)
+	_, spareID, err := m.parseAuthorizationHeader(tokens[1], "peer1")
+	require.Nil(t, err)
+	require.Nil(t, m.DeleteAccessPolicy(types.NamespacedName{Name: "allow-all", Namespace: "default"}, false))
+	require.Nil(t, m.checkSpareAccessToken(ctx, firstID))
+	require.ErrorContains(t, m.checkSpareAccessToken(ctx, spareID), "denied")
+}
+
+func TestAccessTokenBatchRateLimited(t *testing.T) {
+	limitedExportName := types.NamespacedName{Namespace: "default", Name: "limited"}
+	m := newIngressManager(t, &v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: limitedExportName.Namespace, Name: limitedExportName.Name},
+		Spec: v1alpha1.ExportSpec{
+			Port:      80,
+			RateLimit: &v1alpha1.ExportRateLimit{ConnectionsPerSecond: 100},
+		},
+	})
+
+	// a single token is issued for rate limited exports, so that each connection is counted by the rate limiter
+	require.Len(t, issueAccessTokens(t, m, "peer1", limitedExportName, accessTokenBatchSize), 1)
+}
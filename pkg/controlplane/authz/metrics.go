@@ -0,0 +1,92 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "clusterlink"
+
+var (
+	decisionsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "authz",
+		Name:      "decisions_total",
+		Help:      "Number of connection authorization decisions, by direction, decision and deciding access policy.",
+	}, []string{"direction", "decision", "policy", "tier"})
+
+	selectionsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "loadbalancer",
+		Name:      "selections_total",
+		Help:      "Number of import sources selected by the load balancer for egress connections.",
+	}, []string{"namespace", "import", "peer"})
+
+	egressConnectionsMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "egress",
+		Name:      "active_connections",
+		Help:      "Number of active egress connections reported by the dataplanes, by import and source peer.",
+	}, []string{"namespace", "import", "peer"})
+
+	rejectedConnectionsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ingress",
+		Name:      "rejected_connections_total",
+		Help:      "Number of ingress connections rejected due to the export rate limit.",
+	}, []string{"namespace", "export"})
+
+	revokedConnectionsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "egress",
+		Name:      "revoked_connections_total",
+		Help:      "Number of active egress connections revoked as they are no longer allowed.",
+	})
+
+	ingressRevokedConnectionsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ingress",
+		Name:      "revoked_connections_total",
+		Help:      "Number of active ingress connections revoked as they are no longer allowed.",
+	})
+
+	decisionCacheLookupsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "authz",
+		Name:      "decision_cache_lookups_total",
+		Help:      "Number of egress decision cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	tokenCacheLookupsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "authz",
+		Name:      "token_cache_lookups_total",
+		Help:      "Number of access token cache lookups for allowed egress connections, by result (hit or miss).",
+	}, []string{"result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		decisionsMetric,
+		selectionsMetric,
+		egressConnectionsMetric,
+		rejectedConnectionsMetric,
+		revokedConnectionsMetric,
+		ingressRevokedConnectionsMetric,
+		decisionCacheLookupsMetric,
+		tokenCacheLookupsMetric,
+	)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+const (
+	// rejectedConnectionsReportInterval is the interval for reporting rejected connections in the export status.
+	rejectedConnectionsReportInterval = 10 * time.Second
+	// rateLimitersPruneInterval is the interval for evicting idle connection rate limiters.
+	rateLimitersPruneInterval = time.Minute
+)
+
+// exportRateLimiters holds the connection rate limiters of an export.
+type exportRateLimiters struct {
+	// spec is the rate limit from which the limiters were created.
+	spec v1alpha1.ExportRateLimit
+	// limiters are keyed by the rate limit scope (e.g. peer name).
+	limiters map[string]*rate.Limiter
+}
+
+// RateLimiter limits the rate of new connections to exported services.
+// Limits are kept in memory, hence each controlplane replica enforces them independently.
+type RateLimiter struct {
+	lock    sync.Mutex
+	exports map[types.NamespacedName]*exportRateLimiters
+	// lastPrune is the last time idle limiters were evicted.
+	lastPrune time.Time
+}
+
+// Allow returns whether a new connection to an export is within the given export rate limit.
+// peerName is the remote peer requesting the connection, and clientNamespace is the namespace of its client.
+func (r *RateLimiter) Allow(
+	exportName types.NamespacedName, rateLimit *v1alpha1.ExportRateLimit, peerName, clientNamespace string,
+) bool {
+	if rateLimit == nil || rateLimit.ConnectionsPerSecond == 0 {
+		r.DeleteExport(exportName)
+		return true
+	}
+
+	var key string
+	switch rateLimit.Per {
+	case v1alpha1.RateLimitScopePeer:
+		key = peerName
+	case v1alpha1.RateLimitScopeClientNamespace:
+		key = peerName + "/" + clientNamespace
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if now := time.Now(); now.Sub(r.lastPrune) >= rateLimitersPruneInterval {
+		r.prune(now)
+	}
+
+	limiters, ok := r.exports[exportName]
+	if !ok || limiters.spec != *rateLimit {
+		// reset limiters on rate limit changes
+		limiters = &exportRateLimiters{
+			spec:     *rateLimit,
+			limiters: make(map[string]*rate.Limiter),
+		}
+		r.exports[exportName] = limiters
+	}
+
+	limiter, ok := limiters.limiters[key]
+	if !ok {
+		burst := rateLimit.Burst
+		if burst == 0 {
+			burst = rateLimit.ConnectionsPerSecond
+		}
+
+		limiter = rate.NewLimiter(rate.Limit(rateLimit.ConnectionsPerSecond), int(burst))
+		limiters.limiters[key] = limiter
+	}
+
+	return limiter.Allow()
+}
+
+// DeleteExport removes the connection rate limiters of an export.
+func (r *RateLimiter) DeleteExport(exportName types.NamespacedName) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.exports, exportName)
+}
+
+// prune evicts limiters which are idle at the given time.
+// A limiter which refilled its burst behaves like a new one, hence it is safe to evict.
+// Must be called while holding the lock.
+func (r *RateLimiter) prune(now time.Time) {
+	for exportName, limiters := range r.exports {
+		for key, limiter := range limiters.limiters {
+			if limiter.TokensAt(now) >= float64(limiter.Burst()) {
+				delete(limiters.limiters, key)
+			}
+		}
+
+		if len(limiters.limiters) == 0 {
+			delete(r.exports, exportName)
+		}
+	}
+
+	r.lastPrune = now
+}
+
+// NewRateLimiter returns a new connection rate limiter.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		exports: make(map[types.NamespacedName]*exportRateLimiters),
+	}
+}
+
+// connectionRejected counts a connection to an export, which was rejected due to the export rate limit.
+// Rejected connections are periodically added to the export status.
+func (m *Manager) connectionRejected(exportName types.NamespacedName) {
+	rejectedConnectionsMetric.WithLabelValues(exportName.Namespace, exportName.Name).Inc()
+
+	m.rejectedConnectionsLock.Lock()
+	defer m.rejectedConnectionsLock.Unlock()
+
+	if m.rejectedConnections[exportName] == 0 {
+		time.AfterFunc(rejectedConnectionsReportInterval, func() {
+			m.reportRejectedConnections(exportName)
+		})
+	}
+
+	m.rejectedConnections[exportName]++
+}
+
+// reportRejectedConnections adds the connections to an export rejected since the last report to the export status.
+func (m *Manager) reportRejectedConnections(exportName types.NamespacedName) {
+	m.rejectedConnectionsLock.Lock()
+	rejected := m.rejectedConnections[exportName]
+	delete(m.rejectedConnections, exportName)
+	m.rejectedConnectionsLock.Unlock()
+
+	if rejected == 0 {
+		return
+	}
+
+	m.logger.Infof("Rejected %d connections to export '%s' due to rate limiting.", rejected, exportName)
+
+	// other controlplane replicas may concurrently report their rejected connections
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var export v1alpha1.Export
+		if err := m.client.Get(context.Background(), exportName, &export); err != nil {
+			return err
+		}
+
+		export.Status.RejectedConnections += rejected
+		return m.client.Status().Update(context.Background(), &export)
+	})
+	if err != nil {
+		m.logger.Warnf("Cannot update export '%s' rejected connections: %v.", exportName, err)
+	}
+}
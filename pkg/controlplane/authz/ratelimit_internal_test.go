@@ -0,0 +1,57 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+)
+
+func TestRateLimiterPrune(t *testing.T) {
+	exportName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	rateLimit := &v1alpha1.ExportRateLimit{
+		ConnectionsPerSecond: 1,
+		Burst:                2,
+		Per:                  v1alpha1.RateLimitScopePeer,
+	}
+
+	rl := NewRateLimiter()
+	start := time.Now()
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer2", "ns1"))
+	require.Len(t, rl.exports[exportName].limiters, 2)
+
+	// limiters which did not refill their burst are kept
+	rl.prune(start.Add(time.Second / 2))
+	require.Len(t, rl.exports[exportName].limiters, 2)
+
+	// peer2 limiter refilled its burst
+	rl.prune(start.Add(3 * time.Second / 2))
+	require.Len(t, rl.exports[exportName].limiters, 1)
+	require.Contains(t, rl.exports[exportName].limiters, "peer1")
+
+	// all limiters refilled their burst
+	rl.prune(start.Add(3 * time.Second))
+	require.Empty(t, rl.exports)
+
+	// a new limiter is created on the next connection
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.Len(t, rl.exports[exportName].limiters, 1)
+}
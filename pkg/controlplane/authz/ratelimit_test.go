@@ -0,0 +1,77 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz"
+)
+
+func TestRateLimiter(t *testing.T) {
+	exportName := types.NamespacedName{Namespace: "default", Name: "svc"}
+
+	// no rate limit
+	rl := authz.NewRateLimiter()
+	for i := 0; i < 100; i++ {
+		require.True(t, rl.Allow(exportName, nil, "peer1", "ns1"))
+	}
+
+	// all connections limited together
+	rateLimit := &v1alpha1.ExportRateLimit{
+		ConnectionsPerSecond: 1,
+		Burst:                2,
+		Per:                  v1alpha1.RateLimitScopeExport,
+	}
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer2", "ns1"))
+	require.False(t, rl.Allow(exportName, rateLimit, "peer1", "ns2"))
+
+	// other exports are not limited
+	otherExportName := types.NamespacedName{Namespace: "default", Name: "other"}
+	require.True(t, rl.Allow(otherExportName, rateLimit, "peer1", "ns1"))
+
+	// connections from each peer limited separately, burst defaults to the rate
+	rateLimit = &v1alpha1.ExportRateLimit{
+		ConnectionsPerSecond: 1,
+		Per:                  v1alpha1.RateLimitScopePeer,
+	}
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.False(t, rl.Allow(exportName, rateLimit, "peer1", "ns2"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer2", "ns1"))
+
+	// connections from each client namespace limited separately
+	rateLimit = &v1alpha1.ExportRateLimit{
+		ConnectionsPerSecond: 1,
+		Per:                  v1alpha1.RateLimitScopeClientNamespace,
+	}
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.False(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns2"))
+	require.True(t, rl.Allow(exportName, rateLimit, "peer2", "ns1"))
+
+	// deleting the export resets its limiters
+	rl.DeleteExport(exportName)
+	require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+
+	// a zero rate means no limit
+	rateLimit = &v1alpha1.ExportRateLimit{MaxConnections: 1}
+	for i := 0; i < 100; i++ {
+		require.True(t, rl.Allow(exportName, rateLimit, "peer1", "ns1"))
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"sync"
+	"time"
+)
+
+// replayCachePruneInterval is the interval for removing expired tokens from the replay cache.
+const replayCachePruneInterval = 10 * time.Second
+
+// ReplayCache records the IDs of used access tokens until they expire, allowing each token to be used once.
+// Ingress connections of all dataplane replicas are authorized by the controlplane, hence share the cache.
+type ReplayCache struct {
+	lock sync.Mutex
+	// used maps the IDs of used tokens to their expiry time
+	used      map[string]time.Time
+	nextPrune time.Time
+}
+
+// Use records the use of a token with the given ID and expiry time.
+// Returns false if the token was already used.
+func (c *ReplayCache) Use(id string, expiry time.Time) bool {
+	now := time.Now()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !now.Before(c.nextPrune) {
+		for usedID, usedExpiry := range c.used {
+			if usedExpiry.Before(now) {
+				delete(c.used, usedID)
+			}
+		}
+		c.nextPrune = now.Add(replayCachePruneInterval)
+	}
+
+	if _, ok := c.used[id]; ok {
+		return false
+	}
+
+	c.used[id] = expiry
+	return true
+}
+
+// Len returns the number of tokens in the cache.
+func (c *ReplayCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.used)
+}
+
+// NewReplayCache returns a new empty replay cache.
+func NewReplayCache() *ReplayCache {
+	return &ReplayCache{
+		used: make(map[string]time.Time),
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz"
+)
+
+func TestReplayCache(t *testing.T) {
+	cache := authz.NewReplayCache()
+	expiry := time.Now().Add(time.Minute)
+
+	// first use of each token is allowed
+	require.True(t, cache.Use("token1", expiry))
+	require.True(t, cache.Use("token2", expiry))
+	require.Equal(t, 2, cache.Len())
+
+	// replayed tokens are rejected
+	require.False(t, cache.Use("token1", expiry))
+	require.False(t, cache.Use("token2", expiry))
+	require.Equal(t, 2, cache.Len())
+}
@@ -0,0 +1,431 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+const (
+	// DefaultRevocationGracePeriod is the default time a connection may stay open once it is no longer allowed.
+	DefaultRevocationGracePeriod = 10 * time.Second
+	// connectionsCheckDelay is the delay for batching changes before re-evaluating the active connections.
+	connectionsCheckDelay = time.Second
+	// scheduleTransitionHorizon bounds the look-ahead for schedule transitions of time-restricted access policies.
+	// Active connections are re-evaluated at least once per horizon.
+	scheduleTransitionHorizon = time.Hour
+	// ingressAuthorizationRetention is the time the authorization of an access token is kept after the token expires,
+	// for ingress connections authorized by the token to be reported by the dataplanes.
+	ingressAuthorizationRetention = time.Minute
+)
+
+// ConnectionRevoker terminates connections revoked by the controlplane.
+type ConnectionRevoker interface {
+	// RevokeConnections sets the revoked active connections of an import listener filter chain (egress connections),
+	// of an import listener if the filter chain is empty, or of an export cluster (ingress connections).
+	RevokeConnections(resourceName, filterChain string, revoked []cpapi.ConnectionID) error
+}
+
+// revocationResource identifies the connections revoked together: the connections of an import listener
+// filter chain, or of an import listener (or export cluster) if the filter chain is empty.
+type revocationResource struct {
+	name        string
+	filterChain string
+}
+
+// ingressAuthorization is an ingress connection authorization, granted to a remote peer by an access token.
+type ingressAuthorization struct {
+	peer          string
+	export        types.NamespacedName
+	port          string
+	srcAttributes connectivitypdp.WorkloadAttrs
+	expiry        time.Time
+	// spare is set for the additional tokens of a batch, which are used by later connections.
+	spare bool
+}
+
+// ingressAuthorizations keeps the authorizations of the issued access tokens, keyed by their token IDs,
+// to re-evaluate the ingress connections reported with the ID of their access token.
+type ingressAuthorizations struct {
+	lock           sync.Mutex
+	authorizations map[string]*ingressAuthorization
+}
+
+// add keeps the authorization of an issued access token, and prunes the authorizations of long expired tokens.
+func (a *ingressAuthorizations) add(tokenID string, authorization *ingressAuthorization) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	for id, auth := range a.authorizations {
+		if now.After(auth.expiry.Add(ingressAuthorizationRetention)) {
+			delete(a.authorizations, id)
+		}
+	}
+
+	a.authorizations[tokenID] = authorization
+}
+
+// get returns the authorization of an access token, or nil if unknown.
+func (a *ingressAuthorizations) get(tokenID string) *ingressAuthorization {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.authorizations[tokenID]
+}
+
+func newIngressAuthorizations() *ingressAuthorizations {
+	return &ingressAuthorizations{
+		authorizations: make(map[string]*ingressAuthorization),
+	}
+}
+
+// trackedConnection is an active connection reported by a dataplane.
+type trackedConnection struct {
+	conn connection
+	// revokeAt is the time the connection is revoked, unless allowed again before.
+	// Zero if the connection is allowed.
+	revokeAt time.Time
+	revoked  bool
+}
+
+// connectionTracker tracks the active connections reported by the dataplanes,
+// revoking connections which are no longer allowed for a grace period.
+type connectionTracker struct {
+	lock        sync.Mutex
+	connections map[cpapi.ConnectionID]*trackedConnection
+}
+
+// add tracks a newly established connection.
+func (t *connectionTracker) add(id cpapi.ConnectionID, conn connection) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.connections[id] = &trackedConnection{conn: conn}
+}
+
+// remove stops tracking a closed connection.
+func (t *connectionTracker) remove(id cpapi.ConnectionID) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.connections, id)
+}
+
+// hasClients returns whether any of the given client IP addresses has a tracked connection.
+func (t *connectionTracker) hasClients(ips ...string) bool {
+	if len(ips) == 0 {
+		return false
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, tracked := range t.connections {
+		if slices.Contains(ips, tracked.conn.clientIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// active returns the connections which are not revoked.
+func (t *connectionTracker) active() map[cpapi.ConnectionID]connection {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	active := make(map[cpapi.ConnectionID]connection, len(t.connections))
+	for id, tracked := range t.connections {
+		if !tracked.revoked {
+			active[id] = tracked.conn
+		}
+	}
+	return active
+}
+
+// update updates the connections following their re-evaluation at the given time.
+// A connection denied for the grace period is revoked, and a connection allowed again is no longer pending revocation.
+// Returns the revoked connections of the resources (listener filter chains, listeners and export clusters)
+// having newly revoked connections, and the time of the next pending revocation (zero if none).
+func (t *connectionTracker) update(
+	allowed map[cpapi.ConnectionID]bool, now time.Time, gracePeriod time.Duration,
+) (map[revocationResource][]cpapi.ConnectionID, time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	revokedResources := make(map[revocationResource]bool)
+	var next time.Time
+	for id, isAllowed := range allowed {
+		tracked, ok := t.connections[id]
+		if !ok || tracked.revoked {
+			continue
+		}
+
+		switch {
+		case isAllowed:
+			tracked.revokeAt = time.Time{}
+			continue
+		case tracked.revokeAt.IsZero():
+			tracked.revokeAt = now.Add(gracePeriod)
+		}
+
+		if tracked.revokeAt.After(now) {
+			if next.IsZero() || tracked.revokeAt.Before(next) {
+				next = tracked.revokeAt
+			}
+			continue
+		}
+
+		tracked.revoked = true
+		if tracked.conn.ingress != nil {
+			ingressRevokedConnectionsMetric.Inc()
+		} else {
+			revokedConnectionsMetric.Inc()
+		}
+		revokedResources[tracked.conn.revocationResource()] = true
+	}
+
+	revoked := make(map[revocationResource][]cpapi.ConnectionID, len(revokedResources))
+	for id, tracked := range t.connections {
+		if resource := tracked.conn.revocationResource(); tracked.revoked && revokedResources[resource] {
+			revoked[resource] = append(revoked[resource], id)
+		}
+	}
+
+	return revoked, next
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		connections: make(map[cpapi.ConnectionID]*trackedConnection),
+	}
+}
+
+// SetRevocationGracePeriod sets the time a connection may stay open once it is no longer allowed.
+func (m *Manager) SetRevocationGracePeriod(gracePeriod time.Duration) {
+	m.revocationGracePeriod = gracePeriod
+}
+
+// SetConnectionRevoker sets the revoker terminating connections which are no longer allowed.
+func (m *Manager) SetConnectionRevoker(revoker ConnectionRevoker) {
+	m.connectionRevoker = revoker
+}
+
+// trackConnection handles a dataplane report of an established connection, to be re-evaluated on changes.
+func (m *Manager) trackConnection(id cpapi.ConnectionID, conn connection) {
+	m.connections.add(id, conn)
+
+	m.connectionsTimerLock.Lock()
+	scheduled := m.connectionsTimer != nil
+	m.connectionsTimerLock.Unlock()
+
+	// a pending re-evaluation schedules the following ones
+	if !scheduled {
+		m.scheduleTransitionCheck(time.Now())
+	}
+}
+
+// untrackConnection handles a dataplane report of a closed connection.
+func (m *Manager) untrackConnection(id cpapi.ConnectionID) {
+	m.connections.remove(id)
+}
+
+// connectionsChanged schedules a re-evaluation of the active connections, following a change
+// to the access policies or to the objects they depend on.
+func (m *Manager) connectionsChanged() {
+	m.scheduleConnectionsCheck(time.Now().Add(connectionsCheckDelay))
+}
+
+// scheduleConnectionsCheck schedules a re-evaluation of the active connections at a given time,
+// unless an earlier re-evaluation is already scheduled.
+func (m *Manager) scheduleConnectionsCheck(at time.Time) {
+	m.connectionsTimerLock.Lock()
+	defer m.connectionsTimerLock.Unlock()
+
+	if m.connectionsTimer != nil {
+		if !m.connectionsCheckAt.After(at) {
+			return
+		}
+		m.connectionsTimer.Stop()
+	}
+
+	m.connectionsCheckAt = at
+	m.connectionsTimer = time.AfterFunc(time.Until(at), m.checkConnections)
+}
+
+// scheduleTransitionCheck schedules a re-evaluation of the active connections at the next time
+// a time-restricted access policy changes its effect.
+func (m *Manager) scheduleTransitionCheck(now time.Time) {
+	m.scheduleConnectionsCheck(m.connectivityPDP.NextScheduleTransition(now, now.Add(scheduleTransitionHorizon)))
+}
+
+// checkConnections re-evaluates the active connections, revoking connections which are no longer allowed.
+func (m *Manager) checkConnections() {
+	m.connectionsTimerLock.Lock()
+	m.connectionsTimer = nil
+	m.connectionsTimerLock.Unlock()
+
+	ctx := context.Background()
+	active := m.connections.active()
+
+	// connections of the same client, import and source share the decision
+	decisions := make(map[connection]bool)
+	allowed := make(map[cpapi.ConnectionID]bool, len(active))
+	for id, conn := range active {
+		isAllowed, ok := decisions[conn]
+		if !ok {
+			var err error
+			if conn.ingress != nil {
+				err = m.checkIngressConnection(ctx, &conn)
+				if err != nil {
+					m.logger.Infof("Connection to export '%v' from peer '%s' is no longer allowed: %v.",
+						conn.export, conn.peer, err)
+				}
+			} else {
+				err = m.checkConnection(ctx, &conn)
+				if err != nil {
+					m.logger.Infof("Connection to import '%v' from %s via peer '%s' is no longer allowed: %v.",
+						conn.importName, conn.clientIP, conn.peer, err)
+				}
+			}
+
+			isAllowed = err == nil
+			decisions[conn] = isAllowed
+		}
+
+		allowed[id] = isAllowed
+	}
+
+	now := time.Now()
+	revoked, next := m.connections.update(allowed, now, m.revocationGracePeriod)
+	if !next.IsZero() {
+		m.scheduleConnectionsCheck(next)
+	}
+	if len(active) > 0 {
+		m.scheduleTransitionCheck(now)
+	}
+
+	for resource, ids := range revoked {
+		m.logger.Infof("Revoking connections of '%s' filter chain '%s' (%d revoked connections).",
+			resource.name, resource.filterChain, len(ids))
+		if m.connectionRevoker == nil {
+			continue
+		}
+		if err := m.connectionRevoker.RevokeConnections(resource.name, resource.filterChain, ids); err != nil {
+			m.logger.Errorf("Cannot revoke connections of '%s' filter chain '%s': %v.",
+				resource.name, resource.filterChain, err)
+		}
+	}
+}
+
+// checkConnection checks if an active egress connection is still allowed:
+// its import, import source and source peer still exist, and the access policies allow it.
+func (m *Manager) checkConnection(ctx context.Context, conn *connection) error {
+	var imp v1alpha1.Import
+	if err := m.client.Get(ctx, conn.importName, &imp); err != nil {
+		return fmt.Errorf("cannot get import: %w", err)
+	}
+
+	if _, ok := imp.Spec.ServicePort(conn.port); !ok {
+		return fmt.Errorf("import has no port named '%s'", conn.port)
+	}
+
+	// connections reported without the export of their source are matched by the source peer only
+	connSource := newSourceID(conn.importName, conn.source())
+	var source *v1alpha1.ImportSource
+	for i := range imp.Spec.Sources {
+		if imp.Spec.Sources[i].Peer != conn.peer {
+			continue
+		}
+		if conn.export.Name == "" || newSourceID(conn.importName, &imp.Spec.Sources[i]) == connSource {
+			source = &imp.Spec.Sources[i]
+			break
+		}
+	}
+	if source == nil {
+		return fmt.Errorf("%s is not a source of the import", connSource)
+	}
+
+	var pr v1alpha1.Peer
+	if err := m.client.Get(ctx, types.NamespacedName{Namespace: m.namespace, Name: conn.peer}, &pr); err != nil {
+		return fmt.Errorf("cannot get peer: %w", err)
+	}
+
+	srcAttributes := m.getSrcAttributes(&egressAuthorizationRequest{IP: conn.clientIP})
+	if len(srcAttributes) == 0 && m.connectivityPDP.DependsOnClientAttrs() {
+		return fmt.Errorf("failed to extract client attributes, however, access policies depend on such attributes")
+	}
+
+	dstAttributes := m.getDstAttributes(
+		source.ExportName, source.ExportNamespace, conn.port, imp.Spec.Protocol,
+		conn.peer, imp.Labels, pr.Status.Labels,
+	)
+	decision, err := m.connectivityPDP.Decide(srcAttributes, dstAttributes, conn.importName.Namespace)
+	if err != nil {
+		return fmt.Errorf("error deciding on an egress connection: %w", err)
+	}
+
+	if decision.Decision != connectivitypdp.DecisionAllow {
+		return fmt.Errorf("denied by access policies")
+	}
+
+	return nil
+}
+
+// checkIngressConnection checks if an active ingress connection is still allowed, given the source attributes
+// sent by the remote peer when requesting its access token.
+func (m *Manager) checkIngressConnection(ctx context.Context, conn *connection) error {
+	return m.checkIngressAccess(ctx, conn.export, conn.port, conn.ingress.srcAttributes)
+}
+
+// checkIngressAccess checks if a client of a remote peer is allowed to access an exported service port:
+// the export and port exist, and the access policies allow it, given the source attributes of the client.
+func (m *Manager) checkIngressAccess(
+	ctx context.Context, exportName types.NamespacedName, port string, srcAttributes connectivitypdp.WorkloadAttrs,
+) error {
+	var export v1alpha1.Export
+	if err := m.client.Get(ctx, exportName, &export); err != nil {
+		return fmt.Errorf("cannot get export: %w", err)
+	}
+
+	if _, ok := export.Spec.ServicePort(port); !ok {
+		return fmt.Errorf("export has no port named '%s'", port)
+	}
+
+	if len(srcAttributes) == 0 && m.connectivityPDP.DependsOnClientAttrs() {
+		return fmt.Errorf("no client attributes, however, access policies depend on such attributes")
+	}
+
+	dstAttributes := m.getDstAttributes(
+		export.Name, export.Namespace, port, export.Spec.Protocol,
+		m.getPeerName(), export.Labels, m.peerLabels,
+	)
+	decision, err := m.connectivityPDP.Decide(srcAttributes, dstAttributes, export.Namespace)
+	if err != nil {
+		return fmt.Errorf("error deciding on an ingress connection: %w", err)
+	}
+
+	if decision.Decision != connectivitypdp.DecisionAllow {
+		return fmt.Errorf("denied by access policies")
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+func TestConnectionTracker(t *testing.T) {
+	tracker := newConnectionTracker()
+	importName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	conn1 := connection{importName: importName, port: "http", peer: "peer1", clientIP: "10.0.0.1", filterChain: "10.0.0.1"}
+	conn2 := connection{importName: importName, port: "http", peer: "peer1", clientIP: "10.0.0.2", filterChain: "10.0.0.2"}
+	id1 := cpapi.ConnectionID{Dataplane: "dp1", StreamID: "1"}
+	id2 := cpapi.ConnectionID{Dataplane: "dp1", StreamID: "2"}
+	id3 := cpapi.ConnectionID{Dataplane: "dp1", StreamID: "3"}
+	chain1 := revocationResource{name: conn1.resourceName(), filterChain: "10.0.0.1"}
+	chain2 := revocationResource{name: conn2.resourceName(), filterChain: "10.0.0.2"}
+	require.Equal(t, chain1, conn1.revocationResource())
+
+	tracker.add(id1, conn1)
+	tracker.add(id2, conn2)
+	require.True(t, tracker.hasClients("10.0.0.2", "10.0.0.3"))
+	require.False(t, tracker.hasClients("10.0.0.3"))
+	require.Len(t, tracker.active(), 2)
+
+	// denied connections are revoked only after the grace period
+	now := time.Now()
+	gracePeriod := 10 * time.Second
+	revoked, next := tracker.update(map[cpapi.ConnectionID]bool{id1: false, id2: true}, now, gracePeriod)
+	require.Empty(t, revoked)
+	require.Equal(t, now.Add(gracePeriod), next)
+
+	// a connection allowed again is no longer pending revocation
+	revoked, next = tracker.update(map[cpapi.ConnectionID]bool{id1: true, id2: true}, now.Add(time.Second), gracePeriod)
+	require.Empty(t, revoked)
+	require.True(t, next.IsZero())
+
+	revoked, _ = tracker.update(map[cpapi.ConnectionID]bool{id1: false, id2: true}, now.Add(2*time.Second), gracePeriod)
+	require.Empty(t, revoked)
+	revoked, next = tracker.update(map[cpapi.ConnectionID]bool{id1: false, id2: true}, now.Add(12*time.Second), gracePeriod)
+	require.Equal(t, map[revocationResource][]cpapi.ConnectionID{chain1: {id1}}, revoked)
+	require.True(t, next.IsZero())
+	require.Len(t, tracker.active(), 1)
+
+	// revoked connections are listed with newly revoked connections of the same filter chain
+	tracker.add(id3, conn1)
+	revoked, _ = tracker.update(map[cpapi.ConnectionID]bool{id2: true, id3: false}, now.Add(12*time.Second), 0)
+	require.Len(t, revoked, 1)
+	require.ElementsMatch(t, []cpapi.ConnectionID{id1, id3}, revoked[chain1])
+
+	// filter chains with no newly revoked connections are not listed
+	revoked, _ = tracker.update(map[cpapi.ConnectionID]bool{id2: false}, now.Add(12*time.Second), 0)
+	require.Equal(t, map[revocationResource][]cpapi.ConnectionID{chain2: {id2}}, revoked)
+
+	// closed connections are no longer tracked
+	tracker.remove(id1)
+	tracker.remove(id2)
+	tracker.remove(id3)
+	require.Empty(t, tracker.active())
+	require.False(t, tracker.hasClients("10.0.0.1"))
+}
+
+func TestCheckConnection(t *testing.T) {
+	importName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	imp := &v1alpha1.Import{
+		ObjectMeta: metav1.ObjectMeta{Namespace: importName.Namespace, Name: importName.Name},
+		Spec: v1alpha1.ImportSpec{
+			Port: 80,
+			Sources: []v1alpha1.ImportSource{
+				{Peer: "peer1", ExportName: "svc", ExportNamespace: "ns1"},
+				{Peer: "peer1", ExportName: "svc", ExportNamespace: "ns2"},
+				{Peer: "peer2"},
+			},
+		},
+	}
+	peers := []*v1alpha1.Peer{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "peer1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "peer2"}},
+	}
+
+	scheme := runtime.NewScheme()
+	require.Nil(t, v1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(imp, peers[0], peers[1]).Build()
+	m := NewManager(cl, "default", nil)
+
+	// allow connections to the sources exporting the service from namespace ns1
+	require.Nil(t, m.AddAccessPolicy(connectivitypdp.PolicyFromCR(&v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-ns1", Namespace: "default"},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From:   []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+			To: []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{ServiceNamespaceLabel: "ns1"},
+			}}},
+		},
+	})))
+
+	ctx := context.Background()
+	newConnection := func(peer, exportNamespace string) *connection {
+		conn := &connection{importName: importName, peer: peer, clientIP: "10.0.0.1"}
+		if exportNamespace != "" {
+			conn.export = types.NamespacedName{Namespace: exportNamespace, Name: "svc"}
+		}
+		return conn
+	}
+
+	// connections are matched with their source, also among sources of the same peer
+	require.Nil(t, m.checkConnection(ctx, newConnection("peer1", "ns1")))
+	require.ErrorContains(t, m.checkConnection(ctx, newConnection("peer1", "ns2")), "denied")
+	require.ErrorContains(t, m.checkConnection(ctx, newConnection("peer1", "ns3")), "not a source")
+
+	// sources with no export name and namespace export the service with the import name and namespace
+	require.ErrorContains(t, m.checkConnection(ctx, newConnection("peer2", "default")), "denied")
+	require.ErrorContains(t, m.checkConnection(ctx, newConnection("peer2", "ns1")), "not a source")
+
+	// connections reported without the export are matched by the source peer
+	require.Nil(t, m.checkConnection(ctx, newConnection("peer1", "")))
+	require.ErrorContains(t, m.checkConnection(ctx, newConnection("peer3", "")), "not a source")
+
+	// connections of a deleted port or import are no longer allowed
+	conn := newConnection("peer1", "ns1")
+	conn.port = "http"
+	require.NotNil(t, m.checkConnection(ctx, conn))
+	require.Nil(t, cl.Delete(ctx, imp))
+	require.NotNil(t, m.checkConnection(ctx, newConnection("peer1", "ns1")))
+}
+
+func TestConnectionsScheduleTransition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.Nil(t, v1alpha1.AddToScheme(scheme))
+	m := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), "default", nil)
+	m.SetRevocationGracePeriod(24 * time.Hour)
+
+	stopTimer := func() {
+		m.connectionsTimerLock.Lock()
+		defer m.connectionsTimerLock.Unlock()
+		if m.connectionsTimer != nil {
+			m.connectionsTimer.Stop()
+			m.connectionsTimer = nil
+		}
+	}
+	defer stopTimer()
+
+	notBefore := time.Now().Add(10 * time.Minute)
+	require.Nil(t, m.AddAccessPolicy(connectivitypdp.PolicyFromCR(&v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-later", Namespace: "default"},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action:    v1alpha1.AccessPolicyActionAllow,
+			From:      []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+			To:        []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+			NotBefore: &metav1.Time{Time: notBefore},
+		},
+	})))
+	stopTimer()
+
+	// tracking a connection schedules a re-evaluation when the policy comes into effect
+	id := cpapi.ConnectionID{Dataplane: "dp", StreamID: "1"}
+	m.trackConnection(id, connection{
+		importName: types.NamespacedName{Namespace: "default", Name: "svc"},
+		peer:       "peer1",
+		clientIP:   "10.0.0.1",
+	})
+	require.True(t, m.connectionsCheckAt.Equal(notBefore))
+
+	// re-evaluations keep the schedule transition, earlier than the revocation of the denied connection
+	stopTimer()
+	m.checkConnections()
+	require.True(t, m.connectionsCheckAt.Equal(notBefore))
+
+	// with no active connections, there is nothing to re-evaluate
+	m.untrackConnection(id)
+	stopTimer()
+	m.checkConnections()
+	m.connectionsTimerLock.Lock()
+	require.Nil(t, m.connectionsTimer)
+	m.connectionsTimerLock.Unlock()
+}
+
+func TestIngressConnections(t *testing.T) {
+	exportName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	m := newIngressManager(t, &v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: exportName.Namespace, Name: exportName.Name},
+		Spec:       v1alpha1.ExportSpec{Port: 80},
+	})
+
+	token := issueAccessToken(t, m, "peer1", exportName)
+	targetCluster, tokenID, err := m.parseAuthorizationHeader(token, "peer1")
+	require.Nil(t, err)
+	require.NotEmpty(t, tokenID)
+
+	server := newAccessLogServer(m)
+	connections := make(map[string]connection)
+	logEntry := func(logType accesslogdatav3.AccessLogType, streamID, tokenID string) *accesslogdatav3.HTTPAccessLogEntry {
+		return &accesslogdatav3.HTTPAccessLogEntry{
+			CommonProperties: &accesslogdatav3.AccessLogCommon{
+				AccessLogType:   logType,
+				StreamId:        streamID,
+				UpstreamCluster: targetCluster,
+			},
+			Request: &accesslogdatav3.HTTPRequestProperties{
+				RequestHeaders: map[string]string{cpapi.AccessTokenIDHeader: tokenID},
+			},
+		}
+	}
+
+	// ingress connections are tracked with the authorization of their access token
+	server.handleLogEntry(
+		logEntry(accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished, "1", tokenID), "dp", connections)
+	server.handleLogEntry(
+		logEntry(accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished, "2", "unknown"), "dp", connections)
+	active := m.connections.active()
+	require.Len(t, active, 1)
+	conn, ok := active[cpapi.ConnectionID{Dataplane: "dp", StreamID: "1"}]
+	require.True(t, ok)
+	require.Equal(t, "peer1", conn.peer)
+	require.Equal(t, exportName, conn.export)
+	require.Equal(t, targetCluster, conn.resourceName())
+
+	// connections are re-evaluated using the source attributes sent when requesting the access token
+	ctx := context.Background()
+	require.Nil(t, m.checkIngressConnection(ctx, &conn))
+	require.Nil(t, m.DeleteAccessPolicy(types.NamespacedName{Name: "allow-all", Namespace: "default"}, false))
+	require.ErrorContains(t, m.checkIngressConnection(ctx, &conn), "denied")
+	require.Nil(t, m.AddAccessPolicy(connectivitypdp.PolicyFromCR(&v1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-default", Namespace: "default"},
+		Spec: v1alpha1.AccessPolicySpec{
+			Action: v1alpha1.AccessPolicyActionAllow,
+			From: []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{ClientNamespaceLabel: "default", PeerNameLabel: "peer1"},
+			}}},
+			To: []v1alpha1.WorkloadSetOrSelector{{WorkloadSelector: &metav1.LabelSelector{}}},
+		},
+	})))
+	require.Nil(t, m.checkIngressConnection(ctx, &conn))
+
+	// connections of a deleted port are no longer allowed
+	conn.port = "http"
+	require.ErrorContains(t, m.checkIngressConnection(ctx, &conn), "no port")
+
+	// closed connections are no longer tracked
+	server.handleLogEntry(logEntry(accesslogdatav3.AccessLogType_DownstreamEnd, "1", tokenID), "dp", connections)
+	require.Empty(t, m.connections.active())
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/sirupsen/logrus"
@@ -30,7 +31,9 @@ import (
 	"google.golang.org/grpc"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 const (
@@ -53,6 +56,9 @@ func (s *server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.C
 		return nil, fmt.Errorf("invalid check request: %v", req)
 	}
 
+	// continue the trace of the connection, if propagated by the dataplane
+	ctx = tracing.ExtractGRPC(ctx)
+
 	var resp *authv3.CheckResponse
 	if req.Attributes.Source.Address != nil &&
 		req.Attributes.Source.Address.GetEnvoyInternalAddress() != nil {
@@ -87,7 +93,9 @@ func (s *server) checkEgress(ctx context.Context, req *authv3.CheckRequest) *aut
 			Namespace: headers[api.ImportNamespaceHeader],
 			Name:      headers[api.ImportNameHeader],
 		},
-		IP: headers[api.ClientIPHeader],
+		ImportPort: headers[api.ImportPortHeader],
+		IP:         headers[api.ClientIPHeader],
+		Headers:    headers,
 	})
 	if err != nil {
 		return buildDeniedResponse(code.Code_INTERNAL, typev3.StatusCode_InternalServerError, err.Error())
@@ -99,6 +107,12 @@ func (s *server) checkEgress(ctx context.Context, req *authv3.CheckRequest) *aut
 		return buildDeniedResponse(code.Code_PERMISSION_DENIED, typev3.StatusCode_Forbidden, errorString)
 	}
 
+	// HTTP requests keep the authorization header of the application
+	authorizationHeader := api.AuthorizationHeader
+	if resp.AppProtocol == v1alpha1.AppProtocolHTTP {
+		authorizationHeader = api.PeerAuthorizationHeader
+	}
+
 	return buildAllowedResponse(&authv3.OkHttpResponse{
 		Headers: []*corev3.HeaderValueOption{
 			{
@@ -109,10 +123,16 @@ func (s *server) checkEgress(ctx context.Context, req *authv3.CheckRequest) *aut
 			},
 			{
 				Header: &corev3.HeaderValue{
-					Key:   api.AuthorizationHeader,
+					Key:   authorizationHeader,
 					Value: bearerSchemaPrefix + resp.AccessToken,
 				},
 			},
+			{
+				Header: &corev3.HeaderValue{
+					Key:   api.ImportSourceHeader,
+					Value: resp.SourceExport.String(),
+				},
+			},
 		},
 	})
 }
@@ -128,27 +148,40 @@ func (s *server) encodePeerLabels() string {
 // check an ingress dataplane connection.
 func (s *server) checkIngress(ctx context.Context, req *authv3.CheckRequest) *authv3.CheckResponse {
 	httpReq := req.Attributes.Request.Http
+	_, httpRequest := httpReq.Headers[api.PeerAuthorizationHeader]
 	switch {
+	case httpRequest:
+		// HTTP requests to services shared with appProtocol http may have any method and path
+		return s.checkServiceAccessRequest(ctx, req.Attributes.Source.Principal, httpReq, api.PeerAuthorizationHeader)
 	case httpReq.Method == http.MethodGet && httpReq.Path == api.HeartbeatPath:
 		// heartbeat request always simply allowed. Peer labels are added to the OK response.
 		hv := &corev3.HeaderValue{Key: api.PeerLabelsCustomHeader, Value: s.encodePeerLabels()}
 		hvo := &corev3.HeaderValueOption{Header: hv, AppendAction: corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD}
 		return buildAllowedResponse(&authv3.OkHttpResponse{ResponseHeadersToAdd: []*corev3.HeaderValueOption{hvo}})
 	case httpReq.Method == http.MethodPost && httpReq.Path == api.RemotePeerAuthorizationPath:
-		return s.checkAuthorizationRequest(ctx, httpReq)
+		return s.checkAuthorizationRequest(ctx, req.Attributes.Source.Principal, httpReq)
 	case httpReq.Method == http.MethodConnect:
-		return s.checkServiceAccessRequest(httpReq)
+		return s.checkServiceAccessRequest(ctx, req.Attributes.Source.Principal, httpReq, api.AuthorizationHeader)
+	case httpReq.Method == http.MethodGet && strings.HasPrefix(httpReq.Path, api.ConnectUDPPathPrefix):
+		// CONNECT-UDP requests are seen as HTTP/1.1 upgrade requests
+		return s.checkServiceAccessRequest(ctx, req.Attributes.Source.Principal, httpReq, api.AuthorizationHeader)
 	}
 
 	errorString := fmt.Sprintf("No handler defined for %s %s.", httpReq.Method, httpReq.Path)
 	return buildDeniedResponse(code.Code_INVALID_ARGUMENT, typev3.StatusCode_BadRequest, errorString)
 }
 
-// check an ingress connection for accessing an exported service.
-func (s *server) checkServiceAccessRequest(req *authv3.AttributeContext_HttpRequest) *authv3.CheckResponse {
-	authorization, ok := req.Headers[api.AuthorizationHeader]
+// check an ingress connection (or HTTP request) of a peer for accessing an exported service,
+// using the access token in the given header.
+func (s *server) checkServiceAccessRequest(
+	ctx context.Context,
+	peerName string,
+	req *authv3.AttributeContext_HttpRequest,
+	authorizationHeader string,
+) *authv3.CheckResponse {
+	authorization, ok := req.Headers[authorizationHeader]
 	if !ok {
-		errorString := fmt.Sprintf("Missing '%s' header.", api.AuthorizationHeader)
+		errorString := fmt.Sprintf("Missing '%s' header.", authorizationHeader)
 		return buildDeniedResponse(code.Code_INVALID_ARGUMENT, typev3.StatusCode_BadRequest, errorString)
 	}
 
@@ -158,28 +191,55 @@ func (s *server) checkServiceAccessRequest(req *authv3.AttributeContext_HttpRequ
 	}
 	token := strings.TrimPrefix(authorization, bearerSchemaPrefix)
 
-	targetCluster, err := s.manager.parseAuthorizationHeader(token)
+	targetCluster, tokenID, err := s.manager.parseAuthorizationHeader(token, peerName)
 	if err != nil {
 		return buildDeniedResponse(code.Code_PERMISSION_DENIED, typev3.StatusCode_Forbidden, err.Error())
 	}
 
-	return buildAllowedResponse(&authv3.OkHttpResponse{
-		Headers: []*corev3.HeaderValueOption{
-			{
-				Header: &corev3.HeaderValue{
-					Key:   api.TargetClusterHeader,
-					Value: targetCluster,
-				},
+	if err := s.manager.checkSpareAccessToken(ctx, tokenID); err != nil {
+		s.logger.Infof("Rejecting spare access token of peer '%s': %v.", peerName, err)
+		return buildDeniedResponse(code.Code_PERMISSION_DENIED, typev3.StatusCode_Forbidden, err.Error())
+	}
+
+	if authorizationHeader == api.PeerAuthorizationHeader {
+		if err := s.manager.checkHTTPExport(ctx, targetCluster); err != nil {
+			return buildDeniedResponse(code.Code_PERMISSION_DENIED, typev3.StatusCode_Forbidden, err.Error())
+		}
+	}
+
+	headers := []*corev3.HeaderValueOption{
+		{
+			Header: &corev3.HeaderValue{
+				Key:   api.TargetClusterHeader,
+				Value: targetCluster,
 			},
 		},
-	})
+	}
+
+	// tunneled connections are reported by the dataplane with their token ID, to be re-evaluated on changes
+	if authorizationHeader == api.AuthorizationHeader {
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{
+				Key:   api.AccessTokenIDHeader,
+				Value: tokenID,
+			},
+		})
+	}
+
+	return buildAllowedResponse(&authv3.OkHttpResponse{Headers: headers})
 }
 
 // check an ingress connection for authorizing access to an exported service.
 func (s *server) checkAuthorizationRequest(
 	ctx context.Context,
+	peerName string,
 	req *authv3.AttributeContext_HttpRequest,
 ) *authv3.CheckResponse {
+	// continue the trace of the remote peer, propagated using the authorization request headers
+	if !tracing.HasSpan(ctx) {
+		ctx = tracing.ExtractMap(ctx, req.Headers)
+	}
+
 	var authzReq api.AuthorizationRequest
 	if err := json.NewDecoder(strings.NewReader(req.Body)).Decode(&authzReq); err != nil {
 		s.logger.Errorf("Cannot decode authorization request: %v.", err)
@@ -189,11 +249,15 @@ func (s *server) checkAuthorizationRequest(
 	resp, err := s.manager.authorizeIngress(
 		ctx,
 		&ingressAuthorizationRequest{
+			PeerName: peerName,
 			ServiceName: types.NamespacedName{
 				Namespace: authzReq.ServiceNamespace,
 				Name:      authzReq.ServiceName,
 			},
-			SrcAttributes: authzReq.SrcAttributes,
+			ServicePort:         authzReq.ServicePort,
+			SrcAttributes:       authzReq.SrcAttributes,
+			SignedSrcAttributes: authzReq.SignedSrcAttributes,
+			AccessTokens:        authzReq.AccessTokens,
 		})
 	switch {
 	case err != nil:
@@ -203,6 +267,11 @@ func (s *server) checkAuthorizationRequest(
 			"Exported service '%s/%s' not found.",
 			authzReq.ServiceNamespace, authzReq.ServiceName)
 		return buildDeniedResponse(code.Code_NOT_FOUND, typev3.StatusCode_NotFound, errorString)
+	case resp.RateLimited:
+		errorString := fmt.Sprintf(
+			"Rate limit exceeded for '%s/%s'.",
+			authzReq.ServiceNamespace, authzReq.ServiceName)
+		return buildDeniedResponse(code.Code_RESOURCE_EXHAUSTED, typev3.StatusCode_TooManyRequests, errorString)
 	case !resp.Allowed:
 		errorString := fmt.Sprintf(
 			"Permission denied for '%s/%s'.",
@@ -215,17 +284,18 @@ func (s *server) checkAuthorizationRequest(
 			{
 				Header: &corev3.HeaderValue{
 					Key:   api.AccessTokenHeader,
-					Value: resp.AccessToken,
+					Value: strings.Join(resp.AccessTokens, ","),
 				},
 			},
 		},
 	})
 }
 
-// RegisterService registers an ext_authz service backed by Manager to the given gRPC server.
+// RegisterService registers the ext_authz and access log services backed by Manager to the given gRPC server.
 func RegisterService(manager *Manager, grpcServer *grpc.Server) {
 	srv := newServer(manager)
 	authv3.RegisterAuthorizationServer(grpcServer, srv)
+	accesslogv3.RegisterAccessLogServiceServer(grpcServer, newAccessLogServer(manager))
 }
 
 func buildAllowedResponse(resp *authv3.OkHttpResponse) *authv3.CheckResponse {
@@ -0,0 +1,68 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+func TestIngressAccessTokenReplay(t *testing.T) {
+	exportName := types.NamespacedName{Namespace: "default", Name: "svc"}
+	m := newIngressManager(t, &v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: exportName.Namespace, Name: exportName.Name},
+		Spec:       v1alpha1.ExportSpec{Port: 80},
+	})
+	srv := newServer(m)
+
+	// checkConnect checks an ingress connection of a peer, authorized by the given access token
+	checkConnect := func(peerName, token string) *authv3.CheckResponse {
+		resp, err := srv.Check(context.Background(), &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Source: &authv3.AttributeContext_Peer{Principal: peerName},
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:  http.MethodConnect,
+						Headers: map[string]string{cpapi.AuthorizationHeader: bearerSchemaPrefix + token},
+					},
+				},
+			},
+		})
+		require.Nil(t, err)
+		return resp
+	}
+
+	// a token is accepted once
+	token := issueAccessToken(t, m, "peer1", exportName)
+	require.Equal(t, int32(code.Code_OK), checkConnect("peer1", token).Status.Code)
+
+	// using the same token again is rejected
+	resp := checkConnect("peer1", token)
+	require.Equal(t, int32(code.Code_PERMISSION_DENIED), resp.Status.Code)
+	require.Contains(t, resp.Status.Message, "already used")
+
+	// a token is not accepted from another peer
+	token = issueAccessToken(t, m, "peer1", exportName)
+	require.Equal(t, int32(code.Code_PERMISSION_DENIED), checkConnect("peer2", token).Status.Code)
+}
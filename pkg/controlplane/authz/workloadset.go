@@ -0,0 +1,47 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/controlplane/authz/connectivitypdp"
+)
+
+// AddWorkloadSet adds a workload set, to be referenced by access policies.
+func (m *Manager) AddWorkloadSet(workloadSet *connectivitypdp.WorkloadSet) error {
+	defer m.connectionsChanged()
+	defer m.decisionCache.Invalidate()
+	return m.connectivityPDP.AddOrUpdateWorkloadSet(workloadSet)
+}
+
+// DeleteWorkloadSet removes a workload set.
+func (m *Manager) DeleteWorkloadSet(name types.NamespacedName, privileged bool) error {
+	defer m.connectionsChanged()
+	defer m.decisionCache.Invalidate()
+	return m.connectivityPDP.DeleteWorkloadSet(name, privileged)
+}
+
+// updateWorkloadSetReferences updates the status of all access policies referencing a workload set.
+func (m *Manager) updateWorkloadSetReferences(ctx context.Context, name types.NamespacedName, privileged bool) error {
+	for _, policyName := range m.connectivityPDP.PoliciesReferencingWorkloadSet(name, privileged) {
+		if err := m.updateWorkloadSetsResolved(ctx, policyName, privileged); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
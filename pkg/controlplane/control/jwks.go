@@ -0,0 +1,295 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	// JWKCreatedParam is the (private) JWK parameter holding the creation time of a key.
+	JWKCreatedParam = "created_at"
+
+	// JWKActivationDelay is the time after its creation a key is first used for signing,
+	// allowing all controlplane instances to learn the key before accepting tokens signed by it.
+	JWKActivationDelay = 30 * time.Second
+	// JWKRetention is the time a key is still accepted for verifying tokens, once superseded by a newer key.
+	// Must exceed the lifetime of access tokens.
+	JWKRetention = 5 * time.Minute
+	// DefaultJWKRotationInterval is the default time between rotations of the signing key.
+	DefaultJWKRotationInterval = 24 * time.Hour
+
+	// jwksRetryInterval is the time after which a failed rotation of the JWKS secret is retried.
+	jwksRetryInterval = time.Minute
+)
+
+// jwksKey is a key of the JWKS secret.
+type jwksKey struct {
+	key     jwk.Key
+	created time.Time
+}
+
+// activation returns the time the key is first used for signing.
+func (k *jwksKey) activation() time.Time {
+	return k.created.Add(JWKActivationDelay)
+}
+
+// JWKS is the set of keys used for signing and verifying access tokens, ordered by their creation time.
+type JWKS struct {
+	keys []jwksKey
+}
+
+// SigningKey returns the key to sign new access tokens with:
+// the newest activated key, or the oldest key if none is activated yet.
+func (s *JWKS) SigningKey(now time.Time) jwk.Key {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].activation().After(now) {
+			return s.keys[i].key
+		}
+	}
+
+	return s.keys[0].key
+}
+
+// retirement returns the time the key at the given index is no longer accepted, once superseded by a newer key.
+// Returns false if the key is not superseded.
+func (s *JWKS) retirement(i int) (time.Time, bool) {
+	if i == len(s.keys)-1 {
+		return time.Time{}, false
+	}
+
+	return s.keys[i+1].activation().Add(JWKRetention), true
+}
+
+// VerificationKeys returns the public keys accepted for verifying access tokens: all non-retired keys.
+func (s *JWKS) VerificationKeys(now time.Time) (jwk.Set, error) {
+	set := jwk.NewSet()
+	for i := range s.keys {
+		if retirement, ok := s.retirement(i); ok && !retirement.After(now) {
+			continue
+		}
+
+		key, err := jwk.PublicKeyOf(s.keys[i].key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get public key: %w", err)
+		}
+		if err := key.Set(jwk.KeyIDKey, s.keys[i].key.KeyID()); err != nil {
+			return nil, err
+		}
+		if err := key.Set(jwk.AlgorithmKey, s.keys[i].key.Algorithm()); err != nil {
+			return nil, err
+		}
+		set.Add(key)
+	}
+
+	return set, nil
+}
+
+// rotate returns the key set following a rotation check at the given time, adding a new key
+// if the newest key is older than the rotation interval, and removing retired keys.
+// Also returns whether the key set changed, and the time of the next scheduled change.
+func (s *JWKS) rotate(now time.Time, interval time.Duration) (*JWKS, bool, time.Time, error) {
+	keys := slices.Clone(s.keys)
+	changed := false
+
+	if interval > 0 && !keys[len(keys)-1].created.Add(interval).After(now) {
+		key, err := generateJWK(now)
+		if err != nil {
+			return nil, false, time.Time{}, err
+		}
+		keys = append(keys, key)
+		changed = true
+	}
+
+	current := &JWKS{keys: keys}
+	rotated := &JWKS{}
+	var next time.Time
+	for i := range keys {
+		retirement, ok := current.retirement(i)
+		if ok && !retirement.After(now) {
+			changed = true
+			continue
+		}
+
+		rotated.keys = append(rotated.keys, keys[i])
+		if ok && (next.IsZero() || retirement.Before(next)) {
+			next = retirement
+		}
+	}
+
+	if interval > 0 {
+		nextRotation := keys[len(keys)-1].created.Add(interval)
+		if next.IsZero() || nextRotation.Before(next) {
+			next = nextRotation
+		}
+	}
+
+	return rotated, changed, next, nil
+}
+
+// marshal encodes the key set (including private keys) as a JWK set.
+func (s *JWKS) marshal() ([]byte, error) {
+	set := jwk.NewSet()
+	for _, key := range s.keys {
+		set.Add(key.key)
+	}
+
+	return json.Marshal(set)
+}
+
+// generateJWK generates a new signing key.
+func generateJWK(now time.Time) (jwksKey, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return jwksKey{}, fmt.Errorf("unable to generate JWK key: %w", err)
+	}
+
+	key, err := jwk.New(privateKey)
+	if err != nil {
+		return jwksKey{}, fmt.Errorf("unable to create JWK key: %w", err)
+	}
+
+	if err := jwk.AssignKeyID(key); err != nil {
+		return jwksKey{}, fmt.Errorf("unable to assign JWK key ID: %w", err)
+	}
+	if err := key.Set(jwk.AlgorithmKey, cpapi.JWTSignatureAlgorithm); err != nil {
+		return jwksKey{}, err
+	}
+
+	created := now.Truncate(time.Second)
+	if err := key.Set(JWKCreatedParam, created.Unix()); err != nil {
+		return jwksKey{}, err
+	}
+
+	return jwksKey{key: key, created: created}, nil
+}
+
+// generateJWKSecret generates the data of a new JWKS secret, holding a single key.
+func generateJWKSecret() ([]byte, error) {
+	key, err := generateJWK(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return (&JWKS{keys: []jwksKey{key}}).marshal()
+}
+
+// ParseJWKSSecret parses the JWKS secret.
+func ParseJWKSSecret(secret *v1.Secret) (*JWKS, error) {
+	data, ok := secret.Data[JWKSecretKeyName]
+	if !ok {
+		return nil, fmt.Errorf("secret missing %s key", JWKSecretKeyName)
+	}
+
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse JWK set: %w", err)
+	}
+
+	jwks := &JWKS{}
+	for i := range set.Len() {
+		key, _ := set.Get(i)
+		if key.KeyID() == "" || key.Algorithm() == "" {
+			return nil, fmt.Errorf("JWK key missing key ID or algorithm")
+		}
+
+		value, ok := key.Get(JWKCreatedParam)
+		if !ok {
+			return nil, fmt.Errorf("JWK key '%s' missing '%s' parameter", key.KeyID(), JWKCreatedParam)
+		}
+		created, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("invalid '%s' parameter of JWK key '%s'", JWKCreatedParam, key.KeyID())
+		}
+
+		jwks.keys = append(jwks.keys, jwksKey{key: key, created: time.Unix(int64(created), 0)})
+	}
+
+	if len(jwks.keys) == 0 {
+		return nil, fmt.Errorf("empty JWK set")
+	}
+
+	slices.SortStableFunc(jwks.keys, func(a, b jwksKey) int {
+		return a.created.Compare(b.created)
+	})
+
+	return jwks, nil
+}
+
+// SetJWKRotationInterval sets the time between rotations of the key signing access tokens.
+// A zero interval disables rotation.
+func (m *Manager) SetJWKRotationInterval(interval time.Duration) {
+	m.jwkRotationInterval = interval
+}
+
+// rotateJWKS rotates the keys of the JWKS secret if due, and schedules the next rotation.
+func (m *Manager) rotateJWKS(ctx context.Context, secret *v1.Secret, jwks *JWKS) error {
+	rotated, changed, next, err := jwks.rotate(time.Now(), m.jwkRotationInterval)
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		data, err := rotated.marshal()
+		if err != nil {
+			return fmt.Errorf("cannot encode JWK set: %w", err)
+		}
+
+		m.logger.Info("Rotating JWKS secret keys.")
+		updated := secret.DeepCopy()
+		updated.Data[JWKSecretKeyName] = data
+		// the updated secret is checked again, scheduling the next rotation
+		return m.client.Update(ctx, updated)
+	}
+
+	m.jwksTimerLock.Lock()
+	defer m.jwksTimerLock.Unlock()
+
+	if m.jwksTimer != nil {
+		m.jwksTimer.Stop()
+		m.jwksTimer = nil
+	}
+	if next.IsZero() {
+		return nil
+	}
+
+	m.scheduleJWKSCheck(types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}, time.Until(next))
+	return nil
+}
+
+// scheduleJWKSCheck schedules a check (and rotation) of the JWKS secret. Must be called with jwksTimerLock held.
+func (m *Manager) scheduleJWKSCheck(name types.NamespacedName, delay time.Duration) {
+	m.jwksTimer = time.AfterFunc(delay, func() {
+		if err := m.checkJWKSecret(context.Background(), name); err != nil {
+			m.logger.Errorf("Cannot rotate JWKS secret: %v.", err)
+
+			m.jwksTimerLock.Lock()
+			defer m.jwksTimerLock.Unlock()
+			m.scheduleJWKSCheck(name, jwksRetryInterval)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// requireVerifies checks whether a token signed by the given key set at signTime verifies at verifyTime.
+func requireVerifies(t *testing.T, jwks *JWKS, signTime, verifyTime time.Time, verifies bool) {
+	token, err := jwt.NewBuilder().Claim(cpapi.ExportNameJWTClaim, "svc").Build()
+	require.Nil(t, err)
+
+	key := jwks.SigningKey(signTime)
+	signed, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key)
+	require.Nil(t, err)
+
+	keys, err := jwks.VerificationKeys(verifyTime)
+	require.Nil(t, err)
+	_, err = jwt.Parse(signed, jwt.WithKeySet(keys))
+	require.Equal(t, verifies, err == nil)
+}
+
+func TestJWKSRotation(t *testing.T) {
+	data, err := generateJWKSecret()
+	require.Nil(t, err)
+	jwks, err := ParseJWKSSecret(&v1.Secret{Data: map[string][]byte{JWKSecretKeyName: data}})
+	require.Nil(t, err)
+	require.Len(t, jwks.keys, 1)
+
+	oldKey := jwks.keys[0]
+	require.NotEmpty(t, oldKey.key.KeyID())
+	require.Equal(t, cpapi.JWTSignatureAlgorithm.String(), oldKey.key.Algorithm())
+
+	// a new key is used immediately
+	created := oldKey.created
+	require.Equal(t, oldKey.key, jwks.SigningKey(created))
+
+	// no rotation before the interval passes
+	interval := time.Hour
+	rotated, changed, next, err := jwks.rotate(created.Add(time.Minute), interval)
+	require.Nil(t, err)
+	require.False(t, changed)
+	require.Equal(t, created.Add(interval), next)
+	require.Len(t, rotated.keys, 1)
+
+	// rotation adds a new key, used for signing once activated
+	rotationTime := created.Add(interval)
+	rotated, changed, next, err = jwks.rotate(rotationTime, interval)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Len(t, rotated.keys, 2)
+	newKey := rotated.keys[1]
+	require.NotEqual(t, oldKey.key.KeyID(), newKey.key.KeyID())
+	require.Equal(t, newKey.activation().Add(JWKRetention), next)
+
+	require.Equal(t, oldKey.key, rotated.SigningKey(rotationTime))
+	activationTime := newKey.activation()
+	require.Equal(t, newKey.key, rotated.SigningKey(activationTime))
+
+	// the rotated key set survives encoding
+	data, err = rotated.marshal()
+	require.Nil(t, err)
+	rotated, err = ParseJWKSSecret(&v1.Secret{Data: map[string][]byte{JWKSecretKeyName: data}})
+	require.Nil(t, err)
+	require.Len(t, rotated.keys, 2)
+
+	// tokens signed by the superseded key are accepted until it retires
+	retirementTime := activationTime.Add(JWKRetention)
+	requireVerifies(t, rotated, rotationTime, activationTime, true)
+	requireVerifies(t, rotated, rotationTime, retirementTime.Add(-time.Second), true)
+	requireVerifies(t, rotated, rotationTime, retirementTime, false)
+	requireVerifies(t, rotated, activationTime, retirementTime, true)
+
+	// retired keys are removed
+	pruned, changed, next, err := rotated.rotate(retirementTime, interval)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Len(t, pruned.keys, 1)
+	require.Equal(t, newKey.key.KeyID(), pruned.keys[0].key.KeyID())
+	require.Equal(t, newKey.created.Add(interval), next)
+
+	// no rotation if disabled
+	_, changed, next, err = pruned.rotate(retirementTime.Add(10*interval), 0)
+	require.Nil(t, err)
+	require.False(t, changed)
+	require.True(t, next.IsZero())
+}
+
+func TestParseJWKSSecret(t *testing.T) {
+	// the former symmetric key format
+	_, err := ParseJWKSSecret(&v1.Secret{Data: map[string][]byte{JWKSecretKeyName: []byte("c2VjcmV0")}})
+	require.NotNil(t, err)
+
+	_, err = ParseJWKSSecret(&v1.Secret{})
+	require.NotNil(t, err)
+}
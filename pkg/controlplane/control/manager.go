@@ -18,14 +18,13 @@ import (
 
 	//nolint:gosec // G505: use of weak cryptographic primitive is fine for service name
 	"crypto/md5"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
@@ -148,6 +147,11 @@ type Manager struct {
 	lock            sync.Mutex
 	serviceToImport map[string]types.NamespacedName
 
+	// jwkRotationInterval is the time between rotations of the key signing access tokens
+	jwkRotationInterval time.Duration
+	jwksTimerLock       sync.Mutex
+	jwksTimer           *time.Timer
+
 	logger *logrus.Entry
 }
 
@@ -234,13 +238,7 @@ func (m *Manager) addImport(ctx context.Context, imp *v1alpha1.Import) (err erro
 			Labels:    make(map[string]string),
 		},
 		Spec: v1.ServiceSpec{
-			Ports: []v1.ServicePort{
-				{
-					Protocol:   v1.ProtocolTCP,
-					Port:       int32(imp.Spec.Port),
-					TargetPort: intstr.FromInt32(int32(imp.Spec.TargetPort)),
-				},
-			},
+			Ports:    servicePorts(imp),
 			Selector: map[string]string{"app": api.Name},
 			Type:     v1.ServiceTypeClusterIP,
 		},
@@ -404,15 +402,16 @@ func (m *Manager) checkJWKSecret(ctx context.Context, name types.NamespacedName)
 
 	var secret v1.Secret
 	create := true
-	if err := m.client.Get(ctx, secretName, &secret); err == nil {
+	if err := m.client.Get(ctx, secretName, &secret); err != nil {
 		if !k8serrors.IsNotFound(err) {
 			return err
 		}
 
 		m.logger.Info("No JWKS secret defined.")
 	} else {
-		if _, err := ParseJWKSSecret(&secret); err == nil {
-			return nil
+		jwks, err := ParseJWKSSecret(&secret)
+		if err == nil {
+			return m.rotateJWKS(ctx, &secret, jwks)
 		}
 
 		create = false
@@ -441,6 +440,7 @@ func (m *Manager) checkJWKSecret(ctx context.Context, name types.NamespacedName)
 	}
 
 	m.logger.Infof("Updating JWK Secret.")
+	newSecret.ResourceVersion = secret.ResourceVersion
 	return m.client.Update(ctx, newSecret)
 }
 
@@ -561,15 +561,32 @@ func (m *Manager) allocateTargetPort(ctx context.Context, imp *v1alpha1.Import)
 		Name:      imp.Name,
 	}
 
-	leasedPort, err := m.ports.Lease(name, imp.Spec.TargetPort)
-	if err != nil {
-		return fmt.Errorf("cannot generate listening port: %w", err)
+	updated := false
+	portNames := make(map[string]bool)
+	for i, port := range imp.Spec.ServicePorts() {
+		leasedPort, err := m.ports.Lease(name, port.Name, port.TargetPort)
+		if err != nil {
+			return fmt.Errorf("cannot generate listening port: %w", err)
+		}
+
+		portNames[port.Name] = true
+		if port.TargetPort != 0 {
+			continue
+		}
+
+		if len(imp.Spec.Ports) > 0 {
+			imp.Spec.Ports[i].TargetPort = leasedPort
+		} else {
+			imp.Spec.TargetPort = leasedPort
+		}
+		updated = true
 	}
 
-	if imp.Spec.TargetPort == 0 {
-		imp.Spec.TargetPort = leasedPort
+	// release ports of removed import ports
+	m.ports.Retain(name, portNames)
 
-		m.logger.Infof("Updating target port for import %v.", name)
+	if updated {
+		m.logger.Infof("Updating target ports for import %v.", name)
 		if err := m.client.Update(ctx, imp); err != nil {
 			m.ports.Release(name)
 			return err
@@ -737,8 +754,20 @@ func (m *Manager) checkImportEndpointSlice(
 		importName:                 imp.Name,
 		dataplaneEndpointSliceName: dataplaneEndpointSlice.Name,
 	}).Get()
-	protocol := v1.ProtocolTCP
-	port32 := int32(imp.Spec.TargetPort)
+	protocol := serviceProtocol(imp.Spec.Protocol)
+	var ports []discv1.EndpointPort
+	for _, port := range imp.Spec.ServicePorts() {
+		name := port.Name
+		port32 := int32(port.TargetPort)
+		endpointPort := discv1.EndpointPort{
+			Port:     &port32,
+			Protocol: &protocol,
+		}
+		if name != "" {
+			endpointPort.Name = &name
+		}
+		ports = append(ports, endpointPort)
+	}
 
 	importEndpointSlice := discv1.EndpointSlice{
 		ObjectMeta: metav1.ObjectMeta{
@@ -752,12 +781,7 @@ func (m *Manager) checkImportEndpointSlice(
 		},
 		AddressType: discv1.AddressTypeIPv4,
 		Endpoints:   dataplaneEndpointSlice.Endpoints,
-		Ports: []discv1.EndpointPort{
-			{
-				Port:     &port32,
-				Protocol: &protocol,
-			},
-		},
+		Ports:       ports,
 	}
 
 	var oldImportEndpointSlice discv1.EndpointSlice
@@ -874,14 +898,28 @@ func (m *Manager) CreateJWKSSecret(ctx context.Context) error {
 	return nil
 }
 
-func generateJWKSecret() ([]byte, error) {
-	keyBytes := make([]byte, 32)
-	_, err := rand.Read(keyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("unable to generate JWK key: %w", err)
+// servicePorts returns the k8s service ports of an imported service.
+func servicePorts(imp *v1alpha1.Import) []v1.ServicePort {
+	var ports []v1.ServicePort
+	for _, port := range imp.Spec.ServicePorts() {
+		ports = append(ports, v1.ServicePort{
+			Name:       port.Name,
+			Protocol:   serviceProtocol(imp.Spec.Protocol),
+			Port:       int32(port.Port),
+			TargetPort: intstr.FromInt32(int32(port.TargetPort)),
+		})
+	}
+
+	return ports
+}
+
+// serviceProtocol returns the k8s service protocol matching a shared service protocol.
+func serviceProtocol(protocol v1alpha1.Protocol) v1.Protocol {
+	if protocol == v1alpha1.ProtocolUDP {
+		return v1.ProtocolUDP
 	}
 
-	return []byte(base64.StdEncoding.EncodeToString(keyBytes)), nil
+	return v1.ProtocolTCP
 }
 
 func checkServiceLabels(service *v1.Service, importName types.NamespacedName) bool {
@@ -935,6 +973,10 @@ func serviceChanged(svc1, svc2 *v1.Service) bool {
 	}
 
 	for i := 0; i < len(svc1.Spec.Ports); i++ {
+		if svc1.Spec.Ports[i].Name != svc2.Spec.Ports[i].Name {
+			return true
+		}
+
 		if svc1.Spec.Ports[i].Protocol != svc2.Spec.Ports[i].Protocol {
 			return true
 		}
@@ -985,6 +1027,16 @@ func endpointSliceChanged(endpointSlice1, endpointSlice2 *discv1.EndpointSlice)
 		return true
 	}
 
+	if len(endpointSlice1.Ports) != len(endpointSlice2.Ports) {
+		return true
+	}
+
+	for i := range endpointSlice1.Ports {
+		if !reflect.DeepEqual(endpointSlice1.Ports[i], endpointSlice2.Ports[i]) {
+			return true
+		}
+	}
+
 	if len(endpointSlice1.Endpoints) != len(endpointSlice2.Endpoints) {
 		return true
 	}
@@ -1020,30 +1072,17 @@ func endpointSliceChanged(endpointSlice1, endpointSlice2 *discv1.EndpointSlice)
 	return false
 }
 
-func ParseJWKSSecret(secret *v1.Secret) ([]byte, error) {
-	keyBase64, ok := secret.Data[JWKSecretKeyName]
-	if !ok {
-		return nil, fmt.Errorf("secret missing %s key", JWKSecretKeyName)
-	}
-
-	keyBytes, err := base64.StdEncoding.DecodeString(string(keyBase64))
-	if err != nil {
-		return nil, fmt.Errorf("cannot base64 decode key")
-	}
-
-	return keyBytes, nil
-}
-
 // NewManager returns a new control manager.
 func NewManager(cl client.Client, namespace string) *Manager {
 	logger := logrus.WithField("component", "controlplane.control.manager")
 
 	return &Manager{
-		peerManager:     newPeerManager(cl),
-		client:          cl,
-		namespace:       namespace,
-		ports:           newPortManager(),
-		serviceToImport: make(map[string]types.NamespacedName),
-		logger:          logger,
+		peerManager:         newPeerManager(cl),
+		client:              cl,
+		namespace:           namespace,
+		ports:               newPortManager(),
+		serviceToImport:     make(map[string]types.NamespacedName),
+		jwkRotationInterval: DefaultJWKRotationInterval,
+		logger:              logger,
 	}
 }
@@ -0,0 +1,60 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "clusterlink"
+
+var (
+	peerReachableMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "peer",
+		Name:      "reachable",
+		Help:      "Whether a remote peer is reachable (1) or not (0), as determined by heartbeats.",
+	}, []string{"peer"})
+
+	heartbeatLatencyMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "peer",
+		Name:      "heartbeat_latency_seconds",
+		Help:      "Latency of successful heartbeats to remote peers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"peer"})
+
+	heartbeatFailuresMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "peer",
+		Name:      "heartbeat_failures_total",
+		Help:      "Number of failed heartbeats to remote peers.",
+	}, []string{"peer"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		peerReachableMetric,
+		heartbeatLatencyMetric,
+		heartbeatFailuresMetric,
+	)
+}
+
+// deletePeerMetrics removes the metrics of a remote peer.
+func deletePeerMetrics(name string) {
+	peerReachableMetric.DeleteLabelValues(name)
+	heartbeatLatencyMetric.DeleteLabelValues(name)
+	heartbeatFailuresMetric.DeleteLabelValues(name)
+}
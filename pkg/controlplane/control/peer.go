@@ -106,6 +106,7 @@ func (m *peerMonitor) Start() {
 	defer ticker.Stop()
 
 	healthy := meta.IsStatusConditionTrue(m.pr.Status.Conditions, v1alpha1.PeerReachable)
+	peerReachableMetric.WithLabelValues(m.pr.Name).Set(boolToFloat(healthy))
 	strikeCount := 0
 	threshold := 1 // require a single request on startup
 	reachableCond := metav1.Condition{
@@ -121,8 +122,14 @@ func (m *peerMonitor) Start() {
 			break
 		}
 
+		heartbeatStart := time.Now()
 		peerLabels, heartbeatErr := m.getClient().GetHeartbeat()
 		heartbeatOK := heartbeatErr == nil
+		if heartbeatOK {
+			heartbeatLatencyMetric.WithLabelValues(m.pr.Name).Observe(time.Since(heartbeatStart).Seconds())
+		} else {
+			heartbeatFailuresMetric.WithLabelValues(m.pr.Name).Inc()
+		}
 		if healthy == heartbeatOK {
 			if !healthy {
 				ticker.Reset(unhealthyInterval)
@@ -156,6 +163,7 @@ func (m *peerMonitor) Start() {
 
 		strikeCount = 0
 		healthy = heartbeatOK
+		peerReachableMetric.WithLabelValues(m.pr.Name).Set(boolToFloat(healthy))
 
 		m.lock.Lock()
 		meta.SetStatusCondition(&m.pr.Status.Conditions, reachableCond)
@@ -173,6 +181,15 @@ func (m *peerMonitor) Stop() {
 	close(m.stopCh)
 }
 
+// boolToFloat returns 1 for true, and 0 for false.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
 // AddPeer defines a new route target for egress dataplane connections.
 func (m *peerManager) AddPeer(pr *v1alpha1.Peer) {
 	m.logger.Infof("Adding peer '%s'.", pr.Name)
@@ -201,7 +218,12 @@ func (m *peerManager) DeletePeer(name string) {
 
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	delete(m.monitors, name)
+
+	if monitor, ok := m.monitors[name]; ok {
+		monitor.Stop()
+		delete(m.monitors, name)
+	}
+	deletePeerMetrics(name)
 }
 
 // Name of the peer monitor runnable.
@@ -52,10 +52,11 @@ func (e conflictingTargetPortError) Is(target error) bool {
 }
 
 // portManager leases ports for use by imported services.
+// Each port of an imported service (identified by its port name) is leased a separate port.
 type portManager struct {
 	lock         sync.Mutex
 	leasesByPort map[uint16]types.NamespacedName
-	leasesByName map[types.NamespacedName]uint16
+	leasesByName map[types.NamespacedName]map[string]uint16
 
 	logger *logrus.Entry
 }
@@ -91,26 +92,26 @@ func (m *portManager) getRandomFreePort() uint16 {
 	return port
 }
 
-// Lease marks a port as taken by the given name. If port is 0, some random free port is returned.
-func (m *portManager) Lease(name types.NamespacedName, port uint16) (uint16, error) {
-	m.logger.Infof("Leasing %d for %v.", port, name)
+// Lease marks a port as taken by the given name and port name. If port is 0, some random free port is returned.
+func (m *portManager) Lease(name types.NamespacedName, portName string, port uint16) (uint16, error) {
+	m.logger.Infof("Leasing %d for %v (port name: '%s').", port, name, portName)
 
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
 	if port == 0 {
-		return m.leaseWithRandomPort(name)
+		return m.leaseWithRandomPort(name, portName)
 	}
-	return m.leaseWithSpecificPort(name, port)
+	return m.leaseWithSpecificPort(name, portName, port)
 }
 
 // Lease random port.
-func (m *portManager) leaseWithRandomPort(name types.NamespacedName) (uint16, error) {
+func (m *portManager) leaseWithRandomPort(name types.NamespacedName, portName string) (uint16, error) {
 	if len(m.leasesByPort) == int(portCount) {
 		return 0, fmt.Errorf("all ports are taken")
 	}
 
-	if port := m.leasesByName[name]; port != 0 {
+	if port := m.leasesByName[name][portName]; port != 0 {
 		m.logger.Infof("Leased existing: %d.", port)
 		return port, nil
 	}
@@ -118,50 +119,67 @@ func (m *portManager) leaseWithRandomPort(name types.NamespacedName) (uint16, er
 	port := m.getRandomFreePort()
 	m.logger.Infof("Generated port: %d.", port)
 
-	// Mark previous port (if exists) as free
-	if oldPort, ok := m.leasesByName[name]; ok {
-		delete(m.leasesByPort, oldPort)
-	}
-
-	// Mark port as leased
-	m.leasesByPort[port] = name
-	m.leasesByName[name] = port
-
+	m.setLease(name, portName, port)
 	return port, nil
 }
 
 // Lease specific port.
-func (m *portManager) leaseWithSpecificPort(name types.NamespacedName, port uint16) (uint16, error) {
-	if leaseName, ok := m.leasesByPort[port]; ok && leaseName != name {
+func (m *portManager) leaseWithSpecificPort(name types.NamespacedName, portName string, port uint16) (uint16, error) {
+	if leaseName, ok := m.leasesByPort[port]; ok &&
+		(leaseName != name || m.leasesByName[name][portName] != port) {
 		return 0, conflictingTargetPortError{
 			port:      port,
 			leaseName: leaseName,
 		}
 	}
 
-	// Mark previous port (if exists) as free
-	if oldPort, ok := m.leasesByName[name]; ok {
+	m.setLease(name, portName, port)
+	return port, nil
+}
+
+// setLease marks a port as leased, freeing the previous port (if exists) leased by the same name and port name.
+func (m *portManager) setLease(name types.NamespacedName, portName string, port uint16) {
+	ports, ok := m.leasesByName[name]
+	if !ok {
+		ports = make(map[string]uint16)
+		m.leasesByName[name] = ports
+	}
+
+	if oldPort, ok := ports[portName]; ok {
 		delete(m.leasesByPort, oldPort)
 	}
 
-	// Mark port as leased
 	m.leasesByPort[port] = name
-	m.leasesByName[name] = port
+	ports[portName] = port
+}
 
-	return port, nil
+// Retain returns all ports leased by the given name, except for the given port names, to be re-used by others.
+func (m *portManager) Retain(name types.NamespacedName, portNames map[string]bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for portName, port := range m.leasesByName[name] {
+		if portNames[portName] {
+			continue
+		}
+
+		m.logger.Infof("Returning port %d for: '%v' (port name: '%s').", port, name, portName)
+		delete(m.leasesByName[name], portName)
+		delete(m.leasesByPort, port)
+	}
 }
 
-// Release returns a leased port to be re-used by others.
+// Release returns all ports leased by the given name to be re-used by others.
 func (m *portManager) Release(name types.NamespacedName) {
-	m.logger.Infof("Returning port for: '%v'.", name)
+	m.logger.Infof("Returning ports for: '%v'.", name)
 
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	if port, ok := m.leasesByName[name]; ok {
-		delete(m.leasesByName, name)
+	for _, port := range m.leasesByName[name] {
 		delete(m.leasesByPort, port)
 	}
+	delete(m.leasesByName, name)
 }
 
 // newPortManager returns a new empty portManager.
@@ -176,7 +194,7 @@ func newPortManager() *portManager {
 
 	return &portManager{
 		leasesByPort: make(map[uint16]types.NamespacedName),
-		leasesByName: make(map[types.NamespacedName]uint16),
+		leasesByName: make(map[types.NamespacedName]map[string]uint16),
 		logger:       logger,
 	}
 }
@@ -0,0 +1,70 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPortManager(t *testing.T) {
+	m := newPortManager()
+	svc1 := types.NamespacedName{Namespace: "default", Name: "svc1"}
+	svc2 := types.NamespacedName{Namespace: "default", Name: "svc2"}
+
+	// each port name of a service is leased a separate port
+	http, err := m.Lease(svc1, "http", 0)
+	require.Nil(t, err)
+	require.GreaterOrEqual(t, http, startPort)
+	require.Less(t, http, endPort)
+	metrics, err := m.Lease(svc1, "metrics", 0)
+	require.Nil(t, err)
+	require.NotEqual(t, http, metrics)
+
+	// leasing again returns the existing port
+	port, err := m.Lease(svc1, "http", 0)
+	require.Nil(t, err)
+	require.Equal(t, http, port)
+	port, err = m.Lease(svc1, "http", http)
+	require.Nil(t, err)
+	require.Equal(t, http, port)
+
+	// a port leased by another service, or by another port name of the same service, conflicts
+	_, err = m.Lease(svc2, "http", http)
+	require.ErrorIs(t, err, &conflictingTargetPortError{})
+	_, err = m.Lease(svc1, "metrics", http)
+	require.ErrorIs(t, err, &conflictingTargetPortError{})
+
+	// leasing a specific port frees the previously leased port
+	_, err = m.Lease(svc1, "metrics", 2000)
+	require.Nil(t, err)
+	port, err = m.Lease(svc2, "http", metrics)
+	require.Nil(t, err)
+	require.Equal(t, metrics, port)
+
+	// retaining frees the ports of the other port names
+	m.Retain(svc1, map[string]bool{"http": true})
+	require.Equal(t, map[string]uint16{"http": http}, m.leasesByName[svc1])
+	port, err = m.Lease(svc2, "metrics", 2000)
+	require.Nil(t, err)
+	require.Equal(t, uint16(2000), port)
+
+	// releasing frees all ports
+	m.Release(svc1)
+	m.Release(svc2)
+	require.Empty(t, m.leasesByName)
+	require.Empty(t, m.leasesByPort)
+}
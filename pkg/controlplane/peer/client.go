@@ -21,13 +21,17 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
 	"github.com/clusterlink-net/clusterlink/pkg/util/jsonapi"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 // Client for accessing a remote peer.
@@ -95,26 +99,31 @@ func (c *Client) getResponse(
 	return nil, retErr
 }
 
-// Authorize a request for accessing a peer exported service, yielding an access token.
-func (c *Client) Authorize(ctx context.Context, req *api.AuthorizationRequest) (string, error) {
+// Authorize a request for accessing a peer exported service, yielding one or more single-use access tokens.
+func (c *Client) Authorize(ctx context.Context, req *api.AuthorizationRequest) (tokens []string, err error) {
+	ctx, span := tracing.Start(ctx, "peer.Authorize", trace.WithAttributes(
+		attribute.String("clusterlink.peer", c.pr.Name),
+		attribute.String("clusterlink.export", req.ServiceNamespace+"/"+req.ServiceName)))
+	defer func() { tracing.End(span, err) }()
+
 	body, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("unable to serialize authorization request: %w", err)
+		return nil, fmt.Errorf("unable to serialize authorization request: %w", err)
 	}
 
 	serverResp, err := c.getResponse(func(client *jsonapi.Client) (*jsonapi.Response, error) {
 		return client.Post(ctx, api.RemotePeerAuthorizationPath, body)
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if serverResp.Status != http.StatusOK {
-		return "", fmt.Errorf("unable to authorize connection (%d), server returned: %s",
+		return nil, fmt.Errorf("unable to authorize connection (%d), server returned: %s",
 			serverResp.Status, serverResp.Body)
 	}
 
-	return serverResp.Headers.Get(api.AccessTokenHeader), nil
+	return strings.Split(serverResp.Headers.Get(api.AccessTokenHeader), ","), nil
 }
 
 // GetHeartbeat get a heartbeat from other peers.
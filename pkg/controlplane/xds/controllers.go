@@ -16,6 +16,7 @@ package xds
 import (
 	"context"
 
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -53,7 +54,7 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 		return err
 	}
 
-	return controller.AddToManager(controllerManager, &controller.Spec{
+	err = controller.AddToManager(controllerManager, &controller.Spec{
 		Name:   "xds.import",
 		Object: &v1alpha1.Import{},
 		AddHandler: func(ctx context.Context, object any) error {
@@ -63,4 +64,18 @@ func CreateControllers(mgr *Manager, controllerManager ctrl.Manager) error {
 			return mgr.DeleteImport(name)
 		},
 	})
+	if err != nil {
+		return err
+	}
+
+	return controller.AddToManager(controllerManager, &controller.Spec{
+		Name:   "xds.pod",
+		Object: &v1.Pod{},
+		AddHandler: func(ctx context.Context, object any) error {
+			return mgr.AddPod(object.(*v1.Pod))
+		},
+		DeleteHandler: func(ctx context.Context, name types.NamespacedName) error {
+			return mgr.DeletePod(name)
+		},
+	})
 }
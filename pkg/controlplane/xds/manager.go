@@ -14,22 +14,33 @@
 package xds
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
 	"time"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	udpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
 	getaddrinfo "github.com/envoyproxy/go-control-plane/envoy/extensions/network/dns_resolver/getaddrinfo/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -38,17 +49,50 @@ import (
 	utiltls "github.com/clusterlink-net/clusterlink/pkg/util/tls"
 )
 
+const (
+	// egressRouterHost is the hostname used for tunneling connections through the egress router.
+	egressRouterHost = "egress-router"
+	// egressRouterPort is the port used for tunneling connections through the egress router.
+	egressRouterPort = 443
+	// udpProxyFilterName is the name of the envoy UDP proxy listener filter.
+	udpProxyFilterName = "envoy.filters.udp_listener.udp_proxy"
+	// forwardedHostHeader holds the original host of HTTP requests, as the host header is used for routing.
+	forwardedHostHeader = "x-forwarded-host"
+	// httpRetryOn are the conditions for retrying HTTP requests of imported services.
+	httpRetryOn = "5xx,reset,connect-failure"
+	// defaultFilterChainName is the name of the filter chain of TCP import listeners matching clients
+	// with no filter chain of their own. Client filter chains are named by the client IP address.
+	defaultFilterChainName = "default"
+)
+
 // Manager manages the core routing components of the dataplane.
 // It maps the following controlplane types to xDS types:
 // - Peer -> Cluster (whose name starts with a designated prefix)
 // - Export -> Cluster (whose name starts with a designated prefix)
 // - Import -> Listener (whose name starts with a designated prefix)
+// - Pod -> Filter chain of the TCP import listeners (per pod IP address)
 // Note that imported service bindings are handled by the egress authz server.
 type Manager struct {
 	clusters  *cache.LinearCache
 	listeners *cache.LinearCache
 	secrets   *cache.LinearCache
 
+	// importsLock guards imports and podIPs, and serializes the updates of import listeners
+	importsLock sync.Mutex
+	// imports are the imports with listeners, keyed by their name
+	imports map[types.NamespacedName]*v1alpha1.Import
+	// podIPs are the IP addresses of the client pods, each having its own filter chain in the TCP import listeners
+	podIPs map[types.NamespacedName][]string
+
+	// revocationGracePeriod is the time connections of deleted imports, exports and peers may stay open
+	revocationGracePeriod time.Duration
+	revocationLock        sync.Mutex
+	// revoked are the revoked connections, keyed by their import listener (or export cluster) name,
+	// and by their listener filter chain name (empty for connections of the listener or cluster itself)
+	revoked map[string]map[string][]cpapi.ConnectionID
+	// deletions are the pending removals of resources, keyed by their service (or peer) resource name
+	deletions map[string]*time.Timer
+
 	logger *logrus.Entry
 }
 
@@ -57,7 +101,9 @@ func (m *Manager) AddPeer(peer *v1alpha1.Peer) error {
 	m.logger.Infof("Adding peer '%s'.", peer.Name)
 
 	clusterName := cpapi.RemotePeerClusterName(peer.Name)
-	epc, err := makeEndpointsCluster(clusterName, peer.Spec.Gateways, peer.Name+":443")
+	m.cancelDeletion(clusterName)
+
+	epc, err := makeEndpointsCluster(clusterName, peer.Spec.Gateways, peer.Name+":443", core.SocketAddress_TCP)
 	if err != nil {
 		return err
 	}
@@ -103,8 +149,7 @@ func (m *Manager) AddPeer(peer *v1alpha1.Peer) error {
 func (m *Manager) DeletePeer(name string) error {
 	m.logger.Infof("Deleting peer '%s'.", name)
 
-	clusterName := cpapi.RemotePeerClusterName(name)
-	return m.clusters.DeleteResource(clusterName)
+	return m.deleteResources(m.clusters, cpapi.RemotePeerClusterName(name))
 }
 
 // AddExport defines a new route target for ingress dataplane connections.
@@ -116,24 +161,47 @@ func (m *Manager) AddExport(export *v1alpha1.Export) error {
 		host = fmt.Sprintf("%s.%s.svc.cluster.local", export.Name, export.Namespace)
 	}
 
-	clusterName := cpapi.ExportClusterName(export.Name, export.Namespace)
-	cc, err := makeAddressCluster(
-		clusterName,
-		host,
-		export.Spec.Port, "")
-	if err != nil {
-		return err
+	exportClusterName := cpapi.ExportClusterName(export.Name, export.Namespace, "")
+	m.cancelDeletion(exportClusterName)
+
+	clusters := make(map[string]cachetypes.Resource)
+	for _, port := range export.Spec.ServicePorts() {
+		clusterName := cpapi.ExportClusterName(export.Name, export.Namespace, port.Name)
+		cc, err := makeAddressCluster(
+			clusterName,
+			host,
+			port.Port, "",
+			socketProtocol(export.Spec.Protocol))
+		if err != nil {
+			return err
+		}
+
+		if rateLimit := export.Spec.RateLimit; rateLimit != nil && rateLimit.MaxConnections > 0 {
+			cc.CircuitBreakers = &cluster.CircuitBreakers{
+				Thresholds: []*cluster.CircuitBreakers_Thresholds{{
+					MaxConnections: wrapperspb.UInt32(rateLimit.MaxConnections),
+					MaxRequests:    wrapperspb.UInt32(rateLimit.MaxConnections),
+				}},
+			}
+		}
+
+		if revoked := m.revokedConnections(clusterName)[""]; len(revoked) > 0 {
+			if cc.Metadata, err = revocationMetadata(revoked); err != nil {
+				return err
+			}
+		}
+
+		clusters[clusterName] = cc
 	}
 
-	return m.clusters.UpdateResource(clusterName, cc)
+	return m.clusters.UpdateResources(clusters, staleResources(m.clusters, exportClusterName, clusters))
 }
 
 // DeleteExport removes the possibility for ingress dataplane connections to access a given service.
 func (m *Manager) DeleteExport(name types.NamespacedName) error {
 	m.logger.Infof("Deleting export '%v'.", name)
 
-	clusterName := cpapi.ExportClusterName(name.Name, name.Namespace)
-	return m.clusters.DeleteResource(clusterName)
+	return m.deleteResources(m.clusters, cpapi.ExportClusterName(name.Name, name.Namespace, ""))
 }
 
 // AddImport adds a listening socket for an imported remote service.
@@ -146,12 +214,28 @@ func (m *Manager) AddImport(imp *v1alpha1.Import) error {
 		return nil
 	}
 
-	listenerName := cpapi.ImportListenerName(imp.Name, imp.Namespace)
-	egressRouterHostname := "egress-router:443"
+	m.importsLock.Lock()
+	defer m.importsLock.Unlock()
 
-	tunnelingConfig := &tcpproxy.TcpProxy_TunnelingConfig{
-		Hostname: egressRouterHostname,
-		HeadersToAdd: []*core.HeaderValueOption{
+	m.imports[types.NamespacedName{Namespace: imp.Namespace, Name: imp.Name}] = imp
+	return m.updateImportListeners(imp, m.clientIPs())
+}
+
+// updateImportListeners updates the listeners of an import, with filter chains for the given client IP addresses.
+// Must be called with importsLock held.
+func (m *Manager) updateImportListeners(imp *v1alpha1.Import, clientIPs []string) error {
+	importListenerName := cpapi.ImportListenerName(imp.Name, imp.Namespace, "")
+	m.cancelDeletion(importListenerName)
+
+	listeners := make(map[string]cachetypes.Resource)
+	for _, port := range imp.Spec.ServicePorts() {
+		if port.TargetPort == 0 {
+			// target port not yet allocated, skip
+			continue
+		}
+
+		listenerName := cpapi.ImportListenerName(imp.Name, imp.Namespace, port.Name)
+		headersToAdd := []*core.HeaderValueOption{
 			{
 				Header: &core.HeaderValue{
 					Key:   cpapi.ImportNameHeader,
@@ -166,6 +250,13 @@ func (m *Manager) AddImport(imp *v1alpha1.Import) error {
 				},
 				KeepEmptyValue: true,
 			},
+			{
+				Header: &core.HeaderValue{
+					Key:   cpapi.ImportPortHeader,
+					Value: port.Name,
+				},
+				KeepEmptyValue: true,
+			},
 			{
 				Header: &core.HeaderValue{
 					Key:   cpapi.ClientIPHeader,
@@ -173,42 +264,122 @@ func (m *Manager) AddImport(imp *v1alpha1.Import) error {
 				},
 				KeepEmptyValue: true,
 			},
-		},
-	}
+		}
 
-	tcpProxyFilter, err := makeTCPProxyFilter(
-		cpapi.EgressRouterCluster, imp.Name, tunnelingConfig)
-	if err != nil {
-		return err
-	}
+		var ln *listener.Listener
+		var err error
+		switch {
+		case socketProtocol(imp.Spec.Protocol) == core.SocketAddress_UDP:
+			ln, err = makeUDPImportListener(listenerName, imp, &port, headersToAdd)
+		case imp.Spec.AppProtocol == v1alpha1.AppProtocolHTTP:
+			ln, err = makeHTTPImportListener(listenerName, imp, &port, headersToAdd)
+		default:
+			ln, err = makeTCPImportListener(listenerName, imp, &port, headersToAdd, clientIPs)
+		}
+		if err != nil {
+			return err
+		}
 
-	// TODO: listen on a more specific address (i.e. not 0.0.0.0)
-	ln := &listener.Listener{
-		Name: listenerName,
-		Address: &core.Address{
-			Address: &core.Address_SocketAddress{
-				SocketAddress: &core.SocketAddress{
-					Address: "0.0.0.0",
-					PortSpecifier: &core.SocketAddress_PortValue{
-						PortValue: uint32(imp.Spec.TargetPort),
-					},
-				},
-			},
-		},
-		FilterChains: []*listener.FilterChain{{
-			Filters: []*listener.Filter{tcpProxyFilter},
-		}},
+		if err := setListenerRevocationMetadata(ln, m.revokedConnections(listenerName)); err != nil {
+			return err
+		}
+
+		listeners[listenerName] = ln
 	}
 
-	return m.listeners.UpdateResource(listenerName, ln)
+	return m.listeners.UpdateResources(listeners, staleResources(m.listeners, importListenerName, listeners))
 }
 
 // DeleteImport removes the listening socket of a previously imported service.
 func (m *Manager) DeleteImport(name types.NamespacedName) error {
 	m.logger.Infof("Deleting import '%v'.", name)
 
-	listenerName := cpapi.ImportListenerName(name.Name, name.Namespace)
-	return m.listeners.DeleteResource(listenerName)
+	m.importsLock.Lock()
+	delete(m.imports, name)
+	m.importsLock.Unlock()
+
+	return m.deleteResources(m.listeners, cpapi.ImportListenerName(name.Name, name.Namespace, ""))
+}
+
+// AddPod adds or updates the IP addresses of a client pod, adding their filter chains to the TCP import listeners.
+func (m *Manager) AddPod(pod *v1.Pod) error {
+	var ips []string
+	for _, ip := range pod.Status.PodIPs {
+		// ignoring host-networked Pod IPs
+		if ip.IP != pod.Status.HostIP {
+			ips = append(ips, ip.IP)
+		}
+	}
+
+	m.importsLock.Lock()
+	defer m.importsLock.Unlock()
+
+	name := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	if slices.Equal(m.podIPs[name], ips) {
+		return nil
+	}
+
+	if len(ips) == 0 {
+		delete(m.podIPs, name)
+	} else {
+		m.podIPs[name] = ips
+	}
+
+	return m.updateClientFilterChains()
+}
+
+// DeletePod removes the filter chains of the IP addresses of a deleted client pod from the TCP import listeners.
+func (m *Manager) DeletePod(name types.NamespacedName) error {
+	m.importsLock.Lock()
+	defer m.importsLock.Unlock()
+
+	ips, ok := m.podIPs[name]
+	if !ok {
+		return nil
+	}
+	delete(m.podIPs, name)
+
+	m.revocationLock.Lock()
+	for _, revoked := range m.revoked {
+		for _, ip := range ips {
+			delete(revoked, ip)
+		}
+	}
+	m.revocationLock.Unlock()
+
+	return m.updateClientFilterChains()
+}
+
+// updateClientFilterChains updates the TCP import listeners following a change to the client IP addresses.
+// Filter chains of other clients are unchanged, hence their connections are not drained.
+// Must be called with importsLock held.
+func (m *Manager) updateClientFilterChains() error {
+	clientIPs := m.clientIPs()
+
+	var errs []error
+	for _, imp := range m.imports {
+		if socketProtocol(imp.Spec.Protocol) == core.SocketAddress_UDP || imp.Spec.AppProtocol == v1alpha1.AppProtocolHTTP {
+			continue
+		}
+
+		if err := m.updateImportListeners(imp, clientIPs); err != nil {
+			errs = append(errs, fmt.Errorf("cannot update listeners of import '%s/%s': %w", imp.Namespace, imp.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// clientIPs returns the sorted IP addresses of the client pods.
+// Must be called with importsLock held.
+func (m *Manager) clientIPs() []string {
+	var ips []string
+	for _, podIPs := range m.podIPs {
+		ips = append(ips, podIPs...)
+	}
+
+	slices.Sort(ips)
+	return slices.Compact(ips)
 }
 
 // SetPeerCertificates sets the TLS certificates used for peer-to-peer communication.
@@ -257,11 +428,249 @@ func (m *Manager) SetPeerCertificates(_ *utiltls.ParsedCertData, rawCertData *ut
 	return nil
 }
 
-func makeAddressCluster(name, addr string, port uint16, hostname string) (*cluster.Cluster, error) {
-	return makeEndpointsCluster(name, []v1alpha1.Endpoint{{Host: addr, Port: port}}, hostname)
+// makeTCPImportListener returns a listener which tunnels TCP connections of an imported service
+// through the egress router, using HTTP CONNECT.
+// Each client IP address has its own filter chain, so that revoking connections of a client (by modifying its
+// filter chain) drains only the connections of that client. Other clients share a default filter chain.
+func makeTCPImportListener(
+	name string, imp *v1alpha1.Import, port *v1alpha1.ImportPort, headersToAdd []*core.HeaderValueOption,
+	clientIPs []string,
+) (*listener.Listener, error) {
+	defaultChain, err := makeTCPImportFilterChain(defaultFilterChainName, imp, headersToAdd)
+	if err != nil {
+		return nil, err
+	}
+
+	filterChains := make([]*listener.FilterChain, 0, len(clientIPs)+1)
+	filterChains = append(filterChains, defaultChain)
+	for _, ip := range clientIPs {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+
+		chain, err := makeTCPImportFilterChain(ip, imp, headersToAdd)
+		if err != nil {
+			return nil, err
+		}
+
+		chain.FilterChainMatch = &listener.FilterChainMatch{
+			SourcePrefixRanges: []*core.CidrRange{{
+				AddressPrefix: ip,
+				PrefixLen:     wrapperspb.UInt32(uint32(addr.BitLen())),
+			}},
+		}
+		filterChains = append(filterChains, chain)
+	}
+
+	// TODO: listen on a more specific address (i.e. not 0.0.0.0)
+	return &listener.Listener{
+		Name:         name,
+		Address:      makeListenerAddress(port.TargetPort, core.SocketAddress_TCP),
+		FilterChains: filterChains,
+	}, nil
+}
+
+// makeTCPImportFilterChain returns a TCP import listener filter chain, which reports its name
+// in the tunneling requests.
+func makeTCPImportFilterChain(
+	name string, imp *v1alpha1.Import, headersToAdd []*core.HeaderValueOption,
+) (*listener.FilterChain, error) {
+	tunnelingConfig := &tcpproxy.TcpProxy_TunnelingConfig{
+		Hostname: fmt.Sprintf("%s:%d", egressRouterHost, egressRouterPort),
+		HeadersToAdd: append(slices.Clip(headersToAdd), &core.HeaderValueOption{
+			Header: &core.HeaderValue{
+				Key:   cpapi.FilterChainHeader,
+				Value: name,
+			},
+		}),
+	}
+
+	tcpProxyFilter, err := makeTCPProxyFilter(
+		cpapi.EgressRouterCluster, imp.Name, tunnelingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listener.FilterChain{
+		Name:    name,
+		Filters: []*listener.Filter{tcpProxyFilter},
+	}, nil
 }
 
-func makeEndpointsCluster(name string, endpoints []v1alpha1.Endpoint, hostname string) (*cluster.Cluster, error) {
+// makeHTTPImportListener returns a listener which forwards HTTP requests of an imported service
+// through the egress router. Each request is forwarded, and hence authorized, separately.
+func makeHTTPImportListener(
+	name string, imp *v1alpha1.Import, port *v1alpha1.ImportPort, headersToAdd []*core.HeaderValueOption,
+) (*listener.Listener, error) {
+	// import headers set by clients are overwritten
+	requestHeadersToAdd := make([]*core.HeaderValueOption, 0, len(headersToAdd)+1)
+	for _, header := range headersToAdd {
+		requestHeadersToAdd = append(requestHeadersToAdd, &core.HeaderValueOption{
+			Header:         header.Header,
+			AppendAction:   core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			KeepEmptyValue: header.KeepEmptyValue,
+		})
+	}
+	requestHeadersToAdd = append(requestHeadersToAdd, &core.HeaderValueOption{
+		Header: &core.HeaderValue{
+			Key:   forwardedHostHeader,
+			Value: "%REQ(:AUTHORITY)%",
+		},
+		AppendAction: core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	})
+
+	routeAction := &route.RouteAction{
+		ClusterSpecifier: &route.RouteAction_Cluster{
+			Cluster: cpapi.EgressRouterCluster,
+		},
+		// requests may be long-lived (e.g., streaming responses), similarly to connections
+		Timeout: durationpb.New(0),
+	}
+	if imp.Spec.HTTP != nil && imp.Spec.HTTP.Retries > 0 {
+		// each retry is authorized, and hence load-balanced, separately
+		routeAction.RetryPolicy = &route.RetryPolicy{
+			RetryOn:    httpRetryOn,
+			NumRetries: wrapperspb.UInt32(imp.Spec.HTTP.Retries),
+		}
+	}
+
+	routerConfig, err := anypb.New(&router.Router{})
+	if err != nil {
+		return nil, err
+	}
+
+	hcmConfig := &hcm.HttpConnectionManager{
+		StatPrefix: "http-proxy-" + imp.Name,
+		RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{
+			RouteConfig: &route.RouteConfiguration{
+				Name: name,
+				VirtualHosts: []*route.VirtualHost{{
+					Name:    imp.Name,
+					Domains: []string{"*"},
+					Routes: []*route.Route{{
+						Match: &route.RouteMatch{
+							PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+						},
+						Action:              &route.Route_Route{Route: routeAction},
+						RequestHeadersToAdd: requestHeadersToAdd,
+					}},
+				}},
+			},
+		},
+		HttpFilters: []*hcm.HttpFilter{{
+			Name: wellknown.Router,
+			ConfigType: &hcm.HttpFilter_TypedConfig{
+				TypedConfig: routerConfig,
+			},
+		}},
+	}
+
+	pb, err := anypb.New(hcmConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: listen on a more specific address (i.e. not 0.0.0.0)
+	return &listener.Listener{
+		Name:    name,
+		Address: makeListenerAddress(port.TargetPort, core.SocketAddress_TCP),
+		FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &listener.Filter_TypedConfig{
+					TypedConfig: pb,
+				},
+			}},
+		}},
+	}, nil
+}
+
+// makeUDPImportListener returns a listener which tunnels UDP flows of an imported service
+// through the egress router, using HTTP CONNECT-UDP (RFC 9298).
+// Each flow (downstream address) is tunneled, and hence authorized, separately.
+func makeUDPImportListener(
+	name string, imp *v1alpha1.Import, port *v1alpha1.ImportPort, headersToAdd []*core.HeaderValueOption,
+) (*listener.Listener, error) {
+	udpProxyConfig := &udpproxy.UdpProxyConfig{
+		StatPrefix: "udp-proxy-" + imp.Name,
+		RouteSpecifier: &udpproxy.UdpProxyConfig_Cluster{
+			Cluster: cpapi.EgressRouterCluster,
+		},
+		TunnelingConfig: &udpproxy.UdpProxyConfig_UdpTunnelingConfig{
+			ProxyHost:         egressRouterHost,
+			ProxyPort:         wrapperspb.UInt32(egressRouterPort),
+			TargetHost:        imp.Name + "." + imp.Namespace,
+			DefaultTargetPort: uint32(port.Port),
+			HeadersToAdd:      headersToAdd,
+		},
+	}
+
+	pb, err := anypb.New(udpProxyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: listen on a more specific address (i.e. not 0.0.0.0)
+	return &listener.Listener{
+		Name:              name,
+		Address:           makeListenerAddress(port.TargetPort, core.SocketAddress_UDP),
+		UdpListenerConfig: &listener.UdpListenerConfig{},
+		ListenerFilters: []*listener.ListenerFilter{{
+			Name: udpProxyFilterName,
+			ConfigType: &listener.ListenerFilter_TypedConfig{
+				TypedConfig: pb,
+			},
+		}},
+	}, nil
+}
+
+func makeListenerAddress(port uint16, protocol core.SocketAddress_Protocol) *core.Address {
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: protocol,
+				Address:  "0.0.0.0",
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: uint32(port),
+				},
+			},
+		},
+	}
+}
+
+// staleResources returns the names of cached resources belonging to a service, which are not in the given resources.
+func staleResources(
+	linearCache *cache.LinearCache, serviceResourceName string, resources map[string]cachetypes.Resource,
+) []string {
+	var stale []string
+	for name := range linearCache.GetResources() {
+		if _, ok := resources[name]; !ok && cpapi.IsResourceOf(name, serviceResourceName) {
+			stale = append(stale, name)
+		}
+	}
+
+	return stale
+}
+
+// socketProtocol returns the xDS socket protocol matching a shared service protocol.
+func socketProtocol(protocol v1alpha1.Protocol) core.SocketAddress_Protocol {
+	if protocol == v1alpha1.ProtocolUDP {
+		return core.SocketAddress_UDP
+	}
+
+	return core.SocketAddress_TCP
+}
+
+func makeAddressCluster(
+	name, addr string, port uint16, hostname string, protocol core.SocketAddress_Protocol,
+) (*cluster.Cluster, error) {
+	return makeEndpointsCluster(name, []v1alpha1.Endpoint{{Host: addr, Port: port}}, hostname, protocol)
+}
+
+func makeEndpointsCluster(
+	name string, endpoints []v1alpha1.Endpoint, hostname string, protocol core.SocketAddress_Protocol,
+) (*cluster.Cluster, error) {
 	lbEndpoints := make([]*endpoint.LbEndpoint, len(endpoints))
 
 	for i, ep := range endpoints {
@@ -271,7 +680,8 @@ func makeEndpointsCluster(name string, endpoints []v1alpha1.Endpoint, hostname s
 					Address: &core.Address{
 						Address: &core.Address_SocketAddress{
 							SocketAddress: &core.SocketAddress{
-								Address: ep.Host,
+								Protocol: protocol,
+								Address:  ep.Host,
 								PortSpecifier: &core.SocketAddress_PortValue{
 									PortValue: uint32(ep.Port),
 								},
@@ -343,6 +753,13 @@ func NewManager() *Manager {
 		clusters:  cache.NewLinearCache(resource.ClusterType, cache.WithLogger(logger)),
 		listeners: cache.NewLinearCache(resource.ListenerType, cache.WithLogger(logger)),
 		secrets:   cache.NewLinearCache(resource.SecretType, cache.WithLogger(logger)),
-		logger:    logger,
+
+		imports: make(map[types.NamespacedName]*v1alpha1.Import),
+		podIPs:  make(map[types.NamespacedName][]string),
+
+		revoked:   make(map[string]map[string][]cpapi.ConnectionID),
+		deletions: make(map[string]*time.Timer),
+
+		logger: logger,
 	}
 }
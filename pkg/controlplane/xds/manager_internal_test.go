@@ -0,0 +1,205 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	cachetypes "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/clusterlink-net/clusterlink/pkg/apis/clusterlink.net/v1alpha1"
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// listenerResources returns listener resources with the given names.
+func listenerResources(names ...string) map[string]cachetypes.Resource {
+	resources := make(map[string]cachetypes.Resource, len(names))
+	for _, name := range names {
+		resources[name] = &listener.Listener{Name: name}
+	}
+	return resources
+}
+
+func TestStaleResources(t *testing.T) {
+	listeners := cache.NewLinearCache(resource.ListenerType)
+	svcListenerName := cpapi.ImportListenerName("svc", "ns", "")
+	httpListenerName := cpapi.ImportListenerName("svc", "ns", "http")
+	metricsListenerName := cpapi.ImportListenerName("svc", "ns", "metrics")
+	otherListenerName := cpapi.ImportListenerName("svc2", "ns", "http")
+	require.Nil(t, listeners.UpdateResources(
+		listenerResources(svcListenerName, httpListenerName, metricsListenerName, otherListenerName), nil))
+
+	// resources of the service missing from the updated resources are stale
+	stale := staleResources(listeners, svcListenerName, listenerResources(httpListenerName))
+	require.ElementsMatch(t, []string{svcListenerName, metricsListenerName}, stale)
+
+	// all resources of a deleted service are stale
+	stale = staleResources(listeners, svcListenerName, nil)
+	require.ElementsMatch(t, []string{svcListenerName, httpListenerName, metricsListenerName}, stale)
+}
+
+func TestRevokeConnections(t *testing.T) {
+	m := NewManager()
+	listenerName := cpapi.ImportListenerName("svc", "ns", "")
+	clusterName := cpapi.ExportClusterName("svc", "ns", "")
+	require.Nil(t, m.listeners.UpdateResources(listenerResources(listenerName), nil))
+	require.Nil(t, m.AddExport(&v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec:       v1alpha1.ExportSpec{Port: 80},
+	}))
+
+	revoked := []cpapi.ConnectionID{{Dataplane: "dp1", StreamID: "1"}, {Dataplane: "dp2", StreamID: "2"}}
+	revokedOf := func(metadata *core.Metadata) []cpapi.ConnectionID {
+		return cpapi.ParseRevokedConnections(metadata.GetFilterMetadata()[cpapi.MetadataNamespace])
+	}
+
+	// revoked connections are listed in the metadata of import listeners and export clusters
+	require.Nil(t, m.RevokeConnections(listenerName, "", revoked))
+	ln, ok := m.listeners.GetResources()[listenerName].(*listener.Listener)
+	require.True(t, ok)
+	require.Equal(t, revoked, revokedOf(ln.Metadata))
+
+	require.Nil(t, m.RevokeConnections(clusterName, "", revoked[:1]))
+	cc, ok := m.clusters.GetResources()[clusterName].(*cluster.Cluster)
+	require.True(t, ok)
+	require.Equal(t, revoked[:1], revokedOf(cc.Metadata))
+
+	// updated resources keep listing their revoked connections
+	require.Nil(t, m.AddExport(&v1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec:       v1alpha1.ExportSpec{Port: 8080},
+	}))
+	cc, ok = m.clusters.GetResources()[clusterName].(*cluster.Cluster)
+	require.True(t, ok)
+	require.Equal(t, revoked[:1], revokedOf(cc.Metadata))
+
+	// connections of removed resources are already terminated
+	otherListenerName := cpapi.ImportListenerName("svc2", "ns", "")
+	require.Nil(t, m.RevokeConnections(otherListenerName, "", revoked))
+	require.NotContains(t, m.listeners.GetResources(), otherListenerName)
+}
+
+func TestClientFilterChains(t *testing.T) {
+	m := NewManager()
+	imp := &v1alpha1.Import{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec:       v1alpha1.ImportSpec{Port: 80, TargetPort: 8080},
+		Status: v1alpha1.ImportStatus{Conditions: []metav1.Condition{{
+			Type:   v1alpha1.ImportTargetPortValid,
+			Status: metav1.ConditionTrue,
+		}}},
+	}
+	listenerName := cpapi.ImportListenerName("svc", "ns", "")
+	newPod := func(name string, ips ...string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}}
+		for _, ip := range ips {
+			pod.Status.PodIPs = append(pod.Status.PodIPs, v1.PodIP{IP: ip})
+		}
+		return pod
+	}
+	getListener := func() *listener.Listener {
+		ln, ok := m.listeners.GetResources()[listenerName].(*listener.Listener)
+		require.True(t, ok)
+		return ln
+	}
+	getFilterChain := func(name string) *listener.FilterChain {
+		for _, chain := range getListener().FilterChains {
+			if chain.Name == name {
+				return chain
+			}
+		}
+		return nil
+	}
+
+	// clients with no known IP address share the default filter chain
+	require.Nil(t, m.AddImport(imp))
+	require.Len(t, getListener().FilterChains, 1)
+	require.NotNil(t, getFilterChain(defaultFilterChainName))
+
+	// each client pod IP address has its own filter chain
+	require.Nil(t, m.AddPod(newPod("pod1", "10.0.0.1", "fd00::1")))
+	require.Nil(t, m.AddPod(newPod("pod2", "10.0.0.2")))
+	require.Len(t, getListener().FilterChains, 4)
+	chain := getFilterChain("fd00::1")
+	require.NotNil(t, chain)
+	require.Equal(t, "fd00::1", chain.FilterChainMatch.SourcePrefixRanges[0].AddressPrefix)
+	require.Equal(t, uint32(128), chain.FilterChainMatch.SourcePrefixRanges[0].PrefixLen.GetValue())
+	require.Equal(t, uint32(32),
+		getFilterChain("10.0.0.1").FilterChainMatch.SourcePrefixRanges[0].PrefixLen.GetValue())
+
+	// revoking connections modifies only the filter chain of their client
+	revoked := []cpapi.ConnectionID{{Dataplane: "dp1", StreamID: "1"}}
+	revokedOf := func(metadata *core.Metadata) []cpapi.ConnectionID {
+		return cpapi.ParseRevokedConnections(metadata.GetFilterMetadata()[cpapi.MetadataNamespace])
+	}
+	unrevokedChain := getFilterChain("10.0.0.1")
+	require.Nil(t, m.RevokeConnections(listenerName, "10.0.0.2", revoked))
+	require.Equal(t, revoked, revokedOf(getFilterChain("10.0.0.2").Metadata))
+	require.Nil(t, getListener().Metadata)
+	require.True(t, proto.Equal(unrevokedChain, getFilterChain("10.0.0.1")))
+
+	// updated listeners keep listing the revoked connections of their filter chains
+	require.Nil(t, m.AddPod(newPod("pod3", "10.0.0.3")))
+	require.Equal(t, revoked, revokedOf(getFilterChain("10.0.0.2").Metadata))
+	require.True(t, proto.Equal(unrevokedChain, getFilterChain("10.0.0.1")))
+
+	// filter chains of deleted pods are removed
+	require.Nil(t, m.DeletePod(types.NamespacedName{Namespace: "ns", Name: "pod2"}))
+	require.Nil(t, getFilterChain("10.0.0.2"))
+	require.Empty(t, m.revokedConnections(listenerName)["10.0.0.2"])
+	require.Len(t, getListener().FilterChains, 4)
+}
+
+func TestDeleteResources(t *testing.T) {
+	m := NewManager()
+	m.SetRevocationGracePeriod(50 * time.Millisecond)
+
+	svcListenerName := cpapi.ImportListenerName("svc", "ns", "")
+	httpListenerName := cpapi.ImportListenerName("svc", "ns", "http")
+	otherListenerName := cpapi.ImportListenerName("svc2", "ns", "")
+	require.Nil(t, m.listeners.UpdateResources(
+		listenerResources(svcListenerName, httpListenerName, otherListenerName), nil))
+	require.Nil(t, m.RevokeConnections(httpListenerName, "", []cpapi.ConnectionID{{Dataplane: "dp1", StreamID: "1"}}))
+
+	// resources of a deleted service are removed once the grace period passes
+	require.Nil(t, m.deleteResources(m.listeners, svcListenerName))
+	require.Len(t, m.listeners.GetResources(), 3)
+	require.Eventually(t, func() bool {
+		return len(m.listeners.GetResources()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Contains(t, m.listeners.GetResources(), otherListenerName)
+	require.Empty(t, m.revokedConnections(httpListenerName))
+
+	// resources of a re-added service are not removed
+	require.Nil(t, m.deleteResources(m.listeners, otherListenerName))
+	m.cancelDeletion(otherListenerName)
+	time.Sleep(100 * time.Millisecond)
+	require.Contains(t, m.listeners.GetResources(), otherListenerName)
+
+	// resources are removed immediately with no grace period
+	m.SetRevocationGracePeriod(0)
+	require.Nil(t, m.deleteResources(m.listeners, otherListenerName))
+	require.Empty(t, m.listeners.GetResources())
+}
@@ -0,0 +1,30 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var pushesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "clusterlink",
+	Subsystem: "xds",
+	Name:      "pushes_total",
+	Help:      "Number of xDS responses pushed to dataplanes, by resource type.",
+}, []string{"type"})
+
+func init() {
+	metrics.Registry.MustRegister(pushesMetric)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// SetRevocationGracePeriod sets the time connections of deleted imports, exports and peers may stay open,
+// before their listeners and clusters are removed from the dataplanes.
+func (m *Manager) SetRevocationGracePeriod(gracePeriod time.Duration) {
+	m.revocationGracePeriod = gracePeriod
+}
+
+// RevokeConnections sets the revoked active connections of an import listener filter chain
+// (or of an import listener, or of an export cluster, if the filter chain is empty), to be terminated by the dataplanes.
+// Only the metadata of the given filter chain is modified, hence Envoy drains only the connections of that chain.
+func (m *Manager) RevokeConnections(resourceName, filterChain string, revoked []cpapi.ConnectionID) error {
+	linearCache := m.listeners
+	if strings.HasPrefix(resourceName, cpapi.ExportClusterPrefix) {
+		linearCache = m.clusters
+	} else {
+		m.importsLock.Lock()
+		defer m.importsLock.Unlock()
+	}
+
+	res, ok := linearCache.GetResources()[resourceName]
+	if !ok {
+		// resource already removed, terminating its connections
+		return nil
+	}
+
+	m.revocationLock.Lock()
+	if _, ok := m.revoked[resourceName]; !ok {
+		m.revoked[resourceName] = make(map[string][]cpapi.ConnectionID)
+	}
+	m.revoked[resourceName][filterChain] = revoked
+	m.revocationLock.Unlock()
+
+	res = proto.Clone(res)
+	switch r := res.(type) {
+	case *listener.Listener:
+		if err := setListenerRevocationMetadata(r, map[string][]cpapi.ConnectionID{filterChain: revoked}); err != nil {
+			return err
+		}
+	case *cluster.Cluster:
+		metadata, err := revocationMetadata(revoked)
+		if err != nil {
+			return err
+		}
+		r.Metadata = metadata
+	default:
+		return fmt.Errorf("unexpected resource type %T", res)
+	}
+
+	return linearCache.UpdateResource(resourceName, res)
+}
+
+// revokedConnections returns the revoked connections of an import listener (or an export cluster),
+// keyed by their listener filter chain name (empty for connections of the listener or cluster itself).
+func (m *Manager) revokedConnections(resourceName string) map[string][]cpapi.ConnectionID {
+	m.revocationLock.Lock()
+	defer m.revocationLock.Unlock()
+	return maps.Clone(m.revoked[resourceName])
+}
+
+// setListenerRevocationMetadata sets the metadata of an import listener and of its filter chains,
+// listing their revoked connections, keyed by filter chain name (empty for the listener itself).
+// Connections of filter chains missing from the listener were already drained when their chain was removed.
+func setListenerRevocationMetadata(ln *listener.Listener, revoked map[string][]cpapi.ConnectionID) error {
+	for filterChain, ids := range revoked {
+		if len(ids) == 0 {
+			continue
+		}
+
+		metadata, err := revocationMetadata(ids)
+		if err != nil {
+			return err
+		}
+
+		if filterChain == "" {
+			ln.Metadata = metadata
+			continue
+		}
+
+		for _, chain := range ln.FilterChains {
+			if chain.Name == filterChain {
+				chain.Metadata = metadata
+			}
+		}
+	}
+
+	return nil
+}
+
+// revocationMetadata returns the metadata of an import listener, filter chain (or an export cluster),
+// listing its revoked connections.
+func revocationMetadata(revoked []cpapi.ConnectionID) (*core.Metadata, error) {
+	metadata, err := cpapi.RevokedConnectionsMetadata(revoked)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode revoked connections: %w", err)
+	}
+
+	return &core.Metadata{
+		FilterMetadata: map[string]*structpb.Struct{cpapi.MetadataNamespace: metadata},
+	}, nil
+}
+
+// deleteResources removes the resources (clusters or listeners) of a service (or a peer) once the grace period passes,
+// terminating their connections. Resources re-added meanwhile are not removed.
+func (m *Manager) deleteResources(linearCache *cache.LinearCache, serviceResourceName string) error {
+	m.revocationLock.Lock()
+	defer m.revocationLock.Unlock()
+
+	if timer, ok := m.deletions[serviceResourceName]; ok {
+		timer.Stop()
+		delete(m.deletions, serviceResourceName)
+	}
+
+	if m.revocationGracePeriod == 0 {
+		return m.removeResources(linearCache, serviceResourceName)
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(m.revocationGracePeriod, func() {
+		m.revocationLock.Lock()
+		defer m.revocationLock.Unlock()
+
+		// skip if cancelled or replaced meanwhile
+		if m.deletions[serviceResourceName] != timer {
+			return
+		}
+		delete(m.deletions, serviceResourceName)
+
+		m.logger.Infof("Removing resources of '%s'.", serviceResourceName)
+		if err := m.removeResources(linearCache, serviceResourceName); err != nil {
+			m.logger.Errorf("Cannot remove resources of '%s': %v.", serviceResourceName, err)
+		}
+	})
+	m.deletions[serviceResourceName] = timer
+	return nil
+}
+
+// removeResources removes the resources of a service (or a peer), and their revoked connections.
+// Must be called with revocationLock held.
+func (m *Manager) removeResources(linearCache *cache.LinearCache, serviceResourceName string) error {
+	for name := range m.revoked {
+		if cpapi.IsResourceOf(name, serviceResourceName) {
+			delete(m.revoked, name)
+		}
+	}
+
+	return linearCache.UpdateResources(nil, staleResources(linearCache, serviceResourceName, nil))
+}
+
+// cancelDeletion cancels the pending removal of the resources of a re-added service (or peer).
+func (m *Manager) cancelDeletion(serviceResourceName string) {
+	m.revocationLock.Lock()
+	defer m.revocationLock.Unlock()
+
+	if timer, ok := m.deletions[serviceResourceName]; ok {
+		timer.Stop()
+		delete(m.deletions, serviceResourceName)
+	}
+}
@@ -40,6 +40,20 @@ func RegisterService(ctx context.Context, manager *Manager, grpcServer *grpc.Ser
 		},
 	}
 
-	srv := server.NewServer(ctx, muxCache, nil)
+	// count pushed responses
+	callbacks := server.CallbackFuncs{
+		StreamResponseFunc: func(
+			_ context.Context, _ int64, _ *discovery.DiscoveryRequest, resp *discovery.DiscoveryResponse,
+		) {
+			pushesMetric.WithLabelValues(resp.GetTypeUrl()).Inc()
+		},
+		StreamDeltaResponseFunc: func(
+			_ int64, _ *discovery.DeltaDiscoveryRequest, resp *discovery.DeltaDiscoveryResponse,
+		) {
+			pushesMetric.WithLabelValues(resp.GetTypeUrl()).Inc()
+		},
+	}
+
+	srv := server.NewServer(ctx, muxCache, callbacks)
 	discovery.RegisterAggregatedDiscoveryServiceServer(grpcServer, srv)
 }
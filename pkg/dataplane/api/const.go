@@ -22,4 +22,8 @@ const (
 	GoDataplaneName = "cl-go-dataplane"
 	// ReadinessListenPort is the port used to probe for dataplane readiness.
 	ReadinessListenPort = 4445
+	// MetricsPath is the path of the Prometheus metrics endpoint, served on ReadinessListenPort.
+	MetricsPath = "/metrics"
+	// FlowsPath is the path for listing active and recently completed flows, served on ReadinessListenPort.
+	FlowsPath = "/flows"
 )
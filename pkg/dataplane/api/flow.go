@@ -0,0 +1,47 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import "time"
+
+// Flow is a record of a single connection (or UDP flow) forwarded by the dataplane.
+type Flow struct {
+	// ID uniquely identifies the flow.
+	ID string `json:"id"`
+	// Direction is either egress (from a local client to an imported service) or ingress
+	// (from a remote peer to an exported service).
+	Direction string `json:"direction"`
+	// Protocol is either tcp or udp.
+	Protocol string `json:"protocol"`
+	// Import is the imported service (namespace/name) of egress flows.
+	Import string `json:"import,omitempty"`
+	// Export is the exported service (namespace/name) of ingress flows.
+	Export string `json:"export,omitempty"`
+	// Port is the name of the service port, if any.
+	Port string `json:"port,omitempty"`
+	// ClientIP is the IP address of the local client of egress flows.
+	ClientIP string `json:"clientIP,omitempty"`
+	// Peer is the remote peer: the target peer of egress flows, or the client peer of ingress flows.
+	Peer string `json:"peer"`
+	// StartTime is the time the flow started forwarding.
+	StartTime time.Time `json:"startTime"`
+	// EndTime is the time the flow ended. Unset for active flows.
+	EndTime *time.Time `json:"endTime,omitempty"`
+	// SentBytes is the number of bytes forwarded from the local workload to the remote peer.
+	SentBytes int64 `json:"sentBytes"`
+	// ReceivedBytes is the number of bytes forwarded from the remote peer to the local workload.
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// CloseReason describes why the flow ended (e.g. "workload closed", "peer closed"). Unset for active flows.
+	CloseReason string `json:"closeReason,omitempty"`
+}
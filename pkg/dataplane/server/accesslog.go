@@ -0,0 +1,220 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	// accessLogQueueSize is the maximal number of access log entries pending to be sent to the controlplane.
+	accessLogQueueSize = 1024
+	// accessLogRetryInterval is the time to wait before re-opening a failed access log stream.
+	accessLogRetryInterval = time.Second
+)
+
+// accessLogger streams access logs of egress connections, and of ingress connections, to the controlplane,
+// in the same format used by Envoy-based dataplanes.
+type accessLogger struct {
+	client     accesslogv3.AccessLogServiceClient
+	identifier *accesslogv3.StreamAccessLogsMessage_Identifier
+	entries    chan *accesslogdatav3.HTTPAccessLogEntry
+
+	streamCounter atomic.Uint64
+
+	logger *logrus.Entry
+}
+
+// connectionEstablished reports an egress connection of a client to an exported service of a remote peer,
+// returning the ID of the connection.
+func (l *accessLogger) connectionEstablished(
+	listenerName, targetCluster, sourceExport, clientIP string, latency time.Duration,
+) string {
+	streamID := strconv.FormatUint(l.streamCounter.Add(1), 10)
+	l.log(accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished,
+		streamID, listenerName, targetCluster, sourceExport, clientIP, latency, http.StatusOK)
+	return streamID
+}
+
+// connectionEnded reports the end of an egress connection previously reported as established.
+func (l *accessLogger) connectionEnded(streamID, listenerName, targetCluster string) {
+	l.log(accesslogdatav3.AccessLogType_DownstreamEnd, streamID, listenerName, targetCluster, "", "", 0, http.StatusOK)
+}
+
+// connectionFailed reports an egress connection to an exported service of a remote peer which could not be established.
+func (l *accessLogger) connectionFailed(listenerName, targetCluster, sourceExport string, responseCode int) {
+	streamID := strconv.FormatUint(l.streamCounter.Add(1), 10)
+	l.log(accesslogdatav3.AccessLogType_DownstreamEnd,
+		streamID, listenerName, targetCluster, sourceExport, "", 0, responseCode)
+}
+
+// ingressConnectionEstablished reports an ingress connection to an export cluster, authorized by the access token
+// of the given ID, returning the ID of the connection.
+func (l *accessLogger) ingressConnectionEstablished(targetCluster, tokenID string) string {
+	streamID := strconv.FormatUint(l.streamCounter.Add(1), 10)
+	l.logIngress(accesslogdatav3.AccessLogType_DownstreamTunnelSuccessfullyEstablished, streamID, targetCluster, tokenID)
+	return streamID
+}
+
+// ingressConnectionEnded reports the end of an ingress connection previously reported as established.
+func (l *accessLogger) ingressConnectionEnded(streamID, targetCluster, tokenID string) {
+	l.logIngress(accesslogdatav3.AccessLogType_DownstreamEnd, streamID, targetCluster, tokenID)
+}
+
+// connectionRejected reports an ingress connection to an export cluster,
+// which was rejected due to the export limit of concurrent connections.
+func (l *accessLogger) connectionRejected(targetCluster string) {
+	streamID := strconv.FormatUint(l.streamCounter.Add(1), 10)
+	l.enqueue(&accesslogdatav3.HTTPAccessLogEntry{
+		CommonProperties: &accesslogdatav3.AccessLogCommon{
+			AccessLogType:   accesslogdatav3.AccessLogType_DownstreamEnd,
+			StreamId:        streamID,
+			UpstreamCluster: targetCluster,
+			ResponseFlags:   &accesslogdatav3.ResponseFlags{UpstreamOverflow: true},
+		},
+		Response: &accesslogdatav3.HTTPResponseProperties{
+			ResponseCode: wrapperspb.UInt32(http.StatusServiceUnavailable),
+		},
+	})
+}
+
+func (l *accessLogger) log(
+	logType accesslogdatav3.AccessLogType,
+	streamID, listenerName, targetCluster, sourceExport, clientIP string,
+	latency time.Duration,
+	responseCode int,
+) {
+	importName, importNamespace, importPort, err := cpapi.ParseImportListenerName(listenerName)
+	if err != nil {
+		l.logger.Errorf("Cannot parse listener name '%s': %v.", listenerName, err)
+		return
+	}
+
+	entry := &accesslogdatav3.HTTPAccessLogEntry{
+		CommonProperties: &accesslogdatav3.AccessLogCommon{
+			AccessLogType:             logType,
+			IntermediateLogEntry:      logType != accesslogdatav3.AccessLogType_DownstreamEnd,
+			StreamId:                  streamID,
+			UpstreamCluster:           targetCluster,
+			TimeToFirstUpstreamRxByte: durationpb.New(latency),
+		},
+		Request: &accesslogdatav3.HTTPRequestProperties{
+			RequestHeaders: map[string]string{
+				cpapi.ImportNameHeader:      importName,
+				cpapi.ImportNamespaceHeader: importNamespace,
+				cpapi.ImportPortHeader:      importPort,
+				cpapi.ClientIPHeader:        clientIP,
+				cpapi.ImportSourceHeader:    sourceExport,
+			},
+		},
+		Response: &accesslogdatav3.HTTPResponseProperties{
+			ResponseCode: wrapperspb.UInt32(uint32(responseCode)),
+		},
+	}
+
+	l.enqueue(entry)
+}
+
+func (l *accessLogger) logIngress(logType accesslogdatav3.AccessLogType, streamID, targetCluster, tokenID string) {
+	l.enqueue(&accesslogdatav3.HTTPAccessLogEntry{
+		CommonProperties: &accesslogdatav3.AccessLogCommon{
+			AccessLogType:        logType,
+			IntermediateLogEntry: logType != accesslogdatav3.AccessLogType_DownstreamEnd,
+			StreamId:             streamID,
+			UpstreamCluster:      targetCluster,
+		},
+		Request: &accesslogdatav3.HTTPRequestProperties{
+			RequestHeaders: map[string]string{cpapi.AccessTokenIDHeader: tokenID},
+		},
+		Response: &accesslogdatav3.HTTPResponseProperties{
+			ResponseCode: wrapperspb.UInt32(http.StatusOK),
+		},
+	})
+}
+
+// enqueue queues an access log entry to be sent to the controlplane.
+func (l *accessLogger) enqueue(entry *accesslogdatav3.HTTPAccessLogEntry) {
+	// never block connections on the controlplane
+	select {
+	case l.entries <- entry:
+	default:
+		l.logger.Warnf("Access log queue is full, dropping entry for stream %s.",
+			entry.GetCommonProperties().GetStreamId())
+	}
+}
+
+// run streams the queued access log entries to the controlplane, re-opening the stream on failures.
+func (l *accessLogger) run() {
+	for {
+		if err := l.stream(); err != nil {
+			l.logger.Warnf("Access log stream failed: %v.", err)
+		}
+		time.Sleep(accessLogRetryInterval)
+	}
+}
+
+func (l *accessLogger) stream() error {
+	stream, err := l.client.StreamAccessLogs(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = stream.CloseAndRecv()
+	}()
+
+	identifier := l.identifier
+	for entry := range l.entries {
+		msg := &accesslogv3.StreamAccessLogsMessage{
+			// identifier is only sent on the first message of the stream
+			Identifier: identifier,
+			LogEntries: &accesslogv3.StreamAccessLogsMessage_HttpLogs{
+				HttpLogs: &accesslogv3.StreamAccessLogsMessage_HTTPAccessLogEntries{
+					LogEntry: []*accesslogdatav3.HTTPAccessLogEntry{entry},
+				},
+			},
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+		identifier = nil
+	}
+
+	return nil
+}
+
+func newAccessLogger(dataplaneID string, controlplaneClient grpc.ClientConnInterface) *accessLogger {
+	return &accessLogger{
+		client: accesslogv3.NewAccessLogServiceClient(controlplaneClient),
+		identifier: &accesslogv3.StreamAccessLogsMessage_Identifier{
+			Node:    &corev3.Node{Id: dataplaneID},
+			LogName: cpapi.EgressAccessLogName,
+		},
+		entries: make(chan *accesslogdatav3.HTTPAccessLogEntry, accessLogQueueSize),
+		logger:  logrus.WithField("component", "dataplane.server.accesslog"),
+	}
+}
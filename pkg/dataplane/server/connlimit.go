@@ -0,0 +1,58 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+// getClusterMaxConnections returns the maximal number of concurrent connections to a cluster,
+// as set by its circuit breaker thresholds. Zero means no limit.
+func (d *Dataplane) getClusterMaxConnections(name string) uint32 {
+	c, ok := d.clusters[name]
+	if !ok {
+		return 0
+	}
+
+	for _, thresholds := range c.GetCircuitBreakers().GetThresholds() {
+		if maxConnections := thresholds.GetMaxConnections(); maxConnections != nil {
+			return maxConnections.GetValue()
+		}
+	}
+
+	return 0
+}
+
+// acquireConnection reserves a connection to a cluster, returning false if the cluster
+// concurrent connections limit is reached. A reserved connection must be released using releaseConnection.
+func (d *Dataplane) acquireConnection(name string) bool {
+	maxConnections := d.getClusterMaxConnections(name)
+
+	d.activeConnectionsLock.Lock()
+	defer d.activeConnectionsLock.Unlock()
+
+	if maxConnections > 0 && d.activeConnections[name] >= maxConnections {
+		return false
+	}
+
+	d.activeConnections[name]++
+	return true
+}
+
+// releaseConnection releases a connection to a cluster reserved using acquireConnection.
+func (d *Dataplane) releaseConnection(name string) {
+	d.activeConnectionsLock.Lock()
+	defer d.activeConnectionsLock.Unlock()
+
+	d.activeConnections[name]--
+	if d.activeConnections[name] == 0 {
+		delete(d.activeConnections, name)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"sync"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
@@ -41,17 +42,32 @@ type Dataplane struct {
 	ID             string
 	router         *chi.Mux
 	authzClient    authv3.AuthorizationClient
+	accessLogger   *accessLogger
 	parsedCertData *utiltls.ParsedCertData
 	clusters       map[string]*cluster.Cluster
 	listeners      map[string]*listener.Listener
 	listenerEnd    map[string]chan bool
 
+	activeConnectionsLock sync.Mutex
+	activeConnections     map[string]uint32
+
+	flows *flowRecorder
+
+	peerMultiplexing   bool
+	peerTransportsLock sync.Mutex
+	peerTransports     map[string]*peerTransport
+
 	tlsConfigLock sync.RWMutex
 	tlsConfig     *tls.Config
 
 	logger *logrus.Entry
 }
 
+// RunAccessLogger streams access logs of egress connections to the controlplane.
+func (d *Dataplane) RunAccessLogger() {
+	d.accessLogger.run()
+}
+
 // GetClusterTarget returns the cluster address:port from the cluster map.
 func (d *Dataplane) GetClusterTarget(name string) (string, error) {
 	if _, ok := d.clusters[name]; !ok {
@@ -83,14 +99,23 @@ func (d *Dataplane) GetClusterHostname(name string) (string, error) {
 	return d.clusters[name].LoadAssignment.GetEndpoints()[0].LbEndpoints[0].GetEndpoint().Hostname, nil
 }
 
-// AddCluster adds/updates a cluster to the map.
+// AddCluster adds/updates a cluster to the map, closing its connections revoked by the controlplane.
 func (d *Dataplane) AddCluster(c *cluster.Cluster) {
 	d.clusters[c.Name] = c
+	d.revokeConnections(c.Name, c.Metadata)
 }
 
-// RemoveCluster adds a cluster to the map.
+// RemoveCluster removes a cluster from the map, closing the connections forwarded to it.
 func (d *Dataplane) RemoveCluster(name string) {
 	delete(d.clusters, name)
+	d.revokeClusterConnections(name)
+
+	d.peerTransportsLock.Lock()
+	defer d.peerTransportsLock.Unlock()
+	if pt, ok := d.peerTransports[name]; ok {
+		pt.closeIdleConnections()
+		delete(d.peerTransports, name)
+	}
 }
 
 // GetClusters returns the clusters map.
@@ -101,16 +126,38 @@ func (d *Dataplane) GetClusters() map[string]*cluster.Cluster {
 // AddListener adds a listener to the map.
 func (d *Dataplane) AddListener(ln *listener.Listener) {
 	listenerName := strings.TrimPrefix(ln.Name, api.ImportListenerPrefix)
+	d.revokeConnections(ln.Name, ln.Metadata)
 	if le, ok := d.listeners[listenerName]; ok {
-		// Check if there is an update to the listener address/port
+		// Check if there is an update to the listener address/port/protocol
 		if ln.Address.GetSocketAddress().GetAddress() == le.Address.GetSocketAddress().GetAddress() &&
-			ln.Address.GetSocketAddress().GetPortValue() == le.Address.GetSocketAddress().GetPortValue() {
-			return
+			ln.Address.GetSocketAddress().GetPortValue() == le.Address.GetSocketAddress().GetPortValue() &&
+			ln.Address.GetSocketAddress().GetProtocol() == le.Address.GetSocketAddress().GetProtocol() {
+			// and to the application protocol
+			lnRetries, lnHTTP := httpListenerRetries(ln)
+			leRetries, leHTTP := httpListenerRetries(le)
+			if lnHTTP == leHTTP && lnRetries == leRetries {
+				return
+			}
 		}
 		d.listenerEnd[listenerName] <- true
 	}
 	d.listeners[listenerName] = ln
 	go func() {
+		if ln.Address.GetSocketAddress().GetProtocol() == corev3.SocketAddress_UDP {
+			d.CreateUDPListener(listenerName,
+				ln.Address.GetSocketAddress().GetAddress(),
+				ln.Address.GetSocketAddress().GetPortValue())
+			return
+		}
+
+		if retries, ok := httpListenerRetries(ln); ok {
+			d.CreateHTTPListener(listenerName,
+				ln.Address.GetSocketAddress().GetAddress(),
+				ln.Address.GetSocketAddress().GetPortValue(),
+				retries)
+			return
+		}
+
 		d.CreateListener(listenerName,
 			ln.Address.GetSocketAddress().GetAddress(),
 			ln.Address.GetSocketAddress().GetPortValue())
@@ -121,6 +168,7 @@ func (d *Dataplane) AddListener(ln *listener.Listener) {
 func (d *Dataplane) RemoveListener(name string) {
 	delete(d.listeners, name)
 	d.listenerEnd[name] <- true
+	d.revokeListenerConnections(name)
 }
 
 // GetListeners returns the listeners map.
@@ -229,13 +277,18 @@ func NewDataplane(
 	}
 
 	dp := &Dataplane{
-		ID:             dataplaneID,
-		router:         router,
-		authzClient:    authv3.NewAuthorizationClient(controlplaneClient),
-		parsedCertData: parsedCertData,
-		clusters:       make(map[string]*cluster.Cluster),
-		listeners:      make(map[string]*listener.Listener),
-		listenerEnd:    make(map[string]chan bool),
+		ID:                dataplaneID,
+		router:            router,
+		authzClient:       authv3.NewAuthorizationClient(controlplaneClient),
+		accessLogger:      newAccessLogger(dataplaneID, controlplaneClient),
+		parsedCertData:    parsedCertData,
+		clusters:          make(map[string]*cluster.Cluster),
+		listeners:         make(map[string]*listener.Listener),
+		listenerEnd:       make(map[string]chan bool),
+		activeConnections: make(map[string]uint32),
+		flows:             newFlowRecorder(),
+		peerMultiplexing:  true,
+		peerTransports:    make(map[string]*peerTransport),
 		tlsConfig: &tls.Config{
 			MinVersion:         tls.VersionTLS12,
 			ClientAuth:         tls.RequireAndVerifyClientCert,
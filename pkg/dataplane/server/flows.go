@@ -0,0 +1,241 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/dataplane/api"
+)
+
+const (
+	// maxCompletedFlows is the number of completed flows kept in memory for listing.
+	maxCompletedFlows = 1000
+
+	protocolTCP = "tcp"
+	protocolUDP = "udp"
+)
+
+// FlowSink stores records of completed flows.
+type FlowSink interface {
+	// Write stores a flow record.
+	Write(flow *api.Flow) error
+}
+
+// JSONFlowSink writes flow records to a writer, one JSON object per line.
+type JSONFlowSink struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// Write writes a flow record as a single JSON line.
+func (s *JSONFlowSink) Write(flow *api.Flow) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.encoder.Encode(flow)
+}
+
+// NewJSONFlowSink returns a sink writing flow records to the given writer.
+func NewJSONFlowSink(w io.Writer) *JSONFlowSink {
+	return &JSONFlowSink{encoder: json.NewEncoder(w)}
+}
+
+// activeFlow is a flow which is still being forwarded.
+type activeFlow struct {
+	flow      api.Flow
+	forwarder *forwarder
+	// targetCluster is the cluster the flow is forwarded to: a remote peer cluster for egress flows,
+	// or an export cluster for ingress flows.
+	targetCluster string
+	// streamID is the ID of an egress flow in the access log stream.
+	streamID string
+}
+
+// snapshot returns the flow record, with the current byte counts of the forwarder.
+func (f *activeFlow) snapshot() api.Flow {
+	flow := f.flow
+	flow.SentBytes = f.forwarder.sent.Load()
+	flow.ReceivedBytes = f.forwarder.received.Load()
+	return flow
+}
+
+// flowRecorder keeps records of active and recently completed flows.
+type flowRecorder struct {
+	lock      sync.Mutex
+	active    map[string]*activeFlow
+	completed []api.Flow
+	sink      FlowSink
+
+	logger *logrus.Entry
+}
+
+// start records a new flow forwarded by the given forwarder.
+func (r *flowRecorder) start(flow *api.Flow, fwd *forwarder, targetCluster, streamID string) {
+	flow.ID = uuid.New().String()
+	flow.StartTime = time.Now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.active[flow.ID] = &activeFlow{
+		flow:          *flow,
+		forwarder:     fwd,
+		targetCluster: targetCluster,
+		streamID:      streamID,
+	}
+}
+
+// revoke closes the active flows matching the given function. Returns the number of closed flows.
+func (r *flowRecorder) revoke(match func(flow *activeFlow) bool) int {
+	r.lock.Lock()
+	var revoked []*forwarder
+	for _, active := range r.active {
+		if match(active) {
+			revoked = append(revoked, active.forwarder)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, fwd := range revoked {
+		fwd.revoke()
+	}
+	return len(revoked)
+}
+
+// end records the completion of a flow, and writes its record to the sink (if set).
+func (r *flowRecorder) end(id string) {
+	r.lock.Lock()
+	active, ok := r.active[id]
+	if !ok {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.active, id)
+
+	flow := active.snapshot()
+	endTime := time.Now()
+	flow.EndTime = &endTime
+	flow.CloseReason = active.forwarder.closeReason
+
+	r.completed = append(r.completed, flow)
+	if len(r.completed) > maxCompletedFlows {
+		r.completed = r.completed[len(r.completed)-maxCompletedFlows:]
+	}
+	sink := r.sink
+	r.lock.Unlock()
+
+	if sink == nil {
+		return
+	}
+	if err := sink.Write(&flow); err != nil {
+		r.logger.Warnf("Cannot write flow record: %v.", err)
+	}
+}
+
+// list returns the active and recently completed flows, ordered by start time.
+func (r *flowRecorder) list() []api.Flow {
+	r.lock.Lock()
+	flows := make([]api.Flow, 0, len(r.active)+len(r.completed))
+	for _, active := range r.active {
+		flows = append(flows, active.snapshot())
+	}
+	flows = append(flows, r.completed...)
+	r.lock.Unlock()
+
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].StartTime.Before(flows[j].StartTime)
+	})
+	return flows
+}
+
+func newFlowRecorder() *flowRecorder {
+	return &flowRecorder{
+		active: make(map[string]*activeFlow),
+		logger: logrus.WithField("component", "dataplane.flows"),
+	}
+}
+
+// newEgressFlow returns the record of an egress flow from a local client (at clientAddr)
+// to an import listener, forwarded to a remote peer cluster.
+func newEgressFlow(listenerName, targetCluster, protocol string, clientAddr net.Addr) *api.Flow {
+	name, namespace, port, _ := cpapi.ParseImportListenerName(listenerName)
+	flow := &api.Flow{
+		Direction: directionEgress,
+		Protocol:  protocol,
+		Import:    namespace + "/" + name,
+		Port:      port,
+		Peer:      strings.TrimPrefix(targetCluster, cpapi.RemotePeerClusterPrefix),
+	}
+
+	if clientAddr != nil {
+		if host, _, err := net.SplitHostPort(clientAddr.String()); err == nil {
+			flow.ClientIP = host
+		}
+	}
+
+	return flow
+}
+
+// newIngressFlow returns the record of an ingress flow from a remote peer to an export cluster.
+func newIngressFlow(targetCluster, peer, protocol string) *api.Flow {
+	name, namespace, port, _ := cpapi.ParseExportClusterName(strings.TrimPrefix(targetCluster, cpapi.ExportClusterPrefix))
+	return &api.Flow{
+		Direction: directionIngress,
+		Protocol:  protocol,
+		Export:    namespace + "/" + name,
+		Port:      port,
+		Peer:      peer,
+	}
+}
+
+// forward runs a forwarder until the forwarded connection is closed, recording it as a flow
+// to the given target cluster. Egress flows are identified by their access log stream ID.
+func (d *Dataplane) forward(fwd *forwarder, flow *api.Flow, targetCluster, streamID string) {
+	d.flows.start(flow, fwd, targetCluster, streamID)
+	defer d.flows.end(flow.ID)
+
+	fwd.run()
+}
+
+// SetFlowSink sets a sink for storing records of completed flows.
+// Must be called before the dataplane starts forwarding connections.
+func (d *Dataplane) SetFlowSink(sink FlowSink) {
+	d.flows.lock.Lock()
+	defer d.flows.lock.Unlock()
+
+	d.flows.sink = sink
+}
+
+// RegisterFlowsHandler registers an HTTP handler listing the active and recently completed flows.
+func (d *Dataplane) RegisterFlowsHandler(router chi.Router) {
+	router.Get(api.FlowsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.flows.list()); err != nil {
+			d.logger.Errorf("Cannot encode flows: %v.", err)
+		}
+	})
+}
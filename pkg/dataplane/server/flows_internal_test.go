@@ -0,0 +1,187 @@
+//go:build unix
+
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/dataplane/api"
+)
+
+// flowSink collects the flow records written to it.
+type flowSink struct {
+	flows chan *api.Flow
+}
+
+func (s *flowSink) Write(flow *api.Flow) error {
+	s.flows <- flow
+	return nil
+}
+
+func TestFlowRecords(t *testing.T) {
+	clientAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}
+	egressFlow := newEgressFlow("ns/svc:http", cpapi.RemotePeerClusterName("peer1"), protocolTCP, clientAddr)
+	require.Equal(t, directionEgress, egressFlow.Direction)
+	require.Equal(t, protocolTCP, egressFlow.Protocol)
+	require.Equal(t, "ns/svc", egressFlow.Import)
+	require.Equal(t, "http", egressFlow.Port)
+	require.Equal(t, "peer1", egressFlow.Peer)
+	require.Equal(t, "10.0.0.1", egressFlow.ClientIP)
+
+	ingressFlow := newIngressFlow(cpapi.ExportClusterName("svc", "ns", ""), "peer1", protocolUDP)
+	require.Equal(t, directionIngress, ingressFlow.Direction)
+	require.Equal(t, protocolUDP, ingressFlow.Protocol)
+	require.Equal(t, "ns/svc", ingressFlow.Export)
+	require.Equal(t, "", ingressFlow.Port)
+	require.Equal(t, "peer1", ingressFlow.Peer)
+}
+
+// recordedFlow is a flow forwarded between a workload and a peer, and recorded by a flow recorder.
+type recordedFlow struct {
+	flow     *api.Flow
+	workload net.Conn
+	peer     net.Conn
+	done     chan struct{}
+}
+
+// startFlow forwards a new egress flow, recording it until forwarding ends.
+func startFlow(t *testing.T, recorder *flowRecorder, targetCluster, streamID string) *recordedFlow {
+	workloadClient, workloadConn := tcpConnPair(t)
+	peerConn, peerServer := tcpConnPair(t)
+
+	listenerName := "ns/svc"
+	fwd := newForwarder(workloadConn, peerConn, egressConnectionLabels(listenerName, targetCluster))
+	flow := newEgressFlow(listenerName, targetCluster, protocolTCP, workloadClient.LocalAddr())
+	recorder.start(flow, fwd, targetCluster, streamID)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer recorder.end(flow.ID)
+		fwd.run()
+	}()
+
+	return &recordedFlow{flow: flow, workload: workloadClient, peer: peerServer, done: done}
+}
+
+func TestFlowRecorder(t *testing.T) {
+	recorder := newFlowRecorder()
+	sink := &flowSink{flows: make(chan *api.Flow, 2)}
+	recorder.sink = sink
+
+	targetCluster := cpapi.RemotePeerClusterName("peer1")
+	flow1 := startFlow(t, recorder, targetCluster, "stream1")
+	flow2 := startFlow(t, recorder, targetCluster, "stream2")
+	require.NotEqual(t, flow1.flow.ID, flow2.flow.ID)
+
+	// both flows are listed as active
+	flows := recorder.list()
+	require.Len(t, flows, 2)
+	for _, flow := range flows {
+		require.Nil(t, flow.EndTime)
+	}
+
+	// forwarded bytes are counted by the flow
+	_, err := flow1.workload.Write([]byte("data"))
+	require.Nil(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(flow1.peer, buf)
+	require.Nil(t, err)
+
+	// flow ends when the workload closes
+	require.Nil(t, flow1.workload.Close())
+	rest, err := io.ReadAll(flow1.peer)
+	require.Nil(t, err)
+	require.Empty(t, rest)
+	require.Nil(t, flow1.peer.Close())
+	<-flow1.done
+
+	record := <-sink.flows
+	require.Equal(t, flow1.flow.ID, record.ID)
+	require.NotNil(t, record.EndTime)
+	require.Equal(t, "workload closed", record.CloseReason)
+	require.Equal(t, int64(4), record.SentBytes)
+
+	// ending an unknown (or already ended) flow is ignored
+	recorder.end(flow1.flow.ID)
+	recorder.end("unknown")
+
+	flows = recorder.list()
+	require.Len(t, flows, 2)
+	require.Equal(t, flow1.flow.ID, flows[0].ID)
+	require.NotNil(t, flows[0].EndTime)
+	require.Equal(t, flow2.flow.ID, flows[1].ID)
+	require.Nil(t, flows[1].EndTime)
+
+	require.Nil(t, flow2.workload.Close())
+	require.Nil(t, flow2.peer.Close())
+	<-flow2.done
+	<-sink.flows
+}
+
+func TestFlowRecorderRevoke(t *testing.T) {
+	recorder := newFlowRecorder()
+
+	flow1 := startFlow(t, recorder, cpapi.RemotePeerClusterName("peer1"), "stream1")
+	flow2 := startFlow(t, recorder, cpapi.RemotePeerClusterName("peer2"), "stream2")
+
+	// no flow matches
+	require.Equal(t, 0, recorder.revoke(func(flow *activeFlow) bool { return flow.streamID == "unknown" }))
+
+	// revoke a flow by its stream ID
+	require.Equal(t, 1, recorder.revoke(func(flow *activeFlow) bool { return flow.streamID == "stream1" }))
+	<-flow1.done
+
+	// the connections of the revoked flow are closed
+	_, err := io.ReadAll(flow1.workload)
+	require.Nil(t, err)
+	_, err = io.ReadAll(flow1.peer)
+	require.Nil(t, err)
+
+	flows := recorder.list()
+	require.Len(t, flows, 2)
+	require.Equal(t, flow1.flow.ID, flows[0].ID)
+	require.Equal(t, errConnectionRevoked.Error(), flows[0].CloseReason)
+	require.NotNil(t, flows[0].EndTime)
+
+	// other flows are not affected
+	require.Nil(t, flows[1].EndTime)
+	_, err = flow2.workload.Write([]byte("data"))
+	require.Nil(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(flow2.peer, buf)
+	require.Nil(t, err)
+
+	// revoke a flow by its target cluster
+	require.Equal(t, 1, recorder.revoke(func(flow *activeFlow) bool {
+		return flow.targetCluster == cpapi.RemotePeerClusterName("peer2")
+	}))
+	<-flow2.done
+	require.Equal(t, errConnectionRevoked.Error(), recorder.list()[1].CloseReason)
+
+	// revoked flows are no longer active
+	require.Equal(t, 0, recorder.revoke(func(*activeFlow) bool { return true }))
+
+	require.Nil(t, flow1.workload.Close())
+	require.Nil(t, flow1.peer.Close())
+	require.Nil(t, flow2.workload.Close())
+	require.Nil(t, flow2.peer.Close())
+}
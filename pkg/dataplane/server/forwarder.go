@@ -15,25 +15,60 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"sync"
 	"sync/atomic"
-	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	dataBufferSize = 64 * 1024
-	readDeadline   = 10 * time.Millisecond
+	// spliceChunkSize is the maximal number of bytes spliced between updates of the byte counters.
+	spliceChunkSize = 1024 * 1024
+
+	sideWorkload = "workload"
+	sidePeer     = "peer"
 )
 
+// errConnectionRevoked is the close reason of connections revoked by the controlplane.
+var errConnectionRevoked = errors.New("connection revoked")
+
+// bufferPool holds the buffers used for copying data between connections.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, dataBufferSize)
+		return &buf
+	},
+}
+
+// closeWriter is implemented by connections supporting half-close (e.g. *net.TCPConn, *tls.Conn).
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// forwarder copies data between a workload connection and a peer connection, in both directions,
+// until both directions are closed. A direction reaching EOF is half-closed, if supported by the
+// destination connection. Otherwise, and on errors, both connections are closed.
 type forwarder struct {
 	workloadConn net.Conn
 	peerConn     net.Conn
-	closeSignal  atomic.Bool
-	logger       *logrus.Entry
+
+	// number of bytes forwarded from the workload to the peer, and from the peer to the workload
+	sent     atomic.Int64
+	received atomic.Int64
+	// closeReason is the reason the first of the two directions stopped forwarding
+	closeReason string
+	closeOnce   sync.Once
+
+	activeConnections prometheus.Gauge
+	sentBytes         prometheus.Counter
+	receivedBytes     prometheus.Counter
+
+	logger *logrus.Entry
 }
 
 type connDialer struct {
@@ -44,68 +79,115 @@ func (cd connDialer) Dial(_, _ string) (net.Conn, error) {
 	return cd.c, nil
 }
 
-func (f *forwarder) peerToWorkload() error {
-	bufData := make([]byte, dataBufferSize)
-	var err error
-	numBytes := 0
+// copyError is an error copying data from a source to a destination connection.
+type copyError struct {
+	// side is the side (workload or peer) of the connection which failed.
+	side string
+	err  error
+}
+
+func (e *copyError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.side, e.err)
+}
+
+func (e *copyError) Unwrap() error {
+	return e.err
+}
+
+// copyConn copies data from src to dst until src reaches EOF (returning nil) or an error occurs.
+// Data is spliced (copied within the kernel) if both connections are plain TCP connections,
+// and otherwise (e.g. for TLS peer connections) copied using a pooled buffer.
+func copyConn(dst, src net.Conn, dstSide, srcSide string, counter *atomic.Int64, metric prometheus.Counter) error {
+	if canSplice(dst, src) {
+		return spliceConn(dst, src, srcSide, counter, metric)
+	}
+
+	bufPtr := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(bufPtr)
+	buf := *bufPtr
+
 	for {
-		if f.closeSignal.Load() {
-			return nil
-		}
-		err = f.peerConn.SetReadDeadline(time.Now().Add(readDeadline))
-		if err != nil {
-			return err
-		}
-		numBytes, err = f.peerConn.Read(bufData)
-		if err != nil {
-			var netErr net.Error
-			if errors.As(err, &netErr) && netErr.Timeout() {
-				continue
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			written, err := dst.Write(buf[:n])
+			counter.Add(int64(written))
+			metric.Add(float64(written))
+			if err != nil {
+				return &copyError{side: dstSide, err: err}
 			}
-			break
 		}
-		_, err = f.workloadConn.Write(bufData[:numBytes]) // TODO: track actually written byte count
-		if err != nil {
-			break
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return &copyError{side: srcSide, err: readErr}
 		}
 	}
-	f.closeSignal.Swap(true)
-	if errors.Is(err, io.EOF) { // don't log EOF
-		return nil
-	}
-	return err
 }
 
-func (f *forwarder) workloadToPeer() error {
-	bufData := make([]byte, dataBufferSize)
-	var err error
-	numBytes := 0
+// canSplice returns whether data can be spliced from src to dst.
+func canSplice(dst, src net.Conn) bool {
+	_, dstTCP := dst.(*net.TCPConn)
+	_, srcTCP := src.(*net.TCPConn)
+	return dstTCP && srcTCP
+}
+
+// spliceConn copies data between TCP connections within the kernel, in chunks to keep the byte counters updated.
+func spliceConn(dst, src net.Conn, srcSide string, counter *atomic.Int64, metric prometheus.Counter) error {
+	reader := &io.LimitedReader{R: src}
 	for {
-		if f.closeSignal.Load() {
-			return nil
-		}
-		err = f.workloadConn.SetReadDeadline(time.Now().Add(readDeadline))
+		reader.N = spliceChunkSize
+		// *net.TCPConn.ReadFrom splices from a (limited) *net.TCPConn source
+		n, err := dst.(io.ReaderFrom).ReadFrom(reader)
+		counter.Add(n)
+		metric.Add(float64(n))
 		if err != nil {
-			return err
-		}
-		numBytes, err = f.workloadConn.Read(bufData)
-		if err != nil {
-			var netErr net.Error
-			if errors.As(err, &netErr) && netErr.Timeout() {
-				continue
-			}
-			break
+			// errors of either side are indistinguishable when splicing
+			return &copyError{side: srcSide, err: err}
 		}
-		_, err = f.peerConn.Write(bufData[:numBytes]) // TODO: track actually written byte count
-		if err != nil {
-			break
+		if n == 0 {
+			return nil
 		}
 	}
-	f.closeSignal.Swap(true)
-	if errors.Is(err, io.EOF) { // don't log EOF
-		return nil
+}
+
+// forward copies data in a single direction, and handles the direction completion.
+func (f *forwarder) forward(dst, src net.Conn, dstSide, srcSide string, counter *atomic.Int64, metric prometheus.Counter) {
+	err := copyConn(dst, src, dstSide, srcSide, counter, metric)
+	f.setCloseReason(srcSide, err)
+
+	if err == nil {
+		// propagate EOF by half-closing the destination, if supported
+		if cw, ok := dst.(closeWriter); ok {
+			if err := cw.CloseWrite(); err == nil {
+				return
+			}
+		}
+	} else if !errors.Is(err, net.ErrClosed) {
+		f.logger.Errorf("Error forwarding from %s to %s: %v.", srcSide, dstSide, err)
 	}
-	return err
+
+	// unblock the opposite direction
+	f.closeConnections()
+}
+
+// setCloseReason records the reason for closing the forwarded connection, unless already recorded.
+// A nil error means that the source side (workload or peer) closed the connection.
+func (f *forwarder) setCloseReason(srcSide string, err error) {
+	f.closeOnce.Do(func() {
+		if err == nil {
+			f.closeReason = srcSide + " closed"
+		} else {
+			f.closeReason = err.Error()
+		}
+	})
+}
+
+// revoke closes the forwarded connection, as it is no longer allowed.
+func (f *forwarder) revoke() {
+	f.setCloseReason("", errConnectionRevoked)
+	f.closeConnections()
 }
 
 func (f *forwarder) closeConnections() {
@@ -118,34 +200,34 @@ func (f *forwarder) closeConnections() {
 }
 
 func (f *forwarder) run() {
+	f.activeConnections.Inc()
+	defer f.activeConnections.Dec()
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := f.workloadToPeer()
-		if err != nil {
-			f.logger.Errorf("Error in workload to peer connection: %v.", err)
-		}
+		f.forward(f.peerConn, f.workloadConn, sidePeer, sideWorkload, &f.sent, f.sentBytes)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := f.peerToWorkload()
-		if err != nil {
-			f.logger.Errorf("Error in peer to workload connection: %v.", err)
-		}
+		f.forward(f.workloadConn, f.peerConn, sideWorkload, sidePeer, &f.received, f.receivedBytes)
 	}()
 
 	wg.Wait()
 	f.closeConnections()
 }
 
-func newForwarder(workloadConn, peerConn net.Conn) *forwarder {
+func newForwarder(workloadConn, peerConn net.Conn, labels connectionLabels) *forwarder {
 	return &forwarder{
-		workloadConn: workloadConn,
-		peerConn:     peerConn,
-		logger:       logrus.WithField("component", "dataplane.forwarder"),
+		workloadConn:      workloadConn,
+		peerConn:          peerConn,
+		activeConnections: activeConnectionsMetric.WithLabelValues(labels.values()...),
+		sentBytes:         sentBytesMetric.WithLabelValues(labels.values()...),
+		receivedBytes:     receivedBytesMetric.WithLabelValues(labels.values()...),
+		logger:            logrus.WithField("component", "dataplane.forwarder"),
 	}
 }
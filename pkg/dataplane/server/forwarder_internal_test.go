@@ -0,0 +1,308 @@
+//go:build unix
+
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// opaqueConn hides the concrete type of a TCP connection, preventing splicing.
+type opaqueConn struct {
+	net.Conn
+}
+
+// CloseWrite half-closes the connection.
+func (c opaqueConn) CloseWrite() error {
+	return c.Conn.(*net.TCPConn).CloseWrite()
+}
+
+// tcpConnPair returns the two ends of a loopback TCP connection.
+func tcpConnPair(tb testing.TB) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(tb, err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.Nil(tb, err)
+
+	server, err := listener.Accept()
+	require.Nil(tb, err)
+
+	return client, server
+}
+
+// forwardFunc forwards data between a workload connection and a peer connection, until both are closed.
+type forwardFunc func(workloadConn, peerConn net.Conn)
+
+// blockingForward forwards using the forwarder.
+func blockingForward(workloadConn, peerConn net.Conn) {
+	newForwarder(workloadConn, peerConn, egressConnectionLabels("default/bench", "remote-peer-bench")).run()
+}
+
+// pollingForward is the former forwarder implementation, which polls using short read deadlines
+// for noticing that the opposite direction was closed. It is kept as a benchmark baseline.
+func pollingForward(workloadConn, peerConn net.Conn) {
+	var closeSignal atomic.Bool
+	copyLoop := func(dst, src net.Conn) {
+		defer closeSignal.Store(true)
+
+		buf := make([]byte, dataBufferSize)
+		for !closeSignal.Load() {
+			if err := src.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+				return
+			}
+
+			n, err := src.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					continue
+				}
+				return
+			}
+
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		copyLoop(peerConn, workloadConn)
+	}()
+	go func() {
+		defer wg.Done()
+		copyLoop(workloadConn, peerConn)
+	}()
+	wg.Wait()
+
+	workloadConn.Close()
+	peerConn.Close()
+}
+
+// forwardedPair sets up a connection from a workload to a peer, forwarded by the given function.
+// If opaque is set, the forwarded connections are not spliceable.
+// Returns the workload (client) end, the peer (server) end, and a channel closed when forwarding ends.
+func forwardedPair(tb testing.TB, forward forwardFunc, opaque bool) (net.Conn, net.Conn, <-chan struct{}) {
+	workloadClient, workloadConn := tcpConnPair(tb)
+	peerConn, peerServer := tcpConnPair(tb)
+	if opaque {
+		workloadConn = opaqueConn{workloadConn}
+		peerConn = opaqueConn{peerConn}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		forward(workloadConn, peerConn)
+	}()
+
+	return workloadClient, peerServer, done
+}
+
+func TestForwarderHalfClose(t *testing.T) {
+	for _, opaque := range []bool{false, true} {
+		workload, peer, done := forwardedPair(t, blockingForward, opaque)
+
+		// the workload sends a request and half-closes its connection
+		_, err := workload.Write([]byte("request"))
+		require.Nil(t, err)
+		require.Nil(t, workload.(*net.TCPConn).CloseWrite())
+
+		// the peer reads the request until EOF, and can still respond
+		request, err := io.ReadAll(peer)
+		require.Nil(t, err)
+		require.Equal(t, "request", string(request))
+
+		_, err = peer.Write([]byte("response"))
+		require.Nil(t, err)
+		require.Nil(t, peer.Close())
+
+		response, err := io.ReadAll(workload)
+		require.Nil(t, err)
+		require.Equal(t, "response", string(response))
+
+		<-done
+		require.Nil(t, workload.Close())
+	}
+}
+
+func TestForwarderCloseReason(t *testing.T) {
+	workloadClient, workloadConn := tcpConnPair(t)
+	peerConn, peerServer := tcpConnPair(t)
+	fwd := newForwarder(workloadConn, peerConn, egressConnectionLabels("default/test", "remote-peer-test"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fwd.run()
+	}()
+
+	_, err := peerServer.Write([]byte("data"))
+	require.Nil(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(workloadClient, buf)
+	require.Nil(t, err)
+
+	// the peer closing is propagated to the workload
+	require.Nil(t, peerServer.Close())
+	rest, err := io.ReadAll(workloadClient)
+	require.Nil(t, err)
+	require.Empty(t, rest)
+
+	require.Nil(t, workloadClient.Close())
+	<-done
+
+	require.Equal(t, "peer closed", fwd.closeReason)
+	require.Equal(t, int64(4), fwd.received.Load())
+	require.Equal(t, int64(0), fwd.sent.Load())
+}
+
+func TestForwarderSplice(t *testing.T) {
+	workloadClient, workloadConn := tcpConnPair(t)
+	peerConn, peerServer := tcpConnPair(t)
+	require.True(t, canSplice(peerConn, workloadConn))
+	require.False(t, canSplice(opaqueConn{peerConn}, workloadConn))
+
+	fwd := newForwarder(workloadConn, peerConn, egressConnectionLabels("default/test", "remote-peer-test"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fwd.run()
+	}()
+
+	// data spanning multiple chunks is spliced completely
+	data := make([]byte, 2*spliceChunkSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	writeErr := make(chan error, 1)
+	go func() {
+		if _, err := workloadClient.Write(data); err != nil {
+			writeErr <- err
+			return
+		}
+		writeErr <- workloadClient.(*net.TCPConn).CloseWrite()
+	}()
+
+	received, err := io.ReadAll(peerServer)
+	require.Nil(t, err)
+	require.Nil(t, <-writeErr)
+	require.Equal(t, data, received)
+
+	require.Nil(t, peerServer.Close())
+	<-done
+	require.Nil(t, workloadClient.Close())
+
+	require.Equal(t, "workload closed", fwd.closeReason)
+	require.Equal(t, int64(len(data)), fwd.sent.Load())
+	require.Equal(t, int64(0), fwd.received.Load())
+}
+
+// benchmarkThroughput measures the throughput of forwarding data from a workload to a peer.
+func benchmarkThroughput(b *testing.B, forward forwardFunc, opaque bool) {
+	workload, peer, done := forwardedPair(b, forward, opaque)
+
+	received := make(chan int64)
+	go func() {
+		n, _ := io.Copy(io.Discard, peer)
+		received <- n
+	}()
+
+	data := make([]byte, dataBufferSize)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := workload.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	require.Nil(b, workload.(*net.TCPConn).CloseWrite())
+	n := <-received
+
+	b.StopTimer()
+	require.Equal(b, int64(b.N*len(data)), n)
+
+	workload.Close()
+	peer.Close()
+	<-done
+}
+
+func BenchmarkForwarderThroughput(b *testing.B) {
+	b.Run("polling", func(b *testing.B) { benchmarkThroughput(b, pollingForward, true) })
+	b.Run("copy", func(b *testing.B) { benchmarkThroughput(b, blockingForward, true) })
+	b.Run("splice", func(b *testing.B) { benchmarkThroughput(b, blockingForward, false) })
+}
+
+// cpuTime returns the CPU time (user and system) consumed by the process.
+func cpuTime(b *testing.B) time.Duration {
+	var usage syscall.Rusage
+	require.Nil(b, syscall.Getrusage(syscall.RUSAGE_SELF, &usage))
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// benchmarkIdle measures the CPU time consumed by idle forwarded connections.
+// Each iteration keeps the connections idle for idlePeriod.
+func benchmarkIdle(b *testing.B, forward forwardFunc) {
+	const (
+		connections = 100
+		idlePeriod  = 10 * time.Millisecond
+	)
+
+	conns := make([]net.Conn, 0, 2*connections)
+	dones := make([]<-chan struct{}, 0, connections)
+	for i := 0; i < connections; i++ {
+		workload, peer, done := forwardedPair(b, forward, true)
+		conns = append(conns, workload, peer)
+		dones = append(dones, done)
+	}
+
+	start := cpuTime(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		time.Sleep(idlePeriod)
+	}
+
+	b.StopTimer()
+	b.ReportMetric(float64(cpuTime(b)-start)/float64(b.N*connections), "cpu-ns/conn/op")
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	for _, done := range dones {
+		<-done
+	}
+}
+
+func BenchmarkForwarderIdle(b *testing.B) {
+	b.Run("polling", func(b *testing.B) { benchmarkIdle(b, pollingForward) })
+	b.Run("blocking", func(b *testing.B) { benchmarkIdle(b, blockingForward) })
+}
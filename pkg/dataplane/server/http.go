@@ -0,0 +1,330 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	// maxRetryBodySize is the maximal size of a request body buffered for retrying the request.
+	maxRetryBodySize = 64 * 1024
+	// httpReadHeaderTimeout is the time allowed for reading the headers of HTTP requests to imported services.
+	httpReadHeaderTimeout = 10 * time.Second
+)
+
+// ingressHeadersToRemove are the headers removed from HTTP requests forwarded to exported services.
+var ingressHeadersToRemove = []string{
+	cpapi.PeerAuthorizationHeader,
+	cpapi.ImportNameHeader,
+	cpapi.ImportNamespaceHeader,
+	cpapi.ImportPortHeader,
+	cpapi.ClientIPHeader,
+	cpapi.ImportSourceHeader,
+}
+
+// egressAuthError is an error authorizing an HTTP request to an imported service.
+type egressAuthError struct {
+	err error
+}
+
+func (e *egressAuthError) Error() string {
+	return e.err.Error()
+}
+
+func (e *egressAuthError) Unwrap() error {
+	return e.err
+}
+
+// httpListenerRetries returns whether a listener forwards HTTP requests (rather than connections),
+// and the number of retries of failed requests.
+func httpListenerRetries(ln *listener.Listener) (uint32, bool) {
+	for _, chain := range ln.GetFilterChains() {
+		for _, filter := range chain.GetFilters() {
+			if filter.GetName() != wellknown.HTTPConnectionManager {
+				continue
+			}
+
+			var config hcm.HttpConnectionManager
+			if err := filter.GetTypedConfig().UnmarshalTo(&config); err != nil {
+				return 0, true
+			}
+
+			var retries uint32
+			for _, virtualHost := range config.GetRouteConfig().GetVirtualHosts() {
+				for _, route := range virtualHost.GetRoutes() {
+					retries = max(retries, route.GetRoute().GetRetryPolicy().GetNumRetries().GetValue())
+				}
+			}
+
+			return retries, true
+		}
+	}
+
+	return 0, false
+}
+
+// CreateHTTPListener starts a listener to an imported service with appProtocol http.
+func (d *Dataplane) CreateHTTPListener(name, ip string, port, retries uint32) {
+	listenTarget := ip + ":" + strconv.Itoa(int(port))
+	d.listenerEnd[name] = make(chan bool)
+	d.logger.Infof("Starting an HTTP listener for imported service %s at %s.", name, listenTarget)
+	acceptor, err := net.Listen("tcp", listenTarget)
+	if err != nil {
+		d.logger.Infof("Error listening to port: %v.", err)
+		return
+	}
+
+	server := &http.Server{
+		Handler:           d.newEgressProxy(name, retries),
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+	}
+	go func() {
+		if err := server.Serve(acceptor); err != nil && !errors.Is(err, net.ErrClosed) {
+			d.logger.Errorf("Failed to serve egress requests on %s: %+v.", listenTarget, err)
+		}
+	}()
+	<-d.listenerEnd[name]
+	d.logger.Infof("Ending the HTTP listener for imported service %s at %s.", name, listenTarget)
+	acceptor.Close()
+}
+
+// newEgressProxy returns a proxy for HTTP requests to an imported service.
+// Each request is authorized, and hence routed to a source, separately.
+func (d *Dataplane) newEgressProxy(listenerName string, retries uint32) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// the target peer is set by the transport, for each attempt of the request
+			pr.SetXForwarded()
+		},
+		Transport: &egressTransport{
+			dataplane:    d,
+			listenerName: listenerName,
+			retries:      retries,
+		},
+		FlushInterval: -1, // flush immediately, for streaming responses
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			status := http.StatusBadGateway
+			if errors.As(err, new(*egressAuthError)) {
+				status = http.StatusForbidden
+			}
+
+			d.logger.Infof("Failed forwarding request to imported service %s: %v.", listenerName, err)
+			http.Error(w, err.Error(), status)
+		},
+	}
+}
+
+// egressTransport sends HTTP requests of an imported service to the remote peers.
+type egressTransport struct {
+	dataplane    *Dataplane
+	listenerName string
+	// retries is the maximal number of times a failed request is retried
+	retries uint32
+}
+
+// RoundTrip sends a request to the source peer selected by the controlplane, retrying failed requests.
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retries := t.retries
+	if retries > 0 {
+		replayable, err := bufferRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if !replayable {
+			retries = 0
+		}
+	}
+
+	clientIP, _, _ := net.SplitHostPort(req.RemoteAddr)
+	headers := requestHeaders(req)
+
+	for attempt := uint32(0); ; attempt++ {
+		resp, err := t.attempt(req, clientIP, headers)
+		if attempt >= retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		t.dataplane.logger.Debugf("Retrying request to imported service %s: %v.", t.listenerName, err)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// attempt authorizes a request, and sends it to the selected source peer.
+func (t *egressTransport) attempt(req *http.Request, clientIP string, headers map[string]string) (*http.Response, error) {
+	d := t.dataplane
+	targetCluster, accessToken, sourceExport, err := d.getEgressAuth(t.listenerName, clientIP, headers)
+	if err != nil {
+		return nil, &egressAuthError{err: err}
+	}
+
+	targetHostname, err := d.GetClusterHostname(targetCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	peerReq := req.Clone(req.Context())
+	peerReq.URL.Scheme = "https"
+	peerReq.URL.Host = targetHostname
+	peerReq.Host = ""
+	peerReq.Header.Set(cpapi.PeerAuthorizationHeader, accessToken)
+
+	start := time.Now()
+	resp, err := d.peerRoundTrip(targetCluster, peerReq)
+	if err != nil {
+		d.accessLogger.connectionFailed(t.listenerName, targetCluster, sourceExport, http.StatusServiceUnavailable)
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		d.accessLogger.connectionFailed(t.listenerName, targetCluster, sourceExport, resp.StatusCode)
+		return resp, nil
+	}
+
+	// the request is reported as a connection, ending once its response is read
+	streamID := d.accessLogger.connectionEstablished(
+		t.listenerName, targetCluster, sourceExport, clientIP, time.Since(start))
+	resp.Body = &loggedBody{
+		ReadCloser: resp.Body,
+		onClose: func() {
+			d.accessLogger.connectionEnded(streamID, t.listenerName, targetCluster)
+		},
+	}
+	return resp, nil
+}
+
+// shouldRetry returns whether a request attempt failed, and may be retried.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.As(err, new(*egressAuthError))
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// loggedBody is a response body which reports the end of its request once closed.
+type loggedBody struct {
+	io.ReadCloser
+	onClose   func()
+	closeOnce sync.Once
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeOnce.Do(b.onClose)
+	return err
+}
+
+// bufferRequestBody buffers a request body in memory, to be resent on retries.
+// Returns false if the body is too large to be buffered.
+func bufferRequestBody(req *http.Request) (bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
+	if err != nil {
+		return false, fmt.Errorf("cannot read request body: %w", err)
+	}
+
+	if len(body) > maxRetryBodySize {
+		// send the buffered part, followed by the rest of the body
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		return false, nil
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true, nil
+}
+
+// requestHeaders returns the headers of a request, keyed by their lowercase names, as seen by Envoy.
+func requestHeaders(req *http.Request) map[string]string {
+	headers := make(map[string]string, len(req.Header))
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	return headers
+}
+
+// isHTTPRequest returns whether an ingress request is an HTTP request to an exported service,
+// rather than a request for tunneling a connection.
+func isHTTPRequest(r *http.Request) bool {
+	return r.Header.Get(cpapi.PeerAuthorizationHeader) != "" &&
+		r.Method != http.MethodConnect && !isConnectUDPRequest(r)
+}
+
+// proxyIngressRequest forwards an HTTP request from a remote peer to an exported service.
+func (d *Dataplane) proxyIngressRequest(w http.ResponseWriter, r *http.Request, serviceTarget string) {
+	// the server read and write timeouts apply to the forwarded request and response, and must be cleared
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		d.logger.Warnf("Cannot clear read deadline: %v.", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		d.logger.Warnf("Cannot clear write deadline: %v.", err)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(&url.URL{Scheme: "http", Host: serviceTarget})
+			// keep the forwarding headers set by the client peer
+			for _, header := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"} {
+				if values, ok := pr.In.Header[header]; ok {
+					pr.Out.Header[header] = values
+				}
+			}
+			for _, header := range ingressHeadersToRemove {
+				pr.Out.Header.Del(header)
+			}
+		},
+		FlushInterval: -1, // flush immediately, for streaming responses
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			d.logger.Errorf("Failed forwarding request to exported service at %s: %v.", serviceTarget, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	proxy.ServeHTTP(w, r)
+}
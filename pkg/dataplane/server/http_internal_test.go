@@ -0,0 +1,167 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// startHTTPPeer starts a peer server responding with the protocol of each request,
+// optionally supporting HTTP/2. Returns the peer address, and a counter of the accepted TLS connections.
+func startHTTPPeer(t *testing.T, tlsConfig *tls.Config, withHTTP2 bool) (string, *atomic.Int32) {
+	var connections atomic.Int32
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.Proto+" "+r.Header.Get(cpapi.PeerAuthorizationHeader))
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		},
+	}
+	server.TLSConfig = tlsConfig.Clone()
+	if withHTTP2 {
+		require.Nil(t, http2.ConfigureServer(server, &http2.Server{}))
+	} else {
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go func() {
+		_ = server.ServeTLS(listener, "", "")
+	}()
+	t.Cleanup(func() { server.Close() })
+
+	return listener.Addr().String(), &connections
+}
+
+// requirePeerResponse sends a request to the test peer, and checks the response.
+func requirePeerResponse(t *testing.T, d *Dataplane, expected string) {
+	req, err := http.NewRequest(http.MethodGet, "https://"+testPeer+"/", http.NoBody)
+	require.Nil(t, err)
+	req.Header.Set(cpapi.PeerAuthorizationHeader, "token")
+
+	resp, err := d.peerRoundTrip(testPeer, req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, expected, string(body))
+}
+
+func TestPeerRoundTrip(t *testing.T) {
+	tlsConfig := testTLSConfig(t)
+	d := newTestDataplane(tlsConfig)
+	address, connections := startHTTPPeer(t, tlsConfig, true)
+	addPeerCluster(t, d, address)
+
+	// requests share a single TLS connection
+	requirePeerResponse(t, d, "HTTP/2.0 token")
+	requirePeerResponse(t, d, "HTTP/2.0 token")
+	require.Equal(t, int32(1), connections.Load())
+
+	// multiplexing disabled
+	d.SetPeerMultiplexing(false)
+	requirePeerResponse(t, d, "HTTP/1.1 token")
+	require.Equal(t, int32(2), connections.Load())
+}
+
+func TestPeerRoundTripFallback(t *testing.T) {
+	tlsConfig := testTLSConfig(t)
+	d := newTestDataplane(tlsConfig)
+	address, connections := startHTTPPeer(t, tlsConfig, false)
+	addPeerCluster(t, d, address)
+
+	requirePeerResponse(t, d, "HTTP/1.1 token")
+	// the HTTP/2 probe, and the HTTP/1.1 connection
+	require.Equal(t, int32(2), connections.Load())
+
+	// the HTTP/1.1 connection is reused
+	requirePeerResponse(t, d, "HTTP/1.1 token")
+	require.Equal(t, int32(2), connections.Load())
+}
+
+func TestBufferRequestBody(t *testing.T) {
+	// small bodies can be replayed
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data"))
+	replayable, err := bufferRequestBody(req)
+	require.Nil(t, err)
+	require.True(t, replayable)
+	for range 2 {
+		body, err := io.ReadAll(req.Body)
+		require.Nil(t, err)
+		require.Equal(t, "data", string(body))
+		req.Body, err = req.GetBody()
+		require.Nil(t, err)
+	}
+
+	// large bodies are sent as is
+	data := strings.Repeat("x", maxRetryBodySize+1)
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
+	replayable, err = bufferRequestBody(req)
+	require.Nil(t, err)
+	require.False(t, replayable)
+	body, err := io.ReadAll(req.Body)
+	require.Nil(t, err)
+	require.Equal(t, data, string(body))
+}
+
+func TestProxyIngressRequest(t *testing.T) {
+	var serviceReq *http.Request
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serviceReq = r
+		_, _ = io.WriteString(w, "response")
+	}))
+	defer service.Close()
+
+	d := newTestDataplane(nil)
+	req := httptest.NewRequest(http.MethodGet, "/path", http.NoBody)
+	req.Header.Set(cpapi.PeerAuthorizationHeader, "token")
+	req.Header.Set(cpapi.ImportNameHeader, "import")
+	req.Header.Set("X-Forwarded-Host", "svc.example")
+	req.Header.Set("X-App", "value")
+	require.True(t, isHTTPRequest(req))
+
+	recorder := httptest.NewRecorder()
+	d.proxyIngressRequest(recorder, req, strings.TrimPrefix(service.URL, "http://"))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "response", recorder.Body.String())
+
+	require.Equal(t, "/path", serviceReq.URL.Path)
+	require.Equal(t, "value", serviceReq.Header.Get("X-App"))
+	require.Equal(t, "svc.example", serviceReq.Header.Get("X-Forwarded-Host"))
+	require.Empty(t, serviceReq.Header.Get(cpapi.PeerAuthorizationHeader))
+	require.Empty(t, serviceReq.Header.Get(cpapi.ImportNameHeader))
+
+	// tunneling requests are not proxied
+	req = httptest.NewRequest(http.MethodConnect, "/", http.NoBody)
+	req.Header.Set(cpapi.PeerAuthorizationHeader, "token")
+	require.False(t, isHTTPRequest(req))
+}
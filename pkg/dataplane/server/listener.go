@@ -15,6 +15,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strconv"
@@ -22,8 +23,11 @@ import (
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 // CreateListener starts a listener to an imported service.
@@ -59,7 +63,8 @@ func (d *Dataplane) serveEgressConnections(name string, listener net.Listener) e
 			"Received an egress connection at listener for imported service %s from %s.", name, conn.RemoteAddr().String())
 		d.logger.Debugf("Connection: %+v.", conn)
 
-		targetPeer, accessToken, err := d.getEgressAuth(name, strings.Split(conn.RemoteAddr().String(), ":")[0])
+		targetPeer, accessToken, sourceExport, err := d.getEgressAuth(
+			name, strings.Split(conn.RemoteAddr().String(), ":")[0], nil)
 		if err != nil {
 			d.logger.Infof("Failed egress authorization: %v.", err)
 			conn.Close()
@@ -67,21 +72,15 @@ func (d *Dataplane) serveEgressConnections(name string, listener net.Listener) e
 		}
 		d.logger.Infof("Received auth from controlplane: target peer: %s with %s", targetPeer, accessToken)
 
-		targetHost, err := d.GetClusterHost(targetPeer)
+		tlsConfig, err := d.peerTLSConfig(targetPeer)
 		if err != nil {
 			d.logger.Errorf("Unable to get cluster host :%v.", err)
 			conn.Close()
 			continue
 		}
 
-		d.tlsConfigLock.RLock()
-		tlsConfig := d.tlsConfig.Clone()
-		d.tlsConfigLock.RUnlock()
-
-		tlsConfig.ServerName = targetHost
-
 		go func() {
-			err := d.initiateEgressConnection(targetPeer, accessToken, conn, tlsConfig)
+			err := d.initiateEgressConnection(name, targetPeer, sourceExport, accessToken, conn, tlsConfig)
 			if err != nil {
 				d.logger.Errorf("Failed to initiate egress connection: %v.", err)
 				conn.Close()
@@ -90,9 +89,49 @@ func (d *Dataplane) serveEgressConnections(name string, listener net.Listener) e
 	}
 }
 
-// getEgressAuth returns the target cluster and authorization token for the outgoing connection.
-func (d *Dataplane) getEgressAuth(name, sourceIP string) (string, string, error) { //nolint:gocritic // unnamedResult
-	components := strings.SplitN(name, "/", 2)
+// peerTLSConfig returns the TLS configuration for connecting to the given peer cluster.
+func (d *Dataplane) peerTLSConfig(targetPeer string) (*tls.Config, error) {
+	targetHost, err := d.GetClusterHost(targetPeer)
+	if err != nil {
+		return nil, err
+	}
+
+	d.tlsConfigLock.RLock()
+	tlsConfig := d.tlsConfig.Clone()
+	d.tlsConfigLock.RUnlock()
+
+	tlsConfig.ServerName = targetHost
+	return tlsConfig, nil
+}
+
+// getEgressAuth returns the target cluster, authorization token and exported service (of the selected import source)
+// for the outgoing connection. For HTTP requests, headers holds the (lowercase) request headers.
+func (d *Dataplane) getEgressAuth(
+	name, sourceIP string,
+	headers map[string]string,
+) (targetCluster, accessToken, sourceExport string, err error) {
+	importName, importNamespace, importPort, err := api.ParseImportListenerName(name)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// each egress connection starts a new trace
+	ctx, span := tracing.Start(context.Background(), "dataplane.getEgressAuth",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("clusterlink.import", importNamespace+"/"+importName),
+			attribute.String("clusterlink.port", importPort)))
+	defer func() { tracing.End(span, err) }()
+
+	authzHeaders := make(map[string]string, len(headers)+4)
+	for name, value := range headers {
+		authzHeaders[name] = value
+	}
+	authzHeaders[api.ImportNamespaceHeader] = importNamespace
+	authzHeaders[api.ImportNameHeader] = importName
+	authzHeaders[api.ImportPortHeader] = importPort
+	authzHeaders[api.ClientIPHeader] = sourceIP
+
 	authzReq := &authv3.CheckRequest{
 		Attributes: &authv3.AttributeContext{
 			Source: &authv3.AttributeContext_Peer{
@@ -104,47 +143,45 @@ func (d *Dataplane) getEgressAuth(name, sourceIP string) (string, string, error)
 			},
 			Request: &authv3.AttributeContext_Request{
 				Http: &authv3.AttributeContext_HttpRequest{
-					Headers: map[string]string{
-						api.ImportNamespaceHeader: components[0],
-						api.ImportNameHeader:      components[1],
-						api.ClientIPHeader:        sourceIP,
-					},
+					Headers: authzHeaders,
 				},
 			},
 		},
 	}
 
-	resp, err := d.authzClient.Check(context.Background(), authzReq)
+	resp, err := d.authzClient.Check(tracing.InjectGRPC(ctx), authzReq)
 	if err != nil {
 		d.logger.Errorf("Error authorizing egress request: %v.", err)
-		return "", "", err
+		return "", "", "", err
 	}
 
 	okResp, ok := resp.HttpResponse.(*authv3.CheckResponse_OkResponse)
 	if !ok {
 		if deniedResp, denied := resp.HttpResponse.(*authv3.CheckResponse_DeniedResponse); denied {
-			return "", "", fmt.Errorf("egress connection denied: %s", deniedResp.DeniedResponse.Body)
+			return "", "", "", fmt.Errorf("egress connection denied: %s", deniedResp.DeniedResponse.Body)
 		}
-		return "", "", fmt.Errorf("unknown authorization response: %+v", resp)
+		return "", "", "", fmt.Errorf("unknown authorization response: %+v", resp)
 	}
 
-	// get target and access token from response headers
-	var accessToken, targetCluster string
+	// get target, access token and source export from response headers
 	for _, header := range okResp.OkResponse.Headers {
-		if header.Header.Key == api.TargetClusterHeader {
+		switch header.Header.Key {
+		case api.TargetClusterHeader:
 			targetCluster = header.Header.Value
-		} else if header.Header.Key == api.AuthorizationHeader {
+		case api.AuthorizationHeader, api.PeerAuthorizationHeader:
 			accessToken = header.Header.Value
+		case api.ImportSourceHeader:
+			sourceExport = header.Header.Value
 		}
 	}
 
 	if targetCluster == "" {
-		return "", "", fmt.Errorf("missing target cluster")
+		return "", "", "", fmt.Errorf("missing target cluster")
 	}
 
 	if accessToken == "" {
-		return "", "", fmt.Errorf("missing access token")
+		return "", "", "", fmt.Errorf("missing access token")
 	}
 
-	return targetCluster, accessToken, nil
+	return targetCluster, accessToken, sourceExport, nil
 }
@@ -0,0 +1,108 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	metricsNamespace = "clusterlink"
+	metricsSubsystem = "dataplane"
+
+	// directionEgress marks connections from local clients to imported services.
+	directionEgress = "egress"
+	// directionIngress marks connections from remote peers to exported services.
+	directionIngress = "ingress"
+)
+
+var (
+	activeConnectionsMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "active_connections",
+		Help: "Number of active connections, by direction, service (import for egress, export for ingress) " +
+			"and remote peer.",
+	}, []string{"direction", "namespace", "service", "peer"})
+
+	sentBytesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "sent_bytes_total",
+		Help:      "Number of bytes sent to remote peers, by direction, service and remote peer.",
+	}, []string{"direction", "namespace", "service", "peer"})
+
+	receivedBytesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "received_bytes_total",
+		Help:      "Number of bytes received from remote peers, by direction, service and remote peer.",
+	}, []string{"direction", "namespace", "service", "peer"})
+
+	rejectedConnectionsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "rejected_connections_total",
+		Help:      "Number of ingress connections rejected due to the export concurrent connections limit.",
+	}, []string{"namespace", "export"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		activeConnectionsMetric,
+		sentBytesMetric,
+		receivedBytesMetric,
+		rejectedConnectionsMetric,
+	)
+}
+
+// connectionLabels are the metric labels of a forwarded connection.
+type connectionLabels struct {
+	direction string
+	namespace string
+	service   string
+	peer      string
+}
+
+func (l connectionLabels) values() []string {
+	return []string{l.direction, l.namespace, l.service, l.peer}
+}
+
+// egressConnectionLabels returns the metric labels of an egress connection
+// from an import listener to a remote peer cluster.
+func egressConnectionLabels(listenerName, targetCluster string) connectionLabels {
+	name, namespace, _, _ := cpapi.ParseImportListenerName(listenerName)
+	return connectionLabels{
+		direction: directionEgress,
+		namespace: namespace,
+		service:   name,
+		peer:      strings.TrimPrefix(targetCluster, cpapi.RemotePeerClusterPrefix),
+	}
+}
+
+// ingressConnectionLabels returns the metric labels of an ingress connection
+// from a remote peer to an export cluster.
+func ingressConnectionLabels(targetCluster, peer string) connectionLabels {
+	name, namespace, _, _ := cpapi.ParseExportClusterName(strings.TrimPrefix(targetCluster, cpapi.ExportClusterPrefix))
+	return connectionLabels{
+		direction: directionIngress,
+		namespace: namespace,
+		service:   name,
+		peer:      peer,
+	}
+}
@@ -0,0 +1,80 @@
+//go:build unix
+
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+func TestConnectionLabels(t *testing.T) {
+	labels := egressConnectionLabels("ns/svc:http", cpapi.RemotePeerClusterName("peer1"))
+	require.Equal(t, []string{directionEgress, "ns", "svc", "peer1"}, labels.values())
+
+	labels = ingressConnectionLabels(cpapi.ExportClusterName("svc", "ns", "http"), "peer1")
+	require.Equal(t, []string{directionIngress, "ns", "svc", "peer1"}, labels.values())
+}
+
+func TestForwarderMetrics(t *testing.T) {
+	labels := ingressConnectionLabels(cpapi.ExportClusterName("metrics", "ns", ""), "peer1")
+	activeConnections := activeConnectionsMetric.WithLabelValues(labels.values()...)
+	sentBytes := sentBytesMetric.WithLabelValues(labels.values()...)
+	receivedBytes := receivedBytesMetric.WithLabelValues(labels.values()...)
+	sentBefore := testutil.ToFloat64(sentBytes)
+	receivedBefore := testutil.ToFloat64(receivedBytes)
+
+	// spliced connections update their byte counters only per chunk, hence splicing is prevented
+	workloadClient, workloadConn := tcpConnPair(t)
+	peerConn, peerServer := tcpConnPair(t)
+	fwd := newForwarder(opaqueConn{workloadConn}, opaqueConn{peerConn}, labels)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fwd.run()
+	}()
+
+	// data forwarded in both directions is counted while the connection is active
+	_, err := workloadClient.Write([]byte("request"))
+	require.Nil(t, err)
+	buf := make([]byte, len("request"))
+	_, err = io.ReadFull(peerServer, buf)
+	require.Nil(t, err)
+
+	_, err = peerServer.Write([]byte("response!"))
+	require.Nil(t, err)
+	buf = make([]byte, len("response!"))
+	_, err = io.ReadFull(workloadClient, buf)
+	require.Nil(t, err)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(activeConnections))
+	require.Equal(t, sentBefore+float64(len("request")), testutil.ToFloat64(sentBytes))
+	require.Equal(t, receivedBefore+float64(len("response!")), testutil.ToFloat64(receivedBytes))
+
+	// the connection is no longer active once closed
+	fwd.revoke()
+	<-done
+	require.Equal(t, float64(0), testutil.ToFloat64(activeConnections))
+	require.Equal(t, sentBefore+float64(len("request")), testutil.ToFloat64(sentBytes))
+
+	require.Nil(t, workloadClient.Close())
+	require.Nil(t, peerServer.Close())
+}
@@ -0,0 +1,324 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	// peerPingInterval is the idle time after which a multiplexed peer connection is health-checked using a ping.
+	peerPingInterval = 30 * time.Second
+	// peerPingTimeout is the time after which a multiplexed peer connection is closed if a ping is not answered.
+	peerPingTimeout = 10 * time.Second
+	// peerIdleConnTimeout is the idle time after which a non-multiplexed peer connection (of HTTP requests) is closed.
+	peerIdleConnTimeout = 90 * time.Second
+)
+
+// errNoMultiplexing is returned when a peer does not support multiplexing connections over HTTP/2.
+var errNoMultiplexing = errors.New("peer does not support connection multiplexing")
+
+// peerTransport multiplexes connections to a peer as HTTP/2 CONNECT streams,
+// over long-lived mTLS sessions.
+type peerTransport struct {
+	transport *http2.Transport
+	// fallback sends HTTP requests to peers not supporting HTTP/2
+	fallback *http.Transport
+	// target is the peer address dialed by the transport
+	target string
+	// tlsConfig is the dataplane TLS configuration the transport was created with
+	tlsConfig *tls.Config
+	// unsupported is set once the peer failed to negotiate HTTP/2
+	unsupported atomic.Bool
+}
+
+// dial opens a new mTLS session to the peer, which must negotiate HTTP/2.
+func (t *peerTransport) dial(ctx context.Context, network string, tlsConfig *tls.Config) (net.Conn, error) {
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, network, t.target)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.(*tls.Conn).ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+		conn.Close()
+		t.unsupported.Store(true)
+		return nil, errNoMultiplexing
+	}
+
+	return conn, nil
+}
+
+// closeIdleConnections closes the idle connections of the transport.
+func (t *peerTransport) closeIdleConnections() {
+	t.transport.CloseIdleConnections()
+	t.fallback.CloseIdleConnections()
+}
+
+// SetPeerMultiplexing sets whether egress connections to each peer are multiplexed
+// over shared HTTP/2 connections (the default), or use a dedicated TLS connection each.
+// Must be called before the dataplane starts forwarding connections.
+func (d *Dataplane) SetPeerMultiplexing(enabled bool) {
+	d.peerMultiplexing = enabled
+}
+
+// getPeerTransport returns the transport for multiplexing connections to the given peer cluster.
+// A new transport is created if the peer address or the dataplane TLS configuration changed.
+func (d *Dataplane) getPeerTransport(targetCluster string) (*peerTransport, error) {
+	target, err := d.GetClusterTarget(targetCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	targetHost, err := d.GetClusterHost(targetCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	d.tlsConfigLock.RLock()
+	baseTLSConfig := d.tlsConfig
+	d.tlsConfigLock.RUnlock()
+
+	d.peerTransportsLock.Lock()
+	defer d.peerTransportsLock.Unlock()
+
+	current, ok := d.peerTransports[targetCluster]
+	if ok && current.target == target && current.tlsConfig == baseTLSConfig {
+		return current, nil
+	}
+	if ok {
+		// active streams keep their connections until done
+		current.closeIdleConnections()
+	}
+
+	tlsConfig := baseTLSConfig.Clone()
+	tlsConfig.ServerName = targetHost
+	// offer HTTP/1.1 as well, as peers advertising only HTTP/1.1 fail the handshake otherwise
+	tlsConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+
+	pt := &peerTransport{
+		target:    target,
+		tlsConfig: baseTLSConfig,
+	}
+	pt.transport = &http2.Transport{
+		TLSClientConfig: tlsConfig,
+		DialTLSContext: func(ctx context.Context, network, _ string, cfg *tls.Config) (net.Conn, error) {
+			return pt.dial(ctx, network, cfg)
+		},
+		ReadIdleTimeout: peerPingInterval,
+		PingTimeout:     peerPingTimeout,
+	}
+
+	fallbackTLSConfig := tlsConfig.Clone()
+	fallbackTLSConfig.NextProtos = []string{"http/1.1"}
+	pt.fallback = &http.Transport{
+		TLSClientConfig: fallbackTLSConfig,
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, pt.target)
+		},
+		IdleConnTimeout: peerIdleConnTimeout,
+	}
+
+	d.peerTransports[targetCluster] = pt
+	return pt, nil
+}
+
+// connectPeerMultiplexed opens a connection to the given peer cluster as a stream
+// of a shared HTTP/2 connection. Returns errNoMultiplexing if the peer does not support multiplexing.
+// Otherwise, on failure, returns the HTTP status of the connection attempt.
+func (d *Dataplane) connectPeerMultiplexed(targetCluster, authToken string) (net.Conn, int, error) {
+	if !d.peerMultiplexing {
+		return nil, 0, errNoMultiplexing
+	}
+
+	pt, err := d.getPeerTransport(targetCluster)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	if pt.unsupported.Load() {
+		return nil, 0, errNoMultiplexing
+	}
+
+	targetHostname, err := d.GetClusterHostname(targetCluster)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+
+	// the request body carries the data sent to the peer, for the lifetime of the stream
+	bodyReader, bodyWriter := io.Pipe()
+	egressReq, err := http.NewRequestWithContext(
+		context.Background(), http.MethodConnect, "https://"+targetHostname, bodyReader)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	egressReq.Header.Add(cpapi.AuthorizationHeader, authToken)
+
+	resp, err := pt.transport.RoundTrip(egressReq)
+	if err != nil {
+		bodyWriter.Close()
+		if pt.unsupported.Load() {
+			return nil, 0, errNoMultiplexing
+		}
+		return nil, http.StatusServiceUnavailable, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		bodyWriter.Close()
+		return nil, resp.StatusCode, fmt.Errorf(
+			"got HTTP %d while trying to establish dataplane connection", resp.StatusCode)
+	}
+
+	return &streamConn{
+		body:        resp.Body,
+		writer:      bodyWriter,
+		writeCloser: bodyWriter,
+		localAddr:   streamAddr("local"),
+		remoteAddr:  streamAddr(pt.target),
+	}, http.StatusOK, nil
+}
+
+// peerRoundTrip sends an HTTP request to the given peer cluster, as a stream of a shared HTTP/2 connection,
+// or over an HTTP/1.1 connection if the peer does not support multiplexing.
+func (d *Dataplane) peerRoundTrip(targetCluster string, req *http.Request) (*http.Response, error) {
+	pt, err := d.getPeerTransport(targetCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.peerMultiplexing && !pt.unsupported.Load() {
+		resp, err := pt.transport.RoundTrip(req)
+		if err == nil || !pt.unsupported.Load() {
+			return resp, err
+		}
+	}
+
+	return pt.fallback.RoundTrip(req)
+}
+
+// acceptStream accepts an HTTP/2 CONNECT stream from a peer, returning it as a connection.
+func (d *Dataplane) acceptStream(w http.ResponseWriter, r *http.Request) (net.Conn, error) {
+	rc := http.NewResponseController(w)
+	// the server read and write timeouts apply to the stream, and must be cleared
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to clear stream read deadline: %w", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to clear stream write deadline: %w", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush stream headers: %w", err)
+	}
+
+	var localAddr net.Addr = streamAddr("local")
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		localAddr = addr
+	}
+
+	return &streamConn{
+		body:       r.Body,
+		writer:     w,
+		flush:      rc.Flush,
+		localAddr:  localAddr,
+		remoteAddr: streamAddr(r.RemoteAddr),
+	}, nil
+}
+
+// streamAddr is the address of a stream endpoint.
+type streamAddr string
+
+func (a streamAddr) Network() string {
+	return "tcp"
+}
+
+func (a streamAddr) String() string {
+	return string(a)
+}
+
+// streamConn is a connection over an HTTP/2 CONNECT stream,
+// reading from one message body and writing to the other.
+type streamConn struct {
+	body   io.ReadCloser
+	writer io.Writer
+	// flush (if set) flushes written data to the stream
+	flush func() error
+	// writeCloser (if set) ends the written message body, half-closing the stream
+	writeCloser io.Closer
+
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (c *streamConn) Read(b []byte) (int, error) {
+	return c.body.Read(b)
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	n, err := c.writer.Write(b)
+	if err == nil && c.flush != nil {
+		err = c.flush()
+	}
+	return n, err
+}
+
+// CloseWrite half-closes the stream, if supported.
+func (c *streamConn) CloseWrite() error {
+	if c.writeCloser == nil {
+		return errors.ErrUnsupported
+	}
+	return c.writeCloser.Close()
+}
+
+func (c *streamConn) Close() error {
+	if c.writeCloser != nil {
+		c.writeCloser.Close()
+	}
+	return c.body.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SetDeadline is not supported, as stream liveness is checked by the HTTP/2 connection.
+func (c *streamConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *streamConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *streamConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
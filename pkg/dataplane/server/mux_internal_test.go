@@ -0,0 +1,210 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/clusterlink-net/clusterlink/pkg/bootstrap"
+)
+
+const testPeer = "peer"
+
+// testTLSConfig returns an mTLS configuration of a peer certificate.
+func testTLSConfig(t *testing.T) *tls.Config {
+	fabricCert, err := bootstrap.CreateFabricCertificate("fabric")
+	require.Nil(t, err)
+	peerCert, err := bootstrap.CreatePeerCertificate(testPeer, fabricCert)
+	require.Nil(t, err)
+
+	certificate, err := tls.X509KeyPair(peerCert.RawCert(), peerCert.RawKey())
+	require.Nil(t, err)
+
+	caCertPool := x509.NewCertPool()
+	require.True(t, caCertPool.AppendCertsFromPEM(fabricCert.RawCert()))
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    caCertPool,
+		RootCAs:      caCertPool,
+	}
+}
+
+// startEchoPeer starts a peer server echoing CONNECT tunnels, optionally supporting HTTP/2.
+// Returns the peer address, and a counter of the accepted TLS connections.
+func startEchoPeer(t *testing.T, d *Dataplane, tlsConfig *tls.Config, withHTTP2 bool) (string, *atomic.Int32) {
+	var connections atomic.Int32
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var conn net.Conn
+			var err error
+			if r.ProtoMajor == 2 {
+				conn, err = d.acceptStream(w, r)
+			} else {
+				conn, _, err = d.hijackConn(w, connectResponse)
+			}
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			_, _ = io.Copy(conn, conn)
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		},
+	}
+	server.TLSConfig = tlsConfig.Clone()
+	if withHTTP2 {
+		require.Nil(t, http2.ConfigureServer(server, &http2.Server{}))
+	} else {
+		// a peer which does not support multiplexing
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go func() {
+		_ = server.ServeTLS(listener, "", "")
+	}()
+	t.Cleanup(func() { server.Close() })
+
+	return listener.Addr().String(), &connections
+}
+
+// newTestDataplane returns a dataplane using the given TLS configuration.
+func newTestDataplane(tlsConfig *tls.Config) *Dataplane {
+	d := NewDataplane("test", nil, nil)
+	d.tlsConfig = tlsConfig
+	return d
+}
+
+// addPeerCluster adds a peer cluster at the given address.
+func addPeerCluster(t *testing.T, d *Dataplane, address string) {
+	host, port, err := net.SplitHostPort(address)
+	require.Nil(t, err)
+	portValue, err := net.LookupPort("tcp", port)
+	require.Nil(t, err)
+
+	d.AddCluster(&cluster.Cluster{
+		Name: testPeer,
+		LoadAssignment: &endpointv3.ClusterLoadAssignment{
+			Endpoints: []*endpointv3.LocalityLbEndpoints{{
+				LbEndpoints: []*endpointv3.LbEndpoint{{
+					HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+						Endpoint: &endpointv3.Endpoint{
+							Address: &corev3.Address{
+								Address: &corev3.Address_SocketAddress{
+									SocketAddress: &corev3.SocketAddress{
+										Address:       host,
+										PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: uint32(portValue)},
+									},
+								},
+							},
+							Hostname: net.JoinHostPort(testPeer, port),
+						},
+					},
+				}},
+			}},
+		},
+	})
+}
+
+// requireEcho sends data over a connection, half-closes it, and checks that the data is echoed back.
+func requireEcho(t *testing.T, conn net.Conn, data string) {
+	_, err := conn.Write([]byte(data))
+	require.Nil(t, err)
+	require.Nil(t, conn.(closeWriter).CloseWrite())
+
+	echoed, err := io.ReadAll(conn)
+	require.Nil(t, err)
+	require.Equal(t, data, string(echoed))
+	require.Nil(t, conn.Close())
+}
+
+func TestPeerMultiplexing(t *testing.T) {
+	tlsConfig := testTLSConfig(t)
+	d := newTestDataplane(tlsConfig)
+	address, connections := startEchoPeer(t, d, tlsConfig, true)
+	addPeerCluster(t, d, address)
+
+	// concurrent streams share a single TLS connection
+	conn1, status, err := d.connectPeerMultiplexed(testPeer, "token")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, status)
+	conn2, status, err := d.connectPeerMultiplexed(testPeer, "token")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, status)
+
+	requireEcho(t, conn1, "first")
+	requireEcho(t, conn2, "second")
+
+	conn3, _, err := d.connectPeerMultiplexed(testPeer, "token")
+	require.Nil(t, err)
+	requireEcho(t, conn3, "third")
+
+	require.Equal(t, int32(1), connections.Load())
+
+	// a TLS configuration update (e.g. certificate rotation) opens a new TLS connection
+	d.tlsConfig = tlsConfig.Clone()
+	conn4, _, err := d.connectPeerMultiplexed(testPeer, "token")
+	require.Nil(t, err)
+	requireEcho(t, conn4, "fourth")
+	require.Equal(t, int32(2), connections.Load())
+
+	// multiplexing disabled
+	d.SetPeerMultiplexing(false)
+	_, _, err = d.connectPeerMultiplexed(testPeer, "token")
+	require.True(t, errors.Is(err, errNoMultiplexing))
+}
+
+func TestPeerMultiplexingFallback(t *testing.T) {
+	tlsConfig := testTLSConfig(t)
+	d := newTestDataplane(tlsConfig)
+	address, connections := startEchoPeer(t, d, tlsConfig, false)
+	addPeerCluster(t, d, address)
+
+	_, _, err := d.connectPeerMultiplexed(testPeer, "token")
+	require.True(t, errors.Is(err, errNoMultiplexing))
+	require.Equal(t, int32(1), connections.Load())
+
+	// the peer is not probed again
+	_, _, err = d.connectPeerMultiplexed(testPeer, "token")
+	require.True(t, errors.Is(err, errNoMultiplexing))
+	require.Equal(t, int32(1), connections.Load())
+
+	peerTLSConfig, err := d.peerTLSConfig(testPeer)
+	require.Nil(t, err)
+	conn, status, err := d.connectPeer(testPeer, "token", peerTLSConfig)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, status)
+	requireEcho(t, conn, "data")
+}
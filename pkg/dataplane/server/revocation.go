@@ -0,0 +1,83 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/dataplane/api"
+)
+
+// revokeConnections closes the flows of an import listener (egress flows) or of an export cluster (ingress flows)
+// revoked by the controlplane, as listed in the listener (or cluster) metadata.
+func (d *Dataplane) revokeConnections(resourceName string, resourceMetadata *corev3.Metadata) {
+	metadata, ok := resourceMetadata.GetFilterMetadata()[cpapi.MetadataNamespace]
+	if !ok {
+		return
+	}
+
+	revoked := make(map[string]bool)
+	for _, conn := range cpapi.ParseRevokedConnections(metadata) {
+		if conn.Dataplane == d.ID {
+			revoked[conn.StreamID] = true
+		}
+	}
+	if len(revoked) == 0 {
+		return
+	}
+
+	count := d.flows.revoke(func(flow *activeFlow) bool {
+		return flow.streamID != "" && revoked[flow.streamID]
+	})
+	if count > 0 {
+		d.logger.Infof("Revoked %d connections of '%s'.", count, resourceName)
+	}
+}
+
+// revokeListenerConnections closes the egress flows of a removed import listener.
+func (d *Dataplane) revokeListenerConnections(listenerName string) {
+	count := d.flows.revoke(func(flow *activeFlow) bool {
+		return flow.flow.Direction == directionEgress && egressFlowListenerName(&flow.flow) == listenerName
+	})
+	if count > 0 {
+		d.logger.Infof("Revoked %d connections of removed listener '%s'.", count, listenerName)
+	}
+}
+
+// revokeClusterConnections closes the flows forwarded to a removed cluster.
+// Removing a remote peer cluster also closes the ingress flows from that peer.
+func (d *Dataplane) revokeClusterConnections(clusterName string) {
+	peer, isPeer := strings.CutPrefix(clusterName, cpapi.RemotePeerClusterPrefix)
+	count := d.flows.revoke(func(flow *activeFlow) bool {
+		if flow.targetCluster == clusterName {
+			return true
+		}
+		return isPeer && flow.flow.Direction == directionIngress && flow.flow.Peer == peer
+	})
+	if count > 0 {
+		d.logger.Infof("Revoked %d connections of removed cluster '%s'.", count, clusterName)
+	}
+}
+
+// egressFlowListenerName returns the name of the import listener of an egress flow,
+// without the ImportListenerPrefix.
+func egressFlowListenerName(flow *api.Flow) string {
+	if flow.Port == "" {
+		return flow.Import
+	}
+	return flow.Import + cpapi.PortNameSeparator + flow.Port
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+// revocationMetadata returns listener (or cluster) metadata listing the given revoked connections.
+func revocationMetadata(t *testing.T, revoked ...cpapi.ConnectionID) *corev3.Metadata {
+	metadata, err := cpapi.RevokedConnectionsMetadata(revoked)
+	require.Nil(t, err)
+	return &corev3.Metadata{FilterMetadata: map[string]*structpb.Struct{cpapi.MetadataNamespace: metadata}}
+}
+
+func TestRevokeConnections(t *testing.T) {
+	d := NewDataplane("dp1", nil, nil)
+	flow1 := startFlow(t, d.flows, cpapi.RemotePeerClusterName("peer1"), "1")
+	flow2 := startFlow(t, d.flows, cpapi.RemotePeerClusterName("peer1"), "2")
+	flow3 := startFlow(t, d.flows, cpapi.RemotePeerClusterName("peer1"), "3")
+
+	// resources with no revoked connections do not affect flows
+	d.revokeConnections(cpapi.ImportListenerName("svc", "ns", ""), nil)
+	for _, flow := range d.flows.list() {
+		require.Nil(t, flow.EndTime)
+	}
+
+	// only connections of this dataplane are revoked
+	d.revokeConnections(cpapi.ImportListenerName("svc", "ns", ""), revocationMetadata(t,
+		cpapi.ConnectionID{Dataplane: "dp1", StreamID: "1"},
+		cpapi.ConnectionID{Dataplane: "dp2", StreamID: "2"},
+	))
+	<-flow1.done
+
+	// connections listed by an updated cluster are revoked
+	d.AddCluster(&cluster.Cluster{
+		Name:     cpapi.ExportClusterName("svc", "ns", ""),
+		Metadata: revocationMetadata(t, cpapi.ConnectionID{Dataplane: "dp1", StreamID: "2"}),
+	})
+	<-flow2.done
+
+	flows := d.flows.list()
+	require.Len(t, flows, 3)
+	require.Equal(t, errConnectionRevoked.Error(), flows[0].CloseReason)
+	require.Equal(t, errConnectionRevoked.Error(), flows[1].CloseReason)
+	require.Nil(t, flows[2].EndTime)
+
+	for _, flow := range []*recordedFlow{flow1, flow2, flow3} {
+		require.Nil(t, flow.workload.Close())
+		require.Nil(t, flow.peer.Close())
+	}
+}
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -25,11 +26,16 @@ import (
 	"time"
 
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"golang.org/x/net/http2"
 
 	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
 	"github.com/clusterlink-net/clusterlink/pkg/dataplane/api"
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
+const connectResponse = "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n"
+
 // StartDataplaneServer starts the Dataplane server.
 func (d *Dataplane) StartDataplaneServer(dataplaneServerAddress string) error {
 	d.logger.Infof("Dataplane server starting at %s.", dataplaneServerAddress)
@@ -52,12 +58,20 @@ func (d *Dataplane) StartDataplaneServer(dataplaneServerAddress string) error {
 			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
 				// return certificate set by the controlplane (using the SDS protocol)
 				d.tlsConfigLock.RLock()
-				defer d.tlsConfigLock.RUnlock()
-				return d.tlsConfig, nil
+				tlsConfig := d.tlsConfig.Clone()
+				d.tlsConfigLock.RUnlock()
+
+				// accept multiplexed connections (HTTP/2) as well as single connections (HTTP/1.1)
+				tlsConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+				return tlsConfig, nil
 			},
 		},
 	}
 
+	if err := http2.ConfigureServer(server, &http2.Server{}); err != nil {
+		return err
+	}
+
 	return server.ListenAndServeTLS("", "")
 }
 
@@ -93,25 +107,33 @@ func (d *Dataplane) dataplaneIngressAuthorize(w http.ResponseWriter, r *http.Req
 	}
 
 	headers := make(map[string]string)
-	allowedHeaders := []string{cpapi.AuthorizationHeader}
+	allowedHeaders := []string{cpapi.AuthorizationHeader, cpapi.PeerAuthorizationHeader}
 	for _, header := range allowedHeaders {
 		if value := r.Header.Get(header); value != "" {
 			headers[header] = value
 		}
 	}
 
+	// pass on the trace context of the remote peer to the controlplane
+	tracing.InjectMap(tracing.ExtractHTTP(r.Context(), r.Header), headers)
+
 	defer func() {
 		if err := r.Body.Close(); err != nil {
 			d.logger.Warnf("Cannot close response body: %v.", err)
 		}
 	}()
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		errorString := fmt.Sprintf("Unable to read response body: %v.", err)
-		d.logger.Error(errorString)
-		http.Error(w, errorString, http.StatusInternalServerError)
-		return
+	// the body of HTTP requests (to exports with appProtocol http) is forwarded to the service
+	var body []byte
+	if !isHTTPRequest(r) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			errorString := fmt.Sprintf("Unable to read response body: %v.", err)
+			d.logger.Error(errorString)
+			http.Error(w, errorString, http.StatusInternalServerError)
+			return
+		}
 	}
 
 	authzReq := &authv3.CheckRequest{
@@ -143,7 +165,11 @@ func (d *Dataplane) dataplaneIngressAuthorize(w http.ResponseWriter, r *http.Req
 	}
 	if deniedResp, ok := resp.HttpResponse.(*authv3.CheckResponse_DeniedResponse); ok {
 		d.logger.Infof("Ingress connection denied: %s", deniedResp.DeniedResponse.Body)
-		http.Error(w, deniedResp.DeniedResponse.Body, http.StatusForbidden)
+		status := http.StatusForbidden
+		if deniedResp.DeniedResponse.GetStatus().GetCode() == typev3.StatusCode_TooManyRequests {
+			status = http.StatusTooManyRequests
+		}
+		http.Error(w, deniedResp.DeniedResponse.Body, status)
 		return
 	}
 
@@ -152,7 +178,9 @@ func (d *Dataplane) dataplaneIngressAuthorize(w http.ResponseWriter, r *http.Req
 }
 
 func (d *Dataplane) routeIngress(w http.ResponseWriter, r *http.Request, authzResp *authv3.OkHttpResponse) {
-	if r.Method != http.MethodConnect {
+	connectUDP := isConnectUDPRequest(r)
+	httpRequest := isHTTPRequest(r)
+	if r.Method != http.MethodConnect && !connectUDP && !httpRequest {
 		for _, header := range authzResp.ResponseHeadersToAdd {
 			w.Header().Set(header.Header.Key, header.Header.Value)
 		}
@@ -160,12 +188,19 @@ func (d *Dataplane) routeIngress(w http.ResponseWriter, r *http.Request, authzRe
 		return
 	}
 
-	// get target cluster (for export tunnel)
-	var targetCluster string
+	if connectUDP && r.ProtoMajor != 1 {
+		http.Error(w, "UDP tunneling requires HTTP/1.1", http.StatusBadRequest)
+		return
+	}
+
+	// get target cluster (for export tunnel), and the ID of the access token authorizing the connection
+	var targetCluster, tokenID string
 	for _, header := range authzResp.Headers {
-		if header.Header.Key == cpapi.TargetClusterHeader {
+		switch header.Header.Key {
+		case cpapi.TargetClusterHeader:
 			targetCluster = header.Header.Value
-			break
+		case cpapi.AccessTokenIDHeader:
+			tokenID = header.Header.Value
 		}
 	}
 
@@ -176,65 +211,141 @@ func (d *Dataplane) routeIngress(w http.ResponseWriter, r *http.Request, authzRe
 		return
 	}
 
+	if !d.acquireConnection(targetCluster) {
+		d.logger.Infof("Rejecting connection to %s: concurrent connections limit reached.", targetCluster)
+		d.accessLogger.connectionRejected(targetCluster)
+		exportLabels := ingressConnectionLabels(targetCluster, "")
+		rejectedConnectionsMetric.WithLabelValues(exportLabels.namespace, exportLabels.service).Inc()
+		http.Error(w, "concurrent connections limit reached", http.StatusServiceUnavailable)
+		return
+	}
+	defer d.releaseConnection(targetCluster)
+
+	if httpRequest {
+		d.proxyIngressRequest(w, r, serviceTarget)
+		return
+	}
+
 	d.logger.Infof("Initiating connection with %s.", serviceTarget)
 
-	appConn, err := net.DialTimeout("tcp", serviceTarget, time.Second)
+	network := protocolTCP
+	response := connectResponse
+	if connectUDP {
+		network = protocolUDP
+		response = connectUDPResponse
+	}
+
+	appConn, err := net.DialTimeout(network, serviceTarget, time.Second)
 	if err != nil {
 		d.logger.Errorf("Dial to export service failed: %v.", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if connectUDP {
+		// close idle flows, as done for egress flows
+		appConn = newUDPConn(appConn, udpFlowIdleTimeout)
+	}
 
-	// hijack connection
-	peerConn, err := d.hijackConn(w)
-	if err != nil {
-		d.logger.Errorf("Hijacking failed: %v.", err)
-		http.Error(w, "hijacking failed", http.StatusInternalServerError)
-		appConn.Close()
-		return
+	var peerConn net.Conn
+	if r.ProtoMajor == 2 {
+		// multiplexed connection
+		peerConn, err = d.acceptStream(w, r)
+		if err != nil {
+			d.logger.Errorf("Accepting stream failed: %v.", err)
+			appConn.Close()
+			return
+		}
+	} else {
+		// hijack connection
+		var reader *bufio.Reader
+		peerConn, reader, err = d.hijackConn(w, response)
+		if err != nil {
+			d.logger.Errorf("Hijacking failed: %v.", err)
+			http.Error(w, "hijacking failed", http.StatusInternalServerError)
+			appConn.Close()
+			return
+		}
+
+		if connectUDP {
+			peerConn = newCapsuleConn(peerConn, reader)
+		}
+	}
+
+	// report the connection, to be re-evaluated (and possibly revoked) by the controlplane
+	var streamID string
+	if tokenID != "" {
+		streamID = d.accessLogger.ingressConnectionEstablished(targetCluster, tokenID)
+		defer d.accessLogger.ingressConnectionEnded(streamID, targetCluster, tokenID)
 	}
 
-	forward := newForwarder(appConn, peerConn)
-	forward.run()
+	peerName := r.TLS.PeerCertificates[0].DNSNames[0]
+	forward := newForwarder(appConn, peerConn, ingressConnectionLabels(targetCluster, peerName))
+	d.forward(forward, newIngressFlow(targetCluster, peerName, network), targetCluster, streamID)
 }
 
-func (d *Dataplane) hijackConn(w http.ResponseWriter) (net.Conn, error) {
+// hijackConn takes over the connection of an HTTP request, writing the given raw response.
+func (d *Dataplane) hijackConn(w http.ResponseWriter, response string) (net.Conn, *bufio.Reader, error) {
 	d.logger.Debugf("Starting to hijack connection.")
 	hj, ok := w.(http.Hijacker)
 	if !ok {
-		return nil, fmt.Errorf("server doesn't support hijacking")
+		return nil, nil, fmt.Errorf("server doesn't support hijacking")
 	}
 	// Hijack the connection
-	peerConn, _, err := hj.Hijack()
+	peerConn, bufrw, err := hj.Hijack()
 	if err != nil {
-		return nil, fmt.Errorf("hijacking failed: %w", err)
+		return nil, nil, fmt.Errorf("hijacking failed: %w", err)
 	}
 
 	if err = peerConn.SetDeadline(time.Time{}); err != nil {
-		return nil, fmt.Errorf("failed to clear deadlines on connection: %w", err)
+		return nil, nil, fmt.Errorf("failed to clear deadlines on connection: %w", err)
 	}
 
 	if _, err := peerConn.Write([]byte{}); err != nil {
 		_ = peerConn.Close() // close the connection ignoring errors
-		return nil, fmt.Errorf("failed to write to connection: %w", err)
+		return nil, nil, fmt.Errorf("failed to write to connection: %w", err)
 	}
 
-	fmt.Fprintf(peerConn, "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n")
+	fmt.Fprint(peerConn, response)
 	d.logger.Debugf("Connection hijacked %v->%v.", peerConn.RemoteAddr().String(), peerConn.LocalAddr().String())
-	return peerConn, nil
+	return peerConn, bufrw.Reader, nil
 }
 
-func (d *Dataplane) initiateEgressConnection(targetCluster, authToken string, appConn net.Conn, tlsConfig *tls.Config) error {
-	target, err := d.GetClusterTarget(targetCluster)
+func (d *Dataplane) initiateEgressConnection(
+	name, targetCluster, sourceExport, authToken string, appConn net.Conn, tlsConfig *tls.Config,
+) error {
+	start := time.Now()
+	peerConn, status, err := d.connectPeerMultiplexed(targetCluster, authToken)
+	if errors.Is(err, errNoMultiplexing) {
+		d.logger.Debugf("Connecting to %s without multiplexing.", targetCluster)
+		peerConn, status, err = d.connectPeer(targetCluster, authToken, tlsConfig)
+	}
 	if err != nil {
-		d.logger.Error(err)
+		d.logger.Infof("Error in connecting to %s: %v.", targetCluster, err)
+		d.accessLogger.connectionFailed(name, targetCluster, sourceExport, status)
 		return err
 	}
 
+	d.logger.Infof("Connection established successfully!")
+	flow := newEgressFlow(name, targetCluster, protocolTCP, appConn.RemoteAddr())
+	streamID := d.accessLogger.connectionEstablished(name, targetCluster, sourceExport, flow.ClientIP, time.Since(start))
+	defer d.accessLogger.connectionEnded(streamID, name, targetCluster)
+
+	forward := newForwarder(appConn, peerConn, egressConnectionLabels(name, targetCluster))
+	d.forward(forward, flow, targetCluster, streamID)
+	return nil
+}
+
+// connectPeer opens a dedicated TLS connection to the given peer cluster, using an HTTP/1.1 CONNECT request.
+// On failure, returns the HTTP status of the connection attempt.
+func (d *Dataplane) connectPeer(targetCluster, authToken string, tlsConfig *tls.Config) (net.Conn, int, error) {
+	target, err := d.GetClusterTarget(targetCluster)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+
 	targetHostname, err := d.GetClusterHostname(targetCluster)
 	if err != nil {
-		d.logger.Errorf("Unable to get cluster hostname: %v.", err)
-		return err
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("unable to get cluster hostname: %w", err)
 	}
 
 	url := "https://" + targetHostname
@@ -242,8 +353,7 @@ func (d *Dataplane) initiateEgressConnection(targetCluster, authToken string, ap
 
 	peerConn, err := tls.Dial("tcp", target, tlsConfig)
 	if err != nil {
-		d.logger.Infof("Error in connecting.. %+v", err)
-		return err
+		return nil, http.StatusServiceUnavailable, err
 	}
 
 	ctx := context.Background()
@@ -257,28 +367,38 @@ func (d *Dataplane) initiateEgressConnection(targetCluster, authToken string, ap
 
 	egressReq, err := http.NewRequestWithContext(ctx, http.MethodConnect, url, http.NoBody)
 	if err != nil {
-		return err
+		peerConn.Close()
+		return nil, http.StatusInternalServerError, err
 	}
 
 	egressReq.Header.Add(cpapi.AuthorizationHeader, authToken)
 	d.logger.Debugf("Setting %s header to %s.", cpapi.AuthorizationHeader, authToken)
 
 	resp, err := client.Do(egressReq)
-	if resp != nil {
-		defer resp.Body.Close()
-	}
 	if err != nil {
-		d.logger.Infof("Error in TLS connection: %v.", err)
-		return err
+		peerConn.Close()
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("error in TLS connection: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("got HTTP %d while trying to establish dataplane connection", resp.StatusCode)
+		resp.Body.Close()
+		peerConn.Close()
+		return nil, resp.StatusCode, fmt.Errorf(
+			"got HTTP %d while trying to establish dataplane connection", resp.StatusCode)
 	}
 
-	d.logger.Infof("Connection established successfully!")
+	return &tunnelConn{Conn: peerConn, body: resp.Body}, http.StatusOK, nil
+}
 
-	forward := newForwarder(appConn, peerConn)
-	forward.run()
-	return nil
+// tunnelConn is a peer connection tunneled using an HTTP/1.1 CONNECT request.
+// The response body is kept open until the connection is closed, as closing it closes the connection.
+type tunnelConn struct {
+	*tls.Conn
+	body io.Closer
+}
+
+func (c *tunnelConn) Close() error {
+	err := c.Conn.Close()
+	c.body.Close()
+	return err
 }
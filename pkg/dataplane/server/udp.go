@@ -0,0 +1,533 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+const (
+	// udpFlowIdleTimeout is the time after which an idle UDP flow is closed.
+	udpFlowIdleTimeout = 60 * time.Second
+	// udpFlowDenialBackoff is the time datagrams of a client are dropped once its flow failed authorization,
+	// rather than authorizing a new flow for each datagram.
+	udpFlowDenialBackoff = 5 * time.Second
+	// datagramQueueSize is the number of datagrams queued per flow before dropping.
+	datagramQueueSize = 128
+
+	// capsuleTypeDatagram is the type of an HTTP DATAGRAM capsule (RFC 9297).
+	capsuleTypeDatagram = 0x00
+	// maxCapsuleLength is the maximal length of a capsule accepted from a peer.
+	maxCapsuleLength = dataBufferSize + 8
+
+	capsuleProtocolHeader = "Capsule-Protocol"
+	connectUDPResponse    = "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: " + cpapi.ConnectUDPProtocol + "\r\n" +
+		capsuleProtocolHeader + ": ?1\r\n\r\n"
+)
+
+// datagramConn is a net.Conn which preserves datagram boundaries.
+// It is used for representing both a single UDP flow received on a listener,
+// and a UDP flow tunneled to a peer using HTTP DATAGRAM capsules.
+type datagramConn struct {
+	localAddr  net.Addr
+	remoteAddr net.Addr
+
+	datagrams chan []byte
+	write     func([]byte) (int, error)
+	onClose   func()
+
+	// idleTimeout closes the connection when no datagram passed through it. Zero means no timeout.
+	idleTimeout  time.Duration
+	lastActivity atomic.Int64
+
+	deadlineLock sync.Mutex
+	readDeadline time.Time
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Read reads a single datagram. Datagrams exceeding the buffer size are truncated.
+// Returns io.EOF once the connection is closed, or after being idle for idleTimeout.
+func (c *datagramConn) Read(b []byte) (int, error) {
+	for {
+		c.deadlineLock.Lock()
+		deadline := c.readDeadline
+		c.deadlineLock.Unlock()
+
+		idleDeadline := time.Time{}
+		if c.idleTimeout > 0 {
+			idleDeadline = time.Unix(0, c.lastActivity.Load()).Add(c.idleTimeout)
+		}
+
+		wakeup := deadline
+		if wakeup.IsZero() || (!idleDeadline.IsZero() && idleDeadline.Before(wakeup)) {
+			wakeup = idleDeadline
+		}
+
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if !wakeup.IsZero() {
+			timer = time.NewTimer(time.Until(wakeup))
+			timeout = timer.C
+		}
+
+		select {
+		case datagram := <-c.datagrams:
+			stopTimer(timer)
+			c.lastActivity.Store(time.Now().UnixNano())
+			return copy(b, datagram), nil
+		case <-c.closed:
+			stopTimer(timer)
+			return 0, io.EOF
+		case <-timeout:
+		}
+
+		// the idle deadline may have been extended by writes
+		if c.idleTimeout > 0 && time.Since(time.Unix(0, c.lastActivity.Load())) >= c.idleTimeout {
+			return 0, io.EOF
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// stopTimer stops a timer, if set.
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// Write writes a single datagram.
+func (c *datagramConn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	c.lastActivity.Store(time.Now().UnixNano())
+	return c.write(b)
+}
+
+// deliver queues a received datagram, dropping it if the queue is full.
+func (c *datagramConn) deliver(datagram []byte) {
+	select {
+	case c.datagrams <- datagram:
+	default:
+	}
+}
+
+// Close closes the connection.
+func (c *datagramConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return nil
+}
+
+// LocalAddr returns the local address.
+func (c *datagramConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+// RemoteAddr returns the remote address.
+func (c *datagramConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SetDeadline sets the read deadline. Writes never block.
+func (c *datagramConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline.
+func (c *datagramConn) SetReadDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline is a no-op, as writes never block.
+func (c *datagramConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func newDatagramConn(localAddr, remoteAddr net.Addr, write func([]byte) (int, error)) *datagramConn {
+	c := &datagramConn{
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+		datagrams:  make(chan []byte, datagramQueueSize),
+		write:      write,
+		closed:     make(chan struct{}),
+	}
+	c.lastActivity.Store(time.Now().UnixNano())
+	return c
+}
+
+// newCapsuleConn returns a datagramConn which tunnels datagrams over a stream connection,
+// encoded as HTTP DATAGRAM capsules with a zero context ID (RFC 9297, RFC 9298).
+func newCapsuleConn(conn net.Conn, reader *bufio.Reader) *datagramConn {
+	var writeLock sync.Mutex
+	c := newDatagramConn(conn.LocalAddr(), conn.RemoteAddr(), func(b []byte) (int, error) {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		if _, err := conn.Write(encodeDatagramCapsule(b)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	})
+	c.onClose = func() {
+		conn.Close()
+	}
+
+	go func() {
+		defer c.Close()
+		for {
+			datagram, err := readDatagramCapsule(reader)
+			if err != nil {
+				return
+			}
+
+			select {
+			case c.datagrams <- datagram:
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// newUDPConn returns a datagramConn over a connected UDP socket, which is closed after being idle for idleTimeout.
+// Received datagrams are dropped if the queue is full.
+func newUDPConn(conn net.Conn, idleTimeout time.Duration) *datagramConn {
+	c := newDatagramConn(conn.LocalAddr(), conn.RemoteAddr(), conn.Write)
+	c.idleTimeout = idleTimeout
+	c.onClose = func() {
+		conn.Close()
+	}
+
+	go func() {
+		defer c.Close()
+		buf := make([]byte, dataBufferSize)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			c.deliver(append([]byte(nil), buf[:n]...))
+		}
+	}()
+
+	return c
+}
+
+// encodeDatagramCapsule encodes a UDP payload as a DATAGRAM capsule.
+func encodeDatagramCapsule(payload []byte) []byte {
+	// context ID 0 precedes the UDP payload
+	length := uint64(len(payload)) + 1
+	capsule := make([]byte, 0, 1+8+length)
+	capsule = appendVarint(capsule, capsuleTypeDatagram)
+	capsule = appendVarint(capsule, length)
+	capsule = appendVarint(capsule, 0)
+	return append(capsule, payload...)
+}
+
+// readDatagramCapsule reads capsules until a DATAGRAM capsule carrying a UDP payload is read.
+// Unknown capsules, and datagrams with a non-zero context ID, are skipped.
+func readDatagramCapsule(r *bufio.Reader) ([]byte, error) {
+	for {
+		capsuleType, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if length > maxCapsuleLength {
+			return nil, fmt.Errorf("capsule length %d exceeds limit", length)
+		}
+
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+
+		if capsuleType != capsuleTypeDatagram || len(value) == 0 || value[0] != 0 {
+			continue
+		}
+
+		return value[1:], nil
+	}
+}
+
+// appendVarint appends a QUIC variable-length integer (RFC 9000, section 16).
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, byte(v>>8)|0x40, byte(v))
+	case v < 1<<30:
+		return append(b, byte(v>>24)|0x80, byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, byte(v>>56)|0xc0, byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// readVarint reads a QUIC variable-length integer (RFC 9000, section 16).
+func readVarint(r io.ByteReader) (uint64, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	length := 1 << (first >> 6)
+	v := uint64(first & 0x3f)
+	for i := 1; i < length; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+
+	return v, nil
+}
+
+// isConnectUDPRequest returns true if the request is an HTTP/1.1 CONNECT-UDP upgrade request.
+func isConnectUDPRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		strings.HasPrefix(r.URL.Path, cpapi.ConnectUDPPathPrefix) &&
+		strings.EqualFold(r.Header.Get("Upgrade"), cpapi.ConnectUDPProtocol)
+}
+
+// CreateUDPListener starts a UDP listener to an imported service.
+func (d *Dataplane) CreateUDPListener(name, ip string, port uint32) {
+	listenTarget := ip + ":" + strconv.Itoa(int(port))
+	d.listenerEnd[name] = make(chan bool)
+	d.logger.Infof("Starting a UDP listener for imported service %s at %s.", name, listenTarget)
+	packetConn, err := net.ListenPacket("udp", listenTarget)
+	if err != nil {
+		d.logger.Infof("Error listening to port: %v.", err)
+		return
+	}
+	go func() {
+		if err = d.serveEgressFlows(name, packetConn); err != nil && !errors.Is(err, net.ErrClosed) {
+			d.logger.Errorf("Failed to serve egress flows on %s: %+v.", listenTarget, err)
+		}
+	}()
+	<-d.listenerEnd[name]
+	d.logger.Infof("Ending the UDP listener for imported service %s at %s.", name, listenTarget)
+	packetConn.Close()
+}
+
+// serveEgressFlows demultiplexes datagrams received on a UDP listener into flows, keyed by the client address.
+// Each flow is authorized and tunneled to a peer separately.
+func (d *Dataplane) serveEgressFlows(name string, packetConn net.PacketConn) error {
+	var flowsLock sync.Mutex
+	flows := make(map[string]*datagramConn)
+	// denied maps the addresses of clients whose flow failed authorization to the time until which
+	// their datagrams are dropped
+	denied := make(map[string]time.Time)
+
+	d.logger.Infof("Serving for imported service %s at %s.", name, packetConn.LocalAddr())
+	bufData := make([]byte, dataBufferSize)
+	for {
+		numBytes, addr, err := packetConn.ReadFrom(bufData)
+		if err != nil {
+			return err
+		}
+
+		key := addr.String()
+		flowsLock.Lock()
+		if deniedUntil, ok := denied[key]; ok {
+			if time.Now().Before(deniedUntil) {
+				flowsLock.Unlock()
+				continue
+			}
+			delete(denied, key)
+		}
+
+		flow, ok := flows[key]
+		if !ok {
+			d.logger.Debugf("Received a new egress flow at listener for imported service %s from %s.", name, key)
+			flow = newDatagramConn(packetConn.LocalAddr(), addr, func(b []byte) (int, error) {
+				return packetConn.WriteTo(b, addr)
+			})
+			flow.idleTimeout = udpFlowIdleTimeout
+			newFlow := flow
+			flow.onClose = func() {
+				flowsLock.Lock()
+				defer flowsLock.Unlock()
+				if flows[key] == newFlow {
+					delete(flows, key)
+				}
+			}
+			flows[key] = flow
+
+			go d.serveEgressFlow(name, flow, func() {
+				flowsLock.Lock()
+				defer flowsLock.Unlock()
+
+				now := time.Now()
+				for deniedKey, deniedUntil := range denied {
+					if !now.Before(deniedUntil) {
+						delete(denied, deniedKey)
+					}
+				}
+				denied[key] = now.Add(udpFlowDenialBackoff)
+			})
+		}
+		flowsLock.Unlock()
+
+		flow.deliver(append([]byte(nil), bufData[:numBytes]...))
+	}
+}
+
+// serveEgressFlow authorizes a UDP flow and tunnels it to the target peer.
+// onDenied is called before closing a flow which failed authorization.
+func (d *Dataplane) serveEgressFlow(name string, flow *datagramConn, onDenied func()) {
+	sourceIP, _, err := net.SplitHostPort(flow.RemoteAddr().String())
+	if err != nil {
+		d.logger.Errorf("Cannot parse flow source address: %v.", err)
+		flow.Close()
+		return
+	}
+
+	targetPeer, accessToken, sourceExport, err := d.getEgressAuth(name, sourceIP, nil)
+	if err != nil {
+		d.logger.Infof("Failed egress authorization: %v.", err)
+		onDenied()
+		flow.Close()
+		return
+	}
+
+	tlsConfig, err := d.peerTLSConfig(targetPeer)
+	if err != nil {
+		d.logger.Errorf("Unable to get cluster host :%v.", err)
+		flow.Close()
+		return
+	}
+
+	if err := d.initiateEgressFlow(name, targetPeer, sourceExport, accessToken, flow, tlsConfig); err != nil {
+		d.logger.Errorf("Failed to initiate egress flow: %v.", err)
+		flow.Close()
+	}
+}
+
+// initiateEgressFlow tunnels a UDP flow to a peer using an HTTP/1.1 CONNECT-UDP request (RFC 9298).
+func (d *Dataplane) initiateEgressFlow(
+	name, targetCluster, sourceExport, authToken string, flow net.Conn, tlsConfig *tls.Config,
+) error {
+	target, err := d.GetClusterTarget(targetCluster)
+	if err != nil {
+		return err
+	}
+
+	targetHostname, err := d.GetClusterHostname(targetCluster)
+	if err != nil {
+		return err
+	}
+
+	// the target host and port are ignored by the remote peer, which uses the access token instead
+	importName, importNamespace, _, err := cpapi.ParseImportListenerName(name)
+	if err != nil {
+		return err
+	}
+	_, targetPort, err := net.SplitHostPort(flow.LocalAddr().String())
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://%s%s%s.%s/%s/",
+		targetHostname, cpapi.ConnectUDPPathPrefix, importName, importNamespace, targetPort)
+	d.logger.Debugf("Starting to initiate egress flow to: %s.", url)
+
+	egressReq, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	egressReq.Header.Set("Connection", "Upgrade")
+	egressReq.Header.Set("Upgrade", cpapi.ConnectUDPProtocol)
+	egressReq.Header.Set(capsuleProtocolHeader, "?1")
+	egressReq.Header.Set(cpapi.AuthorizationHeader, authToken)
+
+	start := time.Now()
+	peerConn, err := tls.Dial("tcp", target, tlsConfig)
+	if err != nil {
+		d.accessLogger.connectionFailed(name, targetCluster, sourceExport, http.StatusServiceUnavailable)
+		return err
+	}
+
+	if err := egressReq.Write(peerConn); err != nil {
+		peerConn.Close()
+		d.accessLogger.connectionFailed(name, targetCluster, sourceExport, http.StatusServiceUnavailable)
+		return err
+	}
+
+	reader := bufio.NewReader(peerConn)
+	resp, err := http.ReadResponse(reader, egressReq)
+	if err != nil {
+		peerConn.Close()
+		d.accessLogger.connectionFailed(name, targetCluster, sourceExport, http.StatusServiceUnavailable)
+		return err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		d.accessLogger.connectionFailed(name, targetCluster, sourceExport, resp.StatusCode)
+		resp.Body.Close()
+		peerConn.Close()
+		return fmt.Errorf("got HTTP %d while trying to establish dataplane flow", resp.StatusCode)
+	}
+
+	d.logger.Infof("Flow established successfully!")
+	flowRecord := newEgressFlow(name, targetCluster, protocolUDP, flow.RemoteAddr())
+	streamID := d.accessLogger.connectionEstablished(
+		name, targetCluster, sourceExport, flowRecord.ClientIP, time.Since(start))
+	defer d.accessLogger.connectionEnded(streamID, name, targetCluster)
+
+	forward := newForwarder(flow, newCapsuleConn(peerConn, reader), egressConnectionLabels(name, targetCluster))
+	d.forward(forward, flowRecord, targetCluster, streamID)
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	cpapi "github.com/clusterlink-net/clusterlink/pkg/controlplane/api"
+)
+
+func TestVarint(t *testing.T) {
+	for _, tc := range []struct {
+		value  uint64
+		length int
+	}{
+		{value: 0, length: 1},
+		{value: 63, length: 1},
+		{value: 64, length: 2},
+		{value: 1<<14 - 1, length: 2},
+		{value: 1 << 14, length: 4},
+		{value: 1<<30 - 1, length: 4},
+		{value: 1 << 30, length: 8},
+		{value: 1<<62 - 1, length: 8},
+	} {
+		encoded := appendVarint(nil, tc.value)
+		require.Len(t, encoded, tc.length, "value %d", tc.value)
+
+		decoded, err := readVarint(bytes.NewReader(encoded))
+		require.Nil(t, err)
+		require.Equal(t, tc.value, decoded)
+
+		// truncated input
+		_, err = readVarint(bytes.NewReader(encoded[:tc.length-1]))
+		require.ErrorIs(t, err, io.EOF)
+	}
+
+	// RFC 9000, appendix A.1 examples
+	decoded, err := readVarint(bytes.NewReader([]byte{0x7b, 0xbd}))
+	require.Nil(t, err)
+	require.Equal(t, uint64(15293), decoded)
+	decoded, err = readVarint(bytes.NewReader([]byte{0x9d, 0x7f, 0x3e, 0x7d}))
+	require.Nil(t, err)
+	require.Equal(t, uint64(494878333), decoded)
+}
+
+func TestDatagramCapsule(t *testing.T) {
+	var stream bytes.Buffer
+	// an unknown capsule, a datagram with a non-zero context ID, and datagrams to be read
+	stream.Write(appendVarint(appendVarint(nil, 0x2a), 3))
+	stream.WriteString("abc")
+	stream.Write(appendVarint(appendVarint(nil, capsuleTypeDatagram), 2))
+	stream.Write([]byte{1, 'x'})
+	stream.Write(encodeDatagramCapsule([]byte("datagram")))
+	stream.Write(encodeDatagramCapsule(nil))
+	stream.Write(encodeDatagramCapsule(make([]byte, 1000)))
+
+	reader := bufio.NewReader(&stream)
+	datagram, err := readDatagramCapsule(reader)
+	require.Nil(t, err)
+	require.Equal(t, "datagram", string(datagram))
+
+	datagram, err = readDatagramCapsule(reader)
+	require.Nil(t, err)
+	require.Empty(t, datagram)
+
+	datagram, err = readDatagramCapsule(reader)
+	require.Nil(t, err)
+	require.Len(t, datagram, 1000)
+
+	_, err = readDatagramCapsule(reader)
+	require.ErrorIs(t, err, io.EOF)
+
+	// truncated capsule
+	capsule := encodeDatagramCapsule([]byte("datagram"))
+	_, err = readDatagramCapsule(bufio.NewReader(bytes.NewReader(capsule[:len(capsule)-1])))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// the largest accepted payload, and an oversized one
+	_, err = readDatagramCapsule(bufio.NewReader(bytes.NewReader(encodeDatagramCapsule(make([]byte, maxCapsuleLength-1)))))
+	require.Nil(t, err)
+	_, err = readDatagramCapsule(bufio.NewReader(bytes.NewReader(encodeDatagramCapsule(make([]byte, maxCapsuleLength)))))
+	require.NotNil(t, err)
+}
+
+func TestCapsuleConn(t *testing.T) {
+	client, server := net.Pipe()
+	clientConn := newCapsuleConn(client, bufio.NewReader(client))
+	serverConn := newCapsuleConn(server, bufio.NewReader(server))
+
+	// datagram boundaries are preserved
+	go func() {
+		_, _ = clientConn.Write([]byte("first"))
+		_, _ = clientConn.Write([]byte("second"))
+	}()
+
+	buf := make([]byte, 100)
+	n, err := serverConn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "first", string(buf[:n]))
+	n, err = serverConn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "second", string(buf[:n]))
+
+	// closing one end closes the other
+	require.Nil(t, clientConn.Close())
+	_, err = serverConn.Read(buf)
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestUDPConnIdleTimeout(t *testing.T) {
+	app, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer app.Close()
+
+	udpConn, err := net.Dial("udp", app.LocalAddr().String())
+	require.Nil(t, err)
+
+	idleTimeout := 200 * time.Millisecond
+	conn := newUDPConn(udpConn, idleTimeout)
+
+	// datagrams are passed in both directions
+	_, err = conn.Write([]byte("request"))
+	require.Nil(t, err)
+
+	buf := make([]byte, 100)
+	n, addr, err := app.ReadFrom(buf)
+	require.Nil(t, err)
+	require.Equal(t, "request", string(buf[:n]))
+
+	_, err = app.WriteTo([]byte("response"), addr)
+	require.Nil(t, err)
+	n, err = conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "response", string(buf[:n]))
+
+	// an idle connection is closed, closing the UDP socket
+	start := time.Now()
+	_, err = conn.Read(buf)
+	require.ErrorIs(t, err, io.EOF)
+	require.GreaterOrEqual(t, time.Since(start), idleTimeout/2)
+
+	require.Nil(t, conn.Close())
+	_, err = udpConn.Write([]byte("closed"))
+	require.ErrorIs(t, err, net.ErrClosed)
+}
+
+// denyingAuthzClient is an authorization client denying all connections.
+type denyingAuthzClient struct {
+	checks atomic.Int32
+}
+
+func (c *denyingAuthzClient) Check(context.Context, *authv3.CheckRequest, ...grpc.CallOption) (*authv3.CheckResponse, error) {
+	c.checks.Add(1)
+	return &authv3.CheckResponse{
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{Body: "denied"},
+		},
+	}, nil
+}
+
+func TestDeniedEgressFlows(t *testing.T) {
+	d := NewDataplane("dp1", nil, nil)
+	authzClient := &denyingAuthzClient{}
+	d.authzClient = authzClient
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = d.serveEgressFlows(cpapi.ImportListenerName("svc", "ns", ""), packetConn)
+	}()
+
+	client1, err := net.Dial("udp", packetConn.LocalAddr().String())
+	require.Nil(t, err)
+	defer client1.Close()
+	client2, err := net.Dial("udp", packetConn.LocalAddr().String())
+	require.Nil(t, err)
+	defer client2.Close()
+
+	// datagrams of a denied client are dropped, rather than authorized again
+	_, err = client1.Write([]byte("first"))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return authzClient.checks.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		_, err = client1.Write([]byte("dropped"))
+		require.Nil(t, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(1), authzClient.checks.Load())
+
+	// other clients are authorized separately
+	_, err = client2.Write([]byte("first"))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return authzClient.checks.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	require.Nil(t, packetConn.Close())
+	<-done
+}
@@ -69,9 +69,12 @@ type InstanceReconciler struct {
 // +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=list;get;watch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=list;get;watch
-// +kubebuilder:rbac:groups=clusterlink.net,resources=exports;peers;accesspolicies;privilegedaccesspolicies,verbs=list;get;watch
+// +kubebuilder:rbac:groups=clusterlink.net,resources=exports;peers,verbs=list;get;watch
+// +kubebuilder:rbac:groups=clusterlink.net,resources=accesspolicies;privilegedaccesspolicies,verbs=list;get;watch;delete
+// +kubebuilder:rbac:groups=clusterlink.net,resources=workloadsets;privilegedworkloadsets,verbs=list;get;watch
 // +kubebuilder:rbac:groups=clusterlink.net,resources=imports,verbs=get;list;watch;update
 // +kubebuilder:rbac:groups=clusterlink.net,resources=peers/status;exports/status;imports/status,verbs=update
+// +kubebuilder:rbac:groups=clusterlink.net,resources=accesspolicies/status;privilegedaccesspolicies/status,verbs=update
 // +kubebuilder:rbac:groups="apps",resources=deployments,verbs=list;get;watch;create;update;patch;delete
 //nolint:lll // Ignore long line warning for Kubebuilder command.
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterroles;clusterrolebindings,verbs=list;get;watch;create;update;patch;delete
@@ -477,8 +480,15 @@ func (r *InstanceReconciler) createAccessControl(ctx context.Context, name, name
 			},
 			{
 				APIGroups: []string{"clusterlink.net"},
-				Resources: []string{"peers", "exports", "accesspolicies", "privilegedaccesspolicies"},
-				Verbs:     []string{"get", "list", "watch"},
+				Resources: []string{
+					"peers", "exports", "workloadsets", "privilegedworkloadsets",
+				},
+				Verbs: []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"clusterlink.net"},
+				Resources: []string{"accesspolicies", "privilegedaccesspolicies"},
+				Verbs:     []string{"get", "list", "watch", "delete"},
 			},
 			{
 				APIGroups: []string{"clusterlink.net"},
@@ -487,8 +497,11 @@ func (r *InstanceReconciler) createAccessControl(ctx context.Context, name, name
 			},
 			{
 				APIGroups: []string{"clusterlink.net"},
-				Resources: []string{"peers/status", "exports/status", "imports/status"},
-				Verbs:     []string{"update"},
+				Resources: []string{
+					"peers/status", "exports/status", "imports/status",
+					"accesspolicies/status", "privilegedaccesspolicies/status",
+				},
+				Verbs: []string{"update"},
 			},
 		},
 	}
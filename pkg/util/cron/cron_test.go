@@ -0,0 +1,81 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/clusterlink-net/clusterlink/pkg/util/cron"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	} {
+		_, err := cron.Parse(expr)
+		require.NotNil(t, err, "expression: '%s'", expr)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// 2024-01-01 is a Monday
+	monday := time.Date(2024, time.January, 1, 9, 30, 0, 0, time.UTC)
+	sunday := time.Date(2024, time.January, 7, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr    string
+		t       time.Time
+		matches bool
+	}{
+		{expr: "* * * * *", t: monday, matches: true},
+		{expr: "30 9 * * *", t: monday, matches: true},
+		{expr: "31 9 * * *", t: monday, matches: false},
+		{expr: "* 9-16 * * 1-5", t: monday, matches: true},
+		{expr: "* 9-16 * * 1-5", t: sunday, matches: false},
+		{expr: "* 10-16 * * 1-5", t: monday, matches: false},
+		{expr: "*/15 * * * *", t: monday, matches: true},
+		{expr: "*/20 * * * *", t: monday, matches: false},
+		{expr: "10/20 * * * *", t: monday, matches: true},
+		{expr: "0,15,30,45 * * * *", t: monday, matches: true},
+		{expr: "* * * 2 *", t: monday, matches: false},
+		{expr: "* * * * 0", t: sunday, matches: true},
+		{expr: "* * * * 7", t: sunday, matches: true},
+		// both days restricted - either matches
+		{expr: "* * 7 * 1", t: monday, matches: true},
+		{expr: "* * 7 * 1", t: sunday, matches: true},
+		{expr: "* * 8 * 2", t: sunday, matches: false},
+		// one day field restricted - both must match
+		{expr: "* * 1 * *", t: monday, matches: true},
+		{expr: "* * */2 * 1", t: sunday, matches: false},
+	}
+
+	for _, test := range tests {
+		schedule, err := cron.Parse(test.expr)
+		require.Nil(t, err)
+		require.Equal(t, test.matches, schedule.Matches(test.t), "expression: '%s', time: %v", test.expr, test.t)
+	}
+}
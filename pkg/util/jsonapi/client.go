@@ -27,6 +27,10 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
 )
 
 // Client for issuing HTTP requests.
@@ -70,6 +74,24 @@ func (c *Client) ServerURL() string {
 }
 
 func (c *Client) do(ctx context.Context, method, path string, body []byte) (*Response, error) {
+	// requests are traced only as part of an existing trace
+	if !tracing.HasSpan(ctx) {
+		return c.send(ctx, method, path, body)
+	}
+
+	ctx, span := tracing.Start(ctx, method+" "+path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.url", c.serverURL)))
+	resp, err := c.send(ctx, method, path, body)
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.Status))
+	}
+	tracing.End(span, err)
+
+	return resp, err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*Response, error) {
 	requestLogger := c.logger.WithFields(logrus.Fields{"method": method, "path": path})
 
 	requestLogger.WithField("body-length", len(body)).Debugf("Issuing request.")
@@ -84,6 +106,8 @@ func (c *Client) do(ctx context.Context, method, path string, body []byte) (*Res
 		req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	}
 
+	tracing.InjectHTTP(ctx, req.Header)
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		// check for timeout error which could be due to a failed re-used connection
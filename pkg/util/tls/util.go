@@ -14,6 +14,7 @@
 package tls
 
 import (
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -37,6 +38,11 @@ func ParseFiles(ca, cert, key string) (*ParsedCertData, *RawCertData, error) {
 		return nil, nil, fmt.Errorf("unable to read private key file: %w", err)
 	}
 
+	return Parse(rawCA, rawCertificate, rawPrivateKey)
+}
+
+// Parse parses the given raw (PEM-encoded) CA, certificate and private key.
+func Parse(rawCA, rawCertificate, rawPrivateKey []byte) (*ParsedCertData, *RawCertData, error) {
 	certificate, err := tls.X509KeyPair(rawCertificate, rawPrivateKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to parse certificate keypair: %w", err)
@@ -110,6 +116,21 @@ func (c *ParsedCertData) ClientConfig(sni string) *tls.Config {
 	}
 }
 
+// PrivateKey returns the certificate private key.
+func (c *ParsedCertData) PrivateKey() crypto.PrivateKey {
+	return c.certificate.PrivateKey
+}
+
+// CertificateChain returns the DER-encoded certificate chain, starting with the leaf certificate.
+func (c *ParsedCertData) CertificateChain() [][]byte {
+	return c.certificate.Certificate
+}
+
+// CA returns the certificate authority pool.
+func (c *ParsedCertData) CA() *x509.CertPool {
+	return c.ca
+}
+
 // DNSNames returns the certificate DNS names.
 func (c *ParsedCertData) DNSNames() []string {
 	return c.x509cert.DNSNames
@@ -0,0 +1,70 @@
+// Copyright (c) The ClusterLink Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/clusterlink-net/clusterlink/pkg/util/tracing"
+)
+
+func TestPropagation(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), "test", "")
+	require.Nil(t, err)
+	defer func() {
+		require.Nil(t, shutdown(context.Background()))
+	}()
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	// no trace context
+	require.False(t, tracing.HasSpan(context.Background()))
+	require.False(t, tracing.HasSpan(tracing.ExtractGRPC(context.Background())))
+	require.False(t, tracing.HasSpan(tracing.ExtractMap(context.Background(), map[string]string{})))
+	require.True(t, tracing.HasSpan(ctx))
+
+	// gRPC metadata, keeping existing metadata
+	outgoingCtx := tracing.InjectGRPC(metadata.AppendToOutgoingContext(ctx, "key", "value"))
+	md, ok := metadata.FromOutgoingContext(outgoingCtx)
+	require.True(t, ok)
+	require.Equal(t, []string{"value"}, md.Get("key"))
+
+	extracted := trace.SpanContextFromContext(
+		tracing.ExtractGRPC(metadata.NewIncomingContext(context.Background(), md)))
+	require.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	require.Equal(t, spanContext.SpanID(), extracted.SpanID())
+	require.True(t, extracted.IsRemote())
+
+	// HTTP headers, passed on as a map of headers
+	header := http.Header{}
+	tracing.InjectHTTP(ctx, header)
+	headers := make(map[string]string)
+	tracing.InjectMap(tracing.ExtractHTTP(context.Background(), header), headers)
+	require.Contains(t, headers, "traceparent")
+
+	extracted = trace.SpanContextFromContext(tracing.ExtractMap(context.Background(), headers))
+	require.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	require.Equal(t, spanContext.SpanID(), extracted.SpanID())
+}